@@ -0,0 +1,118 @@
+// Copyright © 2019 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+package nvram
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Default files used by ApplyAtBoot.
+const (
+	DefaultSettingsFile   = "/etc/nvram/settings"
+	DefaultStatusFile     = "/run/nvram/apply.status"
+	DefaultRebootFlagFile = "/run/nvram/reboot-required"
+)
+
+// ApplyConfig names the files used by ApplyAtBoot. Empty names use the
+// defaults.
+type ApplyConfig struct {
+	SettingsFile string
+	StatusFile   string
+	// RebootFlagFile is created when applied changes need a reboot to
+	// take effect, for use with a systemd ConditionPathExists= reboot
+	// unit. It is removed when no reboot is needed.
+	RebootFlagFile string
+}
+
+// ApplyStatus is written as JSON to the status file by ApplyAtBoot.
+type ApplyStatus struct {
+	Time           time.Time `json:"time"`
+	SettingsFile   string    `json:"settings_file"`
+	Changed        []string  `json:"changed"`
+	RebootRequired bool      `json:"reboot_required"`
+	Error          string    `json:"error,omitempty"`
+}
+
+func (c *ApplyConfig) setDefaults() {
+	if c.SettingsFile == "" {
+		c.SettingsFile = DefaultSettingsFile
+	}
+	if c.StatusFile == "" {
+		c.StatusFile = DefaultStatusFile
+	}
+	if c.RebootFlagFile == "" {
+		c.RebootFlagFile = DefaultRebootFlagFile
+	}
+}
+
+// ApplyAtBoot reconciles the open NVRAM with the desired settings file once,
+// as done at boot by "nvram apply" from a systemd unit. The outcome is
+// written to the status file and, if the firmware must reboot to use the
+// changes, the reboot flag file is created.
+func (nv *NVRAM) ApplyAtBoot(config ApplyConfig) (status ApplyStatus, err error) {
+	config.setDefaults()
+	status.SettingsFile = config.SettingsFile
+
+	// Always record the outcome
+	defer func() {
+		status.Time = time.Now()
+		if err != nil {
+			status.Error = err.Error()
+		}
+		if werr := writeApplyStatus(config, status); err == nil {
+			err = werr
+		}
+	}()
+
+	f, err := os.Open(config.SettingsFile)
+	if err != nil {
+		return
+	}
+	settings, err := ReadSettings(f)
+	f.Close()
+	if err != nil {
+		return
+	}
+
+	status.Changed, err = nv.ApplySettings(settings)
+
+	// Firmware reads CMOS options at boot, so unless the layout marks the
+	// options needing a reboot any change needs one.
+	if nv.documentsReboot() {
+		status.RebootRequired = nv.RebootRequired()
+	} else {
+		status.RebootRequired = len(status.Changed) > 0
+	}
+	return
+}
+
+func writeApplyStatus(config ApplyConfig, status ApplyStatus) (err error) {
+	b, err := json.MarshalIndent(&status, "", "\t")
+	if err != nil {
+		return
+	}
+	if err = os.MkdirAll(filepath.Dir(config.StatusFile), 0755); err != nil {
+		return
+	}
+	if err = ioutil.WriteFile(config.StatusFile, append(b, '\n'), 0644); err != nil {
+		return
+	}
+
+	// Create or remove the reboot condition flag
+	if status.RebootRequired {
+		if err = os.MkdirAll(filepath.Dir(config.RebootFlagFile), 0755); err != nil {
+			return
+		}
+		return ioutil.WriteFile(config.RebootFlagFile, nil, 0644)
+	}
+	if err = os.Remove(config.RebootFlagFile); os.IsNotExist(err) {
+		err = nil
+	}
+	return
+}
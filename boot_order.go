@@ -0,0 +1,305 @@
+// Copyright © 2019 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+package nvram
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// BootOrderProfile describes how a board's layout encodes its boot order.
+type BootOrderProfile struct {
+	// Slots are the enum parameters holding the boot devices, first
+	// device first. A single slot whose enum texts list several devices
+	// joined by Separator holds the whole order, as in "HDD,USB,Network".
+	Slots     []string
+	Separator string
+	// None is the enum text of an unused slot, such as "None".
+	None string
+	// Next is an optional enum parameter selecting a device for the next
+	// boot only, NextNone its text for no one time boot.
+	Next     string
+	NextNone string
+}
+
+// bootSlotPattern matches numbered boot order entries such as boot_order1,
+// boot_device_2 or boot_dev3.
+var bootSlotPattern = regexp.MustCompile(`^boot_(order|device|dev)_?([0-9]+)$`)
+
+// DetectBootOrderProfile guesses the boot order profile of a layout from
+// common entry names: numbered slots such as boot_order1, boot_device_2 or
+// boot_dev3, or a single boot_order enum of comma separated device lists,
+// and boot_next or boot_once for a one time boot. Unused slots and no one
+// time boot are taken to be "None" or "Disabled" when the enum has one.
+func DetectBootOrderProfile(l *Layout) (p BootOrderProfile, ok bool) {
+	slots := make(map[string]int)
+	for _, e := range l.entryList() {
+		if e.config != CMOSEntryEnum {
+			continue
+		}
+		if m := bootSlotPattern.FindStringSubmatch(e.name); m != nil {
+			var n int
+			fmt.Sscan(m[2], &n)
+			slots[e.name] = n
+		}
+	}
+	for name := range slots {
+		p.Slots = append(p.Slots, name)
+	}
+	sort.Slice(p.Slots, func(i, j int) bool {
+		return slots[p.Slots[i]] < slots[p.Slots[j]]
+	})
+
+	if len(p.Slots) == 0 {
+		e, found := l.entry("boot_order")
+		if !found || e.config != CMOSEntryEnum {
+			return p, false
+		}
+		p.Slots = []string{e.name}
+		p.Separator = ","
+	}
+	p.None = l.noneText(p.Slots[0])
+
+	for _, name := range []string{"boot_next", "boot_once"} {
+		if e, found := l.entry(name); found && e.config == CMOSEntryEnum {
+			p.Next = name
+			p.NextNone = l.noneText(name)
+			break
+		}
+	}
+	return p, true
+}
+
+// noneText returns the enum text of an enum entry meaning no device.
+func (l *Layout) noneText(name string) string {
+	e, ok := l.entry(name)
+	if !ok {
+		return ""
+	}
+	for _, text := range []string{"None", "Disabled", "none", "disabled"} {
+		if _, ok := l.FindCMOSEnumValue(e.config_id, text); ok {
+			return text
+		}
+	}
+	return ""
+}
+
+// BootOrder reads and changes the boot order of a board as a list of
+// device names, whatever the layout's encoding.
+type BootOrder struct {
+	nv      *NVRAM
+	profile BootOrderProfile
+}
+
+// BootOrder returns the boot order encoded as profile, or as detected by
+// DetectBootOrderProfile if profile has no slots.
+func (nv *NVRAM) BootOrder(profile BootOrderProfile) (b *BootOrder, err error) {
+	if len(profile.Slots) == 0 {
+		var ok bool
+		profile, ok = DetectBootOrderProfile(nv.Layout)
+		if !ok {
+			return nil, fmt.Errorf("nvram: Layout has no known boot order entries.")
+		}
+	}
+
+	// Resolve aliases so the profile names layout entries.
+	resolve := func(name string) (string, error) {
+		e, err := nv.findParameterEntry(nv.resolveName(name))
+		if err != nil {
+			return "", err
+		}
+		if e.config != CMOSEntryEnum {
+			return "", fmt.Errorf("nvram: Boot order parameter %s is not an enum.", name)
+		}
+		return e.name, nil
+	}
+	slots := make([]string, len(profile.Slots))
+	for i, name := range profile.Slots {
+		slots[i], err = resolve(name)
+		if err != nil {
+			return
+		}
+	}
+	profile.Slots = slots
+	if profile.Next != "" {
+		profile.Next, err = resolve(profile.Next)
+		if err != nil {
+			return
+		}
+	}
+	return &BootOrder{nv: nv, profile: profile}, nil
+}
+
+// Profile returns the encoding of the boot order.
+func (b *BootOrder) Profile() BootOrderProfile {
+	return b.profile
+}
+
+// Devices returns the devices that can be placed in the boot order.
+func (b *BootOrder) Devices() (devices []string) {
+	seen := make(map[string]bool)
+	for _, name := range b.profile.Slots {
+		e, _ := b.nv.FindCMOSEntry(name)
+		items, _ := b.nv.GetCMOSEnumItemsById(e.config_id)
+		for _, item := range items {
+			for _, d := range b.split(item.text) {
+				if !seen[d] {
+					seen[d] = true
+					devices = append(devices, d)
+				}
+			}
+		}
+	}
+	return
+}
+
+func (b *BootOrder) split(text string) (devices []string) {
+	if text == b.profile.None {
+		return nil
+	}
+	if b.profile.Separator == "" {
+		return []string{text}
+	}
+	for _, d := range strings.Split(text, b.profile.Separator) {
+		if d = strings.TrimSpace(d); d != "" {
+			devices = append(devices, d)
+		}
+	}
+	return
+}
+
+// List returns the devices in boot order. Unused slots are left out.
+func (b *BootOrder) List() (order []string, err error) {
+	for _, name := range b.profile.Slots {
+		var v interface{}
+		v, err = b.nv.ReadCMOSParameter(name)
+		if err != nil {
+			return nil, err
+		}
+		s, ok := v.(string)
+		if !ok {
+			return nil, fmt.Errorf("nvram: Boot order slot %s is not a string parameter.", name)
+		}
+		order = append(order, b.split(s)...)
+	}
+	return
+}
+
+// Set writes a new boot order in one transaction. Slots after the given
+// devices are set unused.
+func (b *BootOrder) Set(order []string) (err error) {
+	seen := make(map[string]bool)
+	for _, d := range order {
+		if seen[d] {
+			return fmt.Errorf("nvram: Boot device %s is listed twice.", d)
+		}
+		seen[d] = true
+	}
+
+	var params []Parameter
+	if b.profile.Separator != "" {
+		params = append(params, Parameter{Name: b.profile.Slots[0],
+			Value: strings.Join(order, b.profile.Separator)})
+	} else {
+		if len(order) > len(b.profile.Slots) {
+			return fmt.Errorf("nvram: Boot order of %d devices does not fit %d slots.",
+				len(order), len(b.profile.Slots))
+		}
+		for i, name := range b.profile.Slots {
+			value := b.profile.None
+			if i < len(order) {
+				value = order[i]
+			} else if value == "" {
+				return fmt.Errorf("nvram: Boot order must fill all %d slots.", len(b.profile.Slots))
+			}
+			params = append(params, Parameter{Name: name, Value: value})
+		}
+	}
+
+	// Check the devices before writing anything.
+	for _, p := range params {
+		if err = b.nv.ValidateParameter(p.Name, p.Value); err != nil {
+			return
+		}
+	}
+	return b.nv.WriteCMOSParameters(params)
+}
+
+// Move places a device at position i of the boot order, keeping the order
+// of the other devices.
+func (b *BootOrder) Move(device string, i int) (err error) {
+	order, err := b.List()
+	if err != nil {
+		return
+	}
+	for j, d := range order {
+		if d == device {
+			order = append(order[:j], order[j+1:]...)
+			break
+		}
+	}
+	if i < 0 || i > len(order) {
+		return fmt.Errorf("nvram: Boot order position %d out of range.", i)
+	}
+	order = append(order[:i], append([]string{device}, order[i:]...)...)
+	return b.Set(order)
+}
+
+// SetNextBoot boots device once at the next boot without changing the boot
+// order. An empty device clears a pending one time boot.
+func (b *BootOrder) SetNextBoot(device string) (err error) {
+	if b.profile.Next == "" {
+		return fmt.Errorf("nvram: Layout has no one time boot parameter.")
+	}
+	if device == "" {
+		device = b.profile.NextNone
+	}
+	return b.nv.WriteCMOSParameter(b.profile.Next, device)
+}
+
+// NextBoot returns the device selected for the next boot only, if any.
+func (b *BootOrder) NextBoot() (device string, ok bool, err error) {
+	if b.profile.Next == "" {
+		return
+	}
+	v, err := b.nv.ReadCMOSParameter(b.profile.Next)
+	if err != nil {
+		return
+	}
+	device, ok = v.(string)
+	if !ok {
+		return "", false, fmt.Errorf("nvram: One time boot parameter %s is not a string parameter.",
+			b.profile.Next)
+	}
+	if device == b.profile.NextNone {
+		return "", false, nil
+	}
+	return device, true, nil
+}
+
+// Register adds a virtual parameter presenting the boot order as a comma
+// separated device list.
+func (b *BootOrder) Register(name string) error {
+	return b.nv.RegisterVirtualParameter(name,
+		func(nv *NVRAM) (value interface{}, err error) {
+			order, err := b.List()
+			return strings.Join(order, ","), err
+		},
+		func(nv *NVRAM, value interface{}) error {
+			s, ok := value.(string)
+			if !ok {
+				return fmt.Errorf("A string value is required for %s, not %T.", name, value)
+			}
+			var order []string
+			for _, d := range strings.Split(s, ",") {
+				if d = strings.TrimSpace(d); d != "" {
+					order = append(order, d)
+				}
+			}
+			return b.Set(order)
+		})
+}
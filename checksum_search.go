@@ -0,0 +1,76 @@
+// Copyright © 2019 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+package nvram
+
+import (
+	"fmt"
+)
+
+// FindChecksumCandidates searches a CMOS dump for checksum records
+// consistent with its data: a big endian 16 bit word outside the summed
+// range holding the sum of its bytes. Zero bytes do not change a sum, so
+// only ranges starting and ending with a nonzero byte are returned; the
+// real range may extend over zero bytes on either side. Candidates are
+// sorted by checksum location, then by range. A dump with few settings
+// matches many ranges by chance, check candidates against a second dump
+// taken after changing a setting.
+func FindChecksumCandidates(dump []byte) (candidates []CMOSChecksum, err error) {
+	if len(dump) < int(cmosSize) {
+		return nil, fmt.Errorf("nvram: CMOS dump smaller than %d bytes.", cmosSize)
+	}
+	return checksumCandidates([][]byte{dump}), nil
+}
+
+// checksumCandidates returns the checksum records matching every dump.
+func checksumCandidates(dumps [][]byte) (candidates []CMOSChecksum) {
+	// Prefix sums of every dump.
+	prefix := make([][]uint, len(dumps))
+	for i, d := range dumps {
+		prefix[i] = make([]uint, cmosSize+1)
+		for off := uint(0); off < cmosSize; off++ {
+			prefix[i][off+1] = prefix[i][off] + uint(d[off])
+		}
+	}
+
+	// A range edge must be nonzero in at least one dump.
+	edge := func(off uint) bool {
+		for _, d := range dumps {
+			if d[off] != 0 {
+				return true
+			}
+		}
+		return false
+	}
+
+	for index := cmosRTCAreaSize; index+1 < cmosSize; index++ {
+		for start := cmosRTCAreaSize; start < cmosSize; start++ {
+			if !edge(start) {
+				continue
+			}
+			for end := start + 1; end < cmosSize; end++ {
+				if !edge(end) || checkAreaOverLap(start, end-start+1, index, 2) {
+					continue
+				}
+				if checksumMatches(dumps, prefix, start, end, index) {
+					candidates = append(candidates, CMOSChecksum{start: start,
+						end: end, index: index, kind: ChecksumPCBIOS})
+				}
+			}
+		}
+	}
+	return
+}
+
+// checksumMatches reports if the word at index is the sum of start..end in
+// every dump.
+func checksumMatches(dumps [][]byte, prefix [][]uint, start, end, index uint) bool {
+	for i, d := range dumps {
+		s := uint16(prefix[i][end+1] - prefix[i][start])
+		if s != uint16(d[index])<<8|uint16(d[index+1]) {
+			return false
+		}
+	}
+	return true
+}
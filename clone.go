@@ -0,0 +1,86 @@
+// Copyright © 2019 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+package nvram
+
+import (
+	"fmt"
+	"path"
+)
+
+// CloneSettings transfers parameter values by name from a CMOS dump of one
+// machine to the CMOS dump of another and returns the new destination image
+// with an updated checksum. The destination dump is not modified. If it is
+// nil the default image of dstLayout, or zeroed CMOS, is used.
+//
+// Parameters marked unique in either layout, parameters matching
+// one of the exclude patterns and parameters dstLayout does not define keep
+// their destination value. Patterns use path.Match syntax, e.g. "mac_*".
+func CloneSettings(srcLayout *Layout, srcDump []byte, dstLayout *Layout, dstDump []byte,
+	exclude []string) (image []byte, err error) {
+	src, err := openImage(srcLayout, srcDump)
+	if err != nil {
+		return
+	}
+
+	// Start with a copy of the destination dump.
+	if dstDump == nil {
+		dstDump, _ = dstLayout.DefaultImage()
+	}
+	image = make([]byte, cmosSize)
+	copy(image, dstDump)
+	dst, err := openImage(dstLayout, image)
+	if err != nil {
+		return
+	}
+
+	for _, e := range srcLayout.entryList() {
+		if e.config == CMOSEntryReserved || e.name == "check_sum" || e.meta.unique {
+			continue
+		}
+		var excluded bool
+		excluded, err = matchesAny(exclude, e.name)
+		if err != nil {
+			return nil, err
+		}
+		if excluded {
+			continue
+		}
+		d, ok := dstLayout.entry(dst.resolveName(e.name))
+		if !ok || d.meta.unique || d.config == CMOSEntryReserved {
+			continue
+		}
+
+		var value interface{}
+		value, err = src.ReadCMOSParameter(e.name)
+		if err == nil {
+			err = dst.WriteCMOSParameter(d.name, value)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("Cloning %s: %v", e.name, err)
+		}
+	}
+
+	if dst.modified && dst.CMOS.checksum.kind != ChecksumNone {
+		var sum uint16
+		sum, err = dst.CMOS.ComputeChecksum()
+		if err == nil {
+			err = dst.CMOS.WriteChecksum(sum)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return
+}
+
+func matchesAny(patterns []string, name string) (ok bool, err error) {
+	for _, pattern := range patterns {
+		ok, err = path.Match(pattern, name)
+		if ok || err != nil {
+			return
+		}
+	}
+	return
+}
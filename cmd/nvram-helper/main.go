@@ -0,0 +1,90 @@
+// Copyright © 2019 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+// Command nvram-helper is a small privileged process that owns CMOS
+// hardware access and serves byte reads and writes on a unix socket, so
+// management daemons using nvram.WithHelperSocket can run unprivileged.
+//
+//	nvram-helper -socket /run/nvram-helper.sock -gid 115
+//
+// With -send-port it instead opens /dev/port, sends it to the process that
+// ran it with nvram.ReceivePortFile and exits.
+//
+//	sudo nvram-helper -send-port
+package main
+
+import (
+	"flag"
+	"fmt"
+	"github.com/platinasystems/nvram"
+	"net"
+	"os"
+)
+
+func main() {
+	socket := flag.String("socket", nvram.DefaultHelperSocket, "unix socket to listen on")
+	uid := flag.Int("uid", -1, "also allow clients running as this user id")
+	gid := flag.Int("gid", -1, "also allow clients running as this group id and make the socket group accessible")
+	ports := flag.String("ports", nvram.DefaultCMOSPorts.String(), "CMOS lower and upper bank index and data ports")
+	nmi := flag.String("nmi", nvram.NMIEnable.String(), "NMI bit of index port writes: enable, disable or preserve")
+	sendPort := flag.Bool("send-port", false, "send /dev/port to the parent process and exit")
+	flag.Parse()
+
+	if *sendPort {
+		if err := nvram.SendPortFile(); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	p, err := nvram.ParseCMOSPorts(*ports)
+	var m nvram.NMIMode
+	if err == nil {
+		m, err = nvram.ParseNMIMode(*nmi)
+	}
+	if err == nil {
+		err = serve(*socket, *uid, *gid, p, m)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func serve(socket string, uid, gid int, ports nvram.CMOSPorts, nmi nvram.NMIMode) (err error) {
+	var hw nvram.CMOSHW
+	hw.SetPorts(ports)
+	hw.SetNMIMode(nmi)
+	err = hw.Open()
+	if err != nil {
+		return
+	}
+	defer hw.Close()
+
+	os.Remove(socket)
+	l, err := net.ListenUnix("unix", &net.UnixAddr{Name: socket, Net: "unix"})
+	if err != nil {
+		return
+	}
+	defer l.Close()
+
+	// Restrict socket to root and the allowed group
+	mode := os.FileMode(0600)
+	if gid >= 0 {
+		if err = os.Chown(socket, 0, gid); err != nil {
+			return
+		}
+		mode = 0660
+	}
+	if err = os.Chmod(socket, mode); err != nil {
+		return
+	}
+
+	return nvram.ServeHelper(l, &hw, func(cred *nvram.PeerCred) bool {
+		return cred.Uid == 0 ||
+			(uid >= 0 && cred.Uid == uint32(uid)) ||
+			(gid >= 0 && cred.Gid == uint32(gid))
+	})
+}
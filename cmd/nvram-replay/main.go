@@ -0,0 +1,51 @@
+// Copyright © 2019 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+// Command nvram-replay applies a CMOS access trace recorded with
+// nvram.WithTrace to a CMOS memory file.
+//
+//	nvram-replay -check trace.json cmos.bin
+package main
+
+import (
+	"flag"
+	"fmt"
+	"github.com/platinasystems/nvram"
+	"os"
+)
+
+func main() {
+	check := flag.Bool("check", false, "stop at the first read that differs from the trace")
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "usage: %s [-check] TRACE CMOS_FILE\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() != 2 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	if err := replay(flag.Arg(0), flag.Arg(1), *check); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func replay(traceFile, cmosFile string, check bool) (err error) {
+	f, err := os.Open(traceFile)
+	if err != nil {
+		return
+	}
+	defer f.Close()
+
+	var mem nvram.CMOSMem
+	err = mem.Open(cmosFile)
+	if err != nil {
+		return
+	}
+	defer mem.Close()
+
+	return nvram.ReplayTrace(f, &mem, check)
+}
@@ -0,0 +1,228 @@
+// Copyright © 2019 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+// Command nvram reads and writes coreboot CMOS parameters.
+//
+//	nvram [-layout FILE] [-cmos FILE] VERB [ARGS]
+//
+// Verbs:
+//
+//	list                 list parameter names
+//	get NAME...          show parameter values
+//	set NAME=VALUE...    write parameter values
+//	export               write all parameters as a settings file
+//	apply                apply a settings file once at boot
+//	selftest             check CMOS access with a scratch parameter
+//	explain NAME...      show how parameters map to CMOS bits
+//
+// Exit status is 0 on success, 1 on failure and 2 for usage errors.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"github.com/platinasystems/nvram"
+	"os"
+	"strings"
+)
+
+const (
+	exitOK      = 0
+	exitFailure = 1
+	exitUsage   = 2
+)
+
+type verb struct {
+	usage string
+	run   func(nv *nvram.NVRAM, args []string) error
+}
+
+var verbs = map[string]verb{
+	"list":   {"list", list},
+	"get":    {"get NAME...", get},
+	"set":    {"set NAME=VALUE...", set},
+	"export": {"export [-descriptions] [-drift]", export},
+	"apply":  {"apply [-settings FILE] [-status FILE] [-reboot-flag FILE]", apply},
+
+	"selftest": {"selftest [-scratch NAME]", selftest},
+	"explain":  {"explain NAME...", explain},
+}
+
+type usageError string
+
+func (e usageError) Error() string {
+	return string(e)
+}
+
+func usage() {
+	fmt.Fprintf(os.Stderr, "usage: %s [-layout FILE] [-cmos FILE] VERB [ARGS]\n", os.Args[0])
+	flag.PrintDefaults()
+	fmt.Fprintln(os.Stderr, "verbs:")
+	for _, name := range []string{"list", "get", "set", "export", "apply", "selftest", "explain"} {
+		fmt.Fprintf(os.Stderr, "  %s\n", verbs[name].usage)
+	}
+}
+
+func main() {
+	layout := flag.String("layout", "", "CMOS layout file, default is the coreboot table")
+	cmos := flag.String("cmos", "", "CMOS memory file, default is the CMOS hardware")
+	ports := flag.String("ports", nvram.DefaultCMOSPorts.String(), "CMOS hardware lower and upper bank index and data ports")
+	nmi := flag.String("nmi", nvram.NMIEnable.String(), "CMOS hardware NMI bit of index port writes: enable, disable or preserve")
+	flag.Usage = usage
+	flag.Parse()
+
+	if flag.NArg() < 1 {
+		usage()
+		os.Exit(exitUsage)
+	}
+	v, ok := verbs[flag.Arg(0)]
+	if !ok {
+		usage()
+		os.Exit(exitUsage)
+	}
+
+	p, err := nvram.ParseCMOSPorts(*ports)
+	var m nvram.NMIMode
+	if err == nil {
+		m, err = nvram.ParseNMIMode(*nmi)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(exitUsage)
+	}
+
+	os.Exit(run(v, *layout, *cmos, []nvram.Option{nvram.WithCMOSPorts(p), nvram.WithNMIMode(m)},
+		flag.Args()[1:]))
+}
+
+func run(v verb, layout, cmos string, opts []nvram.Option, args []string) int {
+	nv := nvram.NewNVRAM(opts...)
+	if err := nv.Open(layout, cmos); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitFailure
+	}
+
+	err := v.run(nv, args)
+	if cerr := nv.Close(); err == nil {
+		err = cerr
+	}
+
+	switch err.(type) {
+	case nil:
+		return exitOK
+	case usageError:
+		fmt.Fprintf(os.Stderr, "usage: %s %s\n", os.Args[0], v.usage)
+		return exitUsage
+	}
+	fmt.Fprintln(os.Stderr, err)
+	return exitFailure
+}
+
+func list(nv *nvram.NVRAM, args []string) error {
+	if len(args) != 0 {
+		return usageError("list")
+	}
+	for _, name := range nv.ParameterNames() {
+		fmt.Println(name)
+	}
+	return nil
+}
+
+func get(nv *nvram.NVRAM, args []string) error {
+	if len(args) == 0 {
+		return usageError("get")
+	}
+	for _, name := range args {
+		v, err := nv.ReadCMOSParameter(name)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("%s = %s\n", name, nv.FormatParameter(name, v))
+	}
+	return nil
+}
+
+func set(nv *nvram.NVRAM, args []string) error {
+	if len(args) == 0 {
+		return usageError("set")
+	}
+	for _, arg := range args {
+		kv := strings.SplitN(arg, "=", 2)
+		if len(kv) != 2 {
+			return usageError("set")
+		}
+		v, err := nv.ParseParameterValue(kv[0], kv[1])
+		if err != nil {
+			return err
+		}
+		if err = nv.WriteCMOSParameter(kv[0], v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func export(nv *nvram.NVRAM, args []string) error {
+	fs := flag.NewFlagSet("export", flag.ContinueOnError)
+	descriptions := fs.Bool("descriptions", false, "include parameter descriptions")
+	drift := fs.Bool("drift", false, "only export parameters changed from their defaults")
+	if fs.Parse(args) != nil || fs.NArg() != 0 {
+		return usageError("export")
+	}
+	return nv.ExportParameters(os.Stdout, nvram.ExportOptions{Descriptions: *descriptions, Drift: *drift})
+}
+
+func apply(nv *nvram.NVRAM, args []string) error {
+	var config nvram.ApplyConfig
+	fs := flag.NewFlagSet("apply", flag.ContinueOnError)
+	fs.StringVar(&config.SettingsFile, "settings", nvram.DefaultSettingsFile, "desired settings file")
+	fs.StringVar(&config.StatusFile, "status", nvram.DefaultStatusFile, "status file written after applying")
+	fs.StringVar(&config.RebootFlagFile, "reboot-flag", nvram.DefaultRebootFlagFile,
+		"file created when a reboot is required, for ConditionPathExists=")
+	if fs.Parse(args) != nil || fs.NArg() != 0 {
+		return usageError("apply")
+	}
+
+	status, err := nv.ApplyAtBoot(config)
+	if err != nil {
+		return err
+	}
+	if len(status.Changed) > 0 {
+		fmt.Printf("changed: %s\n", strings.Join(status.Changed, " "))
+	}
+	if status.RebootRequired {
+		fmt.Println("reboot required")
+	}
+	return nil
+}
+
+func selftest(nv *nvram.NVRAM, args []string) error {
+	fs := flag.NewFlagSet("selftest", flag.ContinueOnError)
+	scratch := fs.String("scratch", "", "parameter to overwrite, default is an unused CMOS byte")
+	if fs.Parse(args) != nil || fs.NArg() != 0 {
+		return usageError("selftest")
+	}
+	if *scratch != "" {
+		nv.SetOptions(nvram.WithScratchParameter(*scratch))
+	}
+	if err := nv.SelfTest(); err != nil {
+		return err
+	}
+	fmt.Println("self test passed")
+	return nil
+}
+
+func explain(nv *nvram.NVRAM, args []string) error {
+	if len(args) == 0 {
+		return usageError("explain")
+	}
+	for _, name := range args {
+		x, err := nv.Explain(name)
+		if err != nil {
+			return err
+		}
+		fmt.Print(x)
+	}
+	return nil
+}
@@ -5,8 +5,12 @@
 package nvram
 
 import (
+	"encoding/json"
 	"fmt"
 	"github.com/platinasystems/nvram/debug"
+	"os"
+	"sync"
+	"time"
 )
 
 const (
@@ -27,20 +31,57 @@ type CMOSer interface {
 type CMOS struct {
 	accessor CMOSer
 	checksum CMOSChecksum
+
+	// mu serializes accessor operations.
+	mu        sync.Mutex
+	shadow    []byte
+	retry     RetryPolicy
+	verify    bool
+	safeOrder bool
+	ports     CMOSPorts
+	nmi       NMIMode
+	directIO  bool
+	ioPriv    IOPrivilege
+	portFile  *os.File
+	drop      *privDrop
+	syncMem   bool
+	memSize   uint
+	memCreate bool
+	trace     *json.Encoder
+
+	backend string
+	statsMu sync.Mutex
+	stats   map[string]*Stats
 }
 
 func (c *CMOS) Open() (err error) {
 	// Close in case it is already opened.
 	c.Close()
 
-	// Open CMOS hardware accessor.
-	accessor := new(CMOSHW)
-	err = accessor.Open()
+	// Open CMOS hardware accessor, on a port file opened by a privileged
+	// helper if one was given.
+	accessor := &CMOSHW{ports: c.ports, nmi: c.nmi, direct: c.directIO,
+		priv: c.ioPriv}
+	if c.portFile != nil {
+		err = accessor.OpenFile(c.portFile)
+		c.portFile = nil
+	} else {
+		err = accessor.Open()
+	}
 	if err != nil {
 		return
 	}
 
-	c.accessor = accessor
+	// Give up privileges once the hardware is open.
+	if c.drop != nil {
+		err = accessor.DropPrivileges(c.drop.uid, c.drop.gid)
+		if err != nil {
+			accessor.Close()
+			return
+		}
+	}
+
+	c.setAccessor(accessor)
 	return
 }
 
@@ -49,17 +90,23 @@ func (c *CMOS) OpenMem(filename string) (err error) {
 	c.Close()
 
 	// Open CMOS memory file accessor.
-	accessor := new(CMOSMem)
+	accessor := &CMOSMem{size: c.memSize, create: c.memCreate}
 	err = accessor.Open(filename)
 	if err != nil {
 		return
 	}
+	accessor.SetSynchronous(c.syncMem)
 
-	c.accessor = accessor
+	c.setAccessor(accessor)
 	return
 }
 
 func (c *CMOS) Close() (err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.shadow = nil
+
 	// Close any accessor if opened
 	if c.accessor != nil {
 		err = c.accessor.Close()
@@ -108,11 +155,11 @@ func (c *CMOS) WriteEntry(e *CMOSEntry, v []byte) (err error) {
 			// Update destination with remaining bits to write
 			mask := (byte(1<<src_size) - 1) << (dst_bit & 0x07)
 			n = (n & ^mask) | ((wvalue << (dst_bit & 0x07)) & mask)
-			err = c.WriteByte(dst_bit>>3, n)
+			err = c.updateByte(dst_bit>>3, n)
 			return
 		} else {
 			// Overwrite whole byte values
-			err = c.WriteByte(dst_bit>>3, wvalue)
+			err = c.updateByte(dst_bit>>3, wvalue)
 			if err != nil {
 				return
 			}
@@ -210,6 +257,11 @@ func (c *CMOS) WriteChecksum(sum uint16) (err error) {
 }
 
 func (c *CMOS) ComputeChecksum() (sum uint16, err error) {
+	start := time.Now()
+	defer func() {
+		c.recordStats(start, err, func(s *Stats) *OpStats { return &s.Checksums })
+	}()
+
 	// Calculate checksum over chemsum area
 	for i := c.checksum.start; i <= c.checksum.end; i++ {
 		var b byte
@@ -223,6 +275,11 @@ func (c *CMOS) ComputeChecksum() (sum uint16, err error) {
 }
 
 func (c *CMOS) ReadAllMemory() (d []byte, err error) {
+	start := time.Now()
+	defer func() {
+		c.recordStats(start, err, func(s *Stats) *OpStats { return &s.Dumps })
+	}()
+
 	// Retrun buffer with all CMOS data bytes
 	// Ignore the RTC area.
 	d = make([]byte, cmosSize)
@@ -236,32 +293,88 @@ func (c *CMOS) ReadAllMemory() (d []byte, err error) {
 }
 
 func (c *CMOS) WriteAllMemory(d []byte) (err error) {
+	start := time.Now()
+	defer func() {
+		c.recordStats(start, err, func(s *Stats) *OpStats { return &s.Dumps })
+	}()
+
 	if len(d) < int(cmosSize) {
 		return fmt.Errorf("nvram: Not enough data.")
 	}
-	// Write buffer to entire CMOS area.
+	// Write buffer to entire CMOS area with the checksum last, so an
+	// interrupted write leaves a bad checksum.
 	// Ignore RTC area.
+	sum := c.checksum.index
+	hasSum := c.checksum.kind != ChecksumNone && verifyCMOSByteIndex(sum) &&
+		verifyCMOSByteIndex(sum+1)
 	for i := cmosRTCAreaSize; i < cmosSize; i++ {
+		if hasSum && (i == sum || i == sum+1) {
+			continue
+		}
 		err = c.WriteByte(i, d[i])
 		if err != nil {
 			return
 		}
 	}
+	if hasSum {
+		err = c.WriteByte(sum, d[sum])
+		if err == nil {
+			err = c.WriteByte(sum+1, d[sum+1])
+		}
+	}
 	return
 }
 
 func (c *CMOS) ReadByte(off uint) (byte, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
 	// Read byte using current accessor
 	if c.accessor == nil {
 		return 0, ErrCMOSNotOpen
 	}
-	return c.accessor.ReadByte(off)
+
+	var b byte
+	start := time.Now()
+	err := c.withRetry(func() (err error) {
+		b, err = c.accessor.ReadByte(off)
+		return
+	})
+	c.recordStats(start, err, func(s *Stats) *OpStats { return &s.ByteReads })
+	c.traceOp(TraceOpRead, off, b, err)
+	return b, c.accessError("read", off, err)
 }
 
 func (c *CMOS) WriteByte(off uint, b byte) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
 	// Write byte using current accessor
 	if c.accessor == nil {
 		return ErrCMOSNotOpen
 	}
-	return c.accessor.WriteByte(off, b)
+	start := time.Now()
+	err := c.withRetry(func() error {
+		return c.writeByteVerified(off, b)
+	})
+	c.recordStats(start, err, func(s *Stats) *OpStats { return &s.ByteWrites })
+	c.traceOp(TraceOpWrite, off, b, err)
+
+	// Keep shadow copy up to date with our own writes.
+	if err == nil && c.shadow != nil {
+		c.shadow[off] = b
+	}
+	return c.accessError("write", off, err)
+}
+
+// accessError adds the operation, offset and backend to an accessor error,
+// e.g. "nvram: write byte 0x3A via /dev/port: input/output error".
+func (c *CMOS) accessError(op string, off uint, err error) error {
+	if err == nil {
+		return nil
+	}
+	if _, ok := err.(*VerifyError); ok {
+		return err
+	}
+	return fmt.Errorf("nvram: %s byte 0x%02X via %s: %w", op, off, c.backend, err)
 }
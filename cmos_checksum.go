@@ -9,8 +9,31 @@ import (
 	"github.com/platinasystems/nvram/debug"
 )
 
+// ChecksumType is the checksum algorithm of a coreboot option table
+// checksum record.
+type ChecksumType uint32
+
+const (
+	// ChecksumNone means the CMOS options are not checksummed.
+	ChecksumNone ChecksumType = 0
+	// ChecksumPCBIOS is the 16-bit sum of the checksummed bytes stored
+	// big endian. It is used for layouts without a checksum type.
+	ChecksumPCBIOS ChecksumType = 1
+)
+
+func (t ChecksumType) String() string {
+	switch t {
+	case ChecksumNone:
+		return "none"
+	case ChecksumPCBIOS:
+		return "pcbios"
+	}
+	return fmt.Sprintf("type-%d", uint32(t))
+}
+
 type CMOSChecksum struct {
 	start, end, index uint
+	kind              ChecksumType
 }
 
 func (c CMOSChecksum) String() string {
@@ -70,7 +93,23 @@ func NewCMOSChecksum(start, end, index uint) (c *CMOSChecksum, err error) {
 	debug.Trace(debug.LevelMSG3, "Valid checksum %d %d %d\n", start, end, index)
 
 	// Create new checksum with byte values
-	c = &CMOSChecksum{start: start, end: end, index: index}
+	c = &CMOSChecksum{start: start, end: end, index: index, kind: ChecksumPCBIOS}
 
 	return
 }
+
+// Type returns the checksum algorithm.
+func (c CMOSChecksum) Type() ChecksumType {
+	return c.kind
+}
+
+// setType sets the checksum algorithm, refusing algorithms that are not
+// known, so a checksum is never "repaired" with the wrong algorithm.
+func (c *CMOSChecksum) setType(t ChecksumType) error {
+	switch t {
+	case ChecksumNone, ChecksumPCBIOS:
+		c.kind = t
+		return nil
+	}
+	return fmt.Errorf("Unknown checksum type %d.", uint32(t))
+}
@@ -17,12 +17,34 @@ const (
 	CMOSEntryReserved CMOSEntryConfig = 'r'
 )
 
+// CMOSEntry is a parameter of a layout. Entries returned by a Layout, such
+// as by FindCMOSEntry or GetCMOSEntriesList, are copies. Their setters only
+// change the copy, which takes effect through UpdateCMOSEntry, or through
+// AddCMOSEntry for a new entry.
 type CMOSEntry struct {
-	bit       uint
-	length    uint
-	config    CMOSEntryConfig
-	config_id uint
-	name      string
+	bit         uint
+	length      uint
+	config      CMOSEntryConfig
+	config_id   uint
+	name        string
+	description string
+	meta        cmosEntryMeta
+}
+
+// NewCMOSEntry returns an entry of length bits at bit with the given config
+// type and enum id for adding to a layout.
+func NewCMOSEntry(bit, length uint, config CMOSEntryConfig, configId uint, name string) (e *CMOSEntry, err error) {
+	e = &CMOSEntry{bit: bit, length: length, config: config, config_id: configId, name: name}
+	err = verifyCMOSEntry(e)
+	if err != nil {
+		e = nil
+	}
+	return
+}
+
+func (e *CMOSEntry) copy() *CMOSEntry {
+	c := *e
+	return &c
 }
 
 func (e CMOSEntry) String() string {
@@ -49,6 +71,15 @@ func (e CMOSEntry) Name() string {
 	return e.name
 }
 
+func (e CMOSEntry) Description() string {
+	return e.description
+}
+
+// SetDescription sets the description shown for the entry.
+func (e *CMOSEntry) SetDescription(description string) {
+	e.description = description
+}
+
 func verifyCMOSEntry(e *CMOSEntry) error {
 	// Check if entry is out of range.
 	if (e.bit >= (8 * cmosSize)) || ((e.bit + e.length) > (8 * cmosSize)) {
@@ -0,0 +1,239 @@
+// Copyright © 2019 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+package nvram
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// cmosEntryMeta holds optional entry metadata from the layout's metadata
+// section. It is not part of the coreboot option table.
+type cmosEntryMeta struct {
+	unit   string
+	base   int
+	min    uint64
+	max    uint64
+	hasMin bool
+	hasMax bool
+
+	deprecated  bool
+	deprecation string
+
+	reboot   bool
+	unique   bool
+	critical bool
+	group    string
+
+	def interface{}
+}
+
+// Unit returns the unit of a hex entry's value, e.g. "MHz".
+func (e CMOSEntry) Unit() string {
+	return e.meta.unit
+}
+
+// DisplayBase returns 10 or 16 for the base hex entry values are shown in.
+func (e CMOSEntry) DisplayBase() int {
+	if e.meta.base == 0 {
+		return 16
+	}
+	return e.meta.base
+}
+
+// Range returns the minimum and maximum allowed values of a hex entry.
+func (e CMOSEntry) Range() (min, max uint64) {
+	min = e.meta.min
+	max = ^uint64(0)
+	if e.length < 64 {
+		max = (uint64(1) << e.length) - 1
+	}
+	if e.meta.hasMax && e.meta.max < max {
+		max = e.meta.max
+	}
+	return
+}
+
+// RequiresReboot returns true if the firmware only uses a new value of the
+// entry after a reboot.
+func (e CMOSEntry) RequiresReboot() bool {
+	return e.meta.reboot
+}
+
+// Unique returns true if the entry holds a value specific to one machine,
+// such as a serial number, that is not cloned or restored from other
+// machines.
+func (e CMOSEntry) Unique() bool {
+	return e.meta.unique
+}
+
+// Critical returns true if the entry selects how the firmware uses other
+// entries, such as boot_option, and is written after them.
+func (e CMOSEntry) Critical() bool {
+	return e.meta.critical
+}
+
+// Default returns the default value of the entry in parameter form, a
+// uint64 for hex and a string for enum and string entries.
+func (e CMOSEntry) Default() (value interface{}, ok bool) {
+	return e.meta.def, e.meta.def != nil
+}
+
+// SetUnit sets the unit of the entry value, such as "baud".
+func (e *CMOSEntry) SetUnit(unit string) {
+	e.meta.unit = unit
+}
+
+// SetDisplayBase sets the base, 10 or 16, hex values are displayed in.
+func (e *CMOSEntry) SetDisplayBase(base int) (err error) {
+	if base != 10 && base != 16 {
+		return fmt.Errorf("CMOS entry %s display base %d is not 10 or 16.", e.name, base)
+	}
+	e.meta.base = base
+	return
+}
+
+// SetRange limits the values that may be written to the entry.
+func (e *CMOSEntry) SetRange(min, max uint64) (err error) {
+	if min > max {
+		return fmt.Errorf("CMOS entry %s minimum 0x%X above maximum 0x%X.", e.name, min, max)
+	}
+	e.meta.min, e.meta.hasMin = min, true
+	e.meta.max, e.meta.hasMax = max, true
+	return
+}
+
+// SetRequiresReboot marks the entry as only used after a reboot.
+func (e *CMOSEntry) SetRequiresReboot(reboot bool) {
+	e.meta.reboot = reboot
+}
+
+// SetUnique marks the entry as specific to one machine.
+func (e *CMOSEntry) SetUnique(unique bool) {
+	e.meta.unique = unique
+}
+
+// SetCritical marks the entry to be written after other entries.
+func (e *CMOSEntry) SetCritical(critical bool) {
+	e.meta.critical = critical
+}
+
+func (e *CMOSEntry) formatValue(value interface{}) string {
+	// Hex entries may be displayed in decimal.
+	if n, ok := value.(uint64); ok && e.DisplayBase() == 10 {
+		return strconv.FormatUint(n, 10)
+	}
+	return formatParameterValue(value)
+}
+
+func (e *CMOSEntry) verifyRange(n uint64) error {
+	min, max := e.Range()
+	if n < min || n > max {
+		return fmt.Errorf("Value %s for CMOS parameter %s is outside %s..%s.",
+			e.formatValue(n), e.name, e.formatValue(min), e.formatValue(max))
+	}
+	return nil
+}
+
+// parseCMOSEntryMeta parses the fields of a metadata line after the entry
+// name. Fields are either key=value pairs or flags.
+//
+//	baud_rate unit=baud base=10 min=1200 max=115200
+//	old_name deprecated=use_new_name
+//	new_name alias=old_name
+//	boot_option reboot default=Fallback
+//	serial_number unique
+//	boot_option critical
+//	cpu_freq group=power
+func (l *Layout) parseCMOSEntryMeta(e *CMOSEntry, fields []string) (err error) {
+	for _, field := range fields {
+		kv := strings.SplitN(field, "=", 2)
+		key := kv[0]
+		value := ""
+		if len(kv) == 2 {
+			value = kv[1]
+		}
+
+		switch key {
+		case "unit":
+			e.meta.unit = value
+		case "base":
+			switch value {
+			case "10", "dec":
+				e.meta.base = 10
+			case "16", "hex":
+				e.meta.base = 16
+			default:
+				err = fmt.Errorf("Unknown base %s", value)
+				return
+			}
+		case "min":
+			e.meta.min, err = strconv.ParseUint(value, 0, 64)
+			if err != nil {
+				return
+			}
+			e.meta.hasMin = true
+		case "max":
+			e.meta.max, err = strconv.ParseUint(value, 0, 64)
+			if err != nil {
+				return
+			}
+			e.meta.hasMax = true
+		case "deprecated":
+			e.meta.deprecated = true
+			e.meta.deprecation = value
+		case "reboot":
+			e.meta.reboot = true
+		case "unique":
+			e.meta.unique = true
+		case "critical":
+			e.meta.critical = true
+		case "group":
+			e.meta.group = value
+		case "default":
+			e.meta.def, err = l.parseCMOSEntryDefault(e, value)
+			if err != nil {
+				return
+			}
+		case "alias":
+			err = l.AddCMOSAlias(value, e.name)
+			if err != nil {
+				return
+			}
+		default:
+			err = fmt.Errorf("Unknown metadata %s", key)
+			return
+		}
+	}
+
+	if e.config != CMOSEntryHex && (e.meta.base != 0 || e.meta.hasMin || e.meta.hasMax) {
+		err = fmt.Errorf("Display base and range only apply to hex entries")
+		return
+	}
+	if e.meta.hasMin && e.meta.hasMax && e.meta.min > e.meta.max {
+		err = fmt.Errorf("Minimum above maximum")
+	}
+	return
+}
+
+func (l *Layout) parseCMOSEntryDefault(e *CMOSEntry, s string) (value interface{}, err error) {
+	switch e.config {
+	case CMOSEntryHex:
+		var n uint64
+		n, err = strconv.ParseUint(s, 0, 64)
+		value = n
+	case CMOSEntryEnum:
+		if _, ok := l.findCMOSEnumValue(e.config_id, s); !ok {
+			err = fmt.Errorf("Bad default %s", s)
+		}
+		value = s
+	case CMOSEntryString:
+		value = s
+	default:
+		err = fmt.Errorf("Reserved entries have no default")
+	}
+	return
+}
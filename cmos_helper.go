@@ -0,0 +1,227 @@
+// Copyright © 2019 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+package nvram
+
+import (
+	"errors"
+	"fmt"
+	"github.com/platinasystems/nvram/debug"
+	"io"
+	"net"
+	"sync"
+)
+
+// Privileged helper protocol. Each request is an operation byte, an offset
+// and a value. Each reply is a status and a value, followed for
+// helperStatusError by a length prefixed error message.
+const (
+	helperOpRead  byte = 'R'
+	helperOpWrite byte = 'W'
+	helperOpRTC   byte = 'S'
+
+	helperStatusOK              byte = 0
+	helperStatusInvalidIndex    byte = 1
+	helperStatusNotOpen         byte = 2
+	helperStatusInvalidRegister byte = 3
+	helperStatusRTCNotSupported byte = 4
+	helperStatusError           byte = 5
+
+	// DefaultHelperSocket is where nvram-helper listens by default.
+	DefaultHelperSocket = "/run/nvram-helper.sock"
+)
+
+// PeerCred holds the credentials of the process at the other end of a
+// helper connection.
+type PeerCred struct {
+	Pid int32
+	Uid uint32
+	Gid uint32
+}
+
+var helperErrors = map[byte]error{
+	helperStatusInvalidIndex:    ErrInvalidCMOSIndex,
+	helperStatusNotOpen:         ErrCMOSNotOpen,
+	helperStatusInvalidRegister: ErrInvalidRTCRegister,
+	helperStatusRTCNotSupported: ErrRTCNotSupported,
+}
+
+// CMOSHelper accesses CMOS through a privileged helper process serving
+// ServeHelper on a unix socket, so the caller needs no I/O privileges.
+type CMOSHelper struct {
+	conn *net.UnixConn
+	path string
+}
+
+func (c *CMOSHelper) Open(path string) (err error) {
+	// Close in case it is already opened
+	c.Close()
+
+	debug.Trace(debug.LevelMSG1, "Opening CMOS helper %s\n", path)
+
+	c.path = path
+	c.conn, err = net.DialUnix("unix", nil, &net.UnixAddr{Name: path, Net: "unix"})
+	return
+}
+
+func (c *CMOSHelper) String() string {
+	return "helper:" + c.path
+}
+
+func (c *CMOSHelper) Close() (err error) {
+	if c.conn != nil {
+		err = c.conn.Close()
+		c.conn = nil
+	}
+	return
+}
+
+func (c *CMOSHelper) call(op byte, off uint, b byte) (v byte, err error) {
+	if c.conn == nil {
+		return 0, ErrCMOSNotOpen
+	}
+	if off > 0xFF {
+		return 0, ErrInvalidCMOSIndex
+	}
+
+	// Send request
+	if _, err = c.conn.Write([]byte{op, byte(off), b}); err != nil {
+		return
+	}
+
+	// Read reply
+	reply := make([]byte, 2)
+	if _, err = io.ReadFull(c.conn, reply); err != nil {
+		return
+	}
+	switch status := reply[0]; status {
+	case helperStatusOK:
+		v = reply[1]
+	case helperStatusError:
+		msg := make([]byte, reply[1])
+		if _, err = io.ReadFull(c.conn, msg); err == nil {
+			err = fmt.Errorf("nvram: helper: %s", msg)
+		}
+	default:
+		err = helperErrors[status]
+		if err == nil {
+			err = fmt.Errorf("nvram: helper: Unknown status %d", status)
+		}
+	}
+	return
+}
+
+func (c *CMOSHelper) ReadByte(off uint) (byte, error) {
+	return c.call(helperOpRead, off, 0)
+}
+
+func (c *CMOSHelper) WriteByte(off uint, b byte) error {
+	_, err := c.call(helperOpWrite, off, b)
+	return err
+}
+
+func (c *CMOSHelper) ReadRTCRegister(reg uint) (byte, error) {
+	return c.call(helperOpRTC, reg, 0)
+}
+
+func (c *CMOS) OpenHelper(path string) (err error) {
+	// Close in case it is already opened
+	c.Close()
+
+	// Open CMOS helper accessor.
+	accessor := new(CMOSHelper)
+	err = accessor.Open(path)
+	if err != nil {
+		return
+	}
+
+	c.setAccessor(accessor)
+	return
+}
+
+// WithHelperSocket makes Open use the privileged helper listening on path
+// instead of accessing the CMOS hardware directly.
+func WithHelperSocket(path string) Option {
+	return func(nv *NVRAM) {
+		nv.helperSocket = path
+	}
+}
+
+// ServeHelper serves CMOS byte reads and writes on accessor to clients
+// connecting to l, usually from CMOSHelper. If allow is not nil, it is
+// called with the credentials of each connecting process and the
+// connection is refused unless it returns true. On systems without peer
+// credentials every connection is refused when allow is set. ServeHelper
+// returns when l is closed.
+func ServeHelper(l *net.UnixListener, accessor CMOSer, allow func(cred *PeerCred) bool) error {
+	var mu sync.Mutex
+	for {
+		conn, err := l.AcceptUnix()
+		if err != nil {
+			return err
+		}
+
+		if allow != nil {
+			cred, err := peerCred(conn)
+			if err != nil || !allow(cred) {
+				debug.Trace(debug.LevelMSG1, "CMOS helper refused client %v\n", cred)
+				conn.Close()
+				continue
+			}
+		}
+
+		go serveHelperConn(conn, accessor, &mu)
+	}
+}
+
+func serveHelperConn(conn *net.UnixConn, accessor CMOSer, mu *sync.Mutex) {
+	defer conn.Close()
+
+	req := make([]byte, 3)
+	for {
+		if _, err := io.ReadFull(conn, req); err != nil {
+			return
+		}
+
+		// Perform request with the accessor locked for other clients
+		var v byte
+		var err error
+		mu.Lock()
+		switch req[0] {
+		case helperOpRead:
+			v, err = accessor.ReadByte(uint(req[1]))
+		case helperOpWrite:
+			err = accessor.WriteByte(uint(req[1]), req[2])
+		case helperOpRTC:
+			if r, ok := accessor.(RTCReader); ok {
+				v, err = r.ReadRTCRegister(uint(req[1]))
+			} else {
+				err = ErrRTCNotSupported
+			}
+		default:
+			err = fmt.Errorf("Unknown operation %d", req[0])
+		}
+		mu.Unlock()
+
+		if _, err = conn.Write(helperReply(v, err)); err != nil {
+			return
+		}
+	}
+}
+
+func helperReply(v byte, err error) []byte {
+	if err == nil {
+		return []byte{helperStatusOK, v}
+	}
+	for status, e := range helperErrors {
+		if errors.Is(err, e) {
+			return []byte{status, 0}
+		}
+	}
+	msg := err.Error()
+	if len(msg) > 0xFF {
+		msg = msg[:0xFF]
+	}
+	return append([]byte{helperStatusError, byte(len(msg))}, msg...)
+}
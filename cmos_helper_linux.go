@@ -0,0 +1,32 @@
+// Copyright © 2019 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+//go:build linux
+// +build linux
+
+package nvram
+
+import (
+	"net"
+	"syscall"
+)
+
+func peerCred(conn *net.UnixConn) (cred *PeerCred, err error) {
+	raw, err := conn.SyscallConn()
+	if err != nil {
+		return
+	}
+	var ucred *syscall.Ucred
+	cerr := raw.Control(func(fd uintptr) {
+		ucred, err = syscall.GetsockoptUcred(int(fd), syscall.SOL_SOCKET, syscall.SO_PEERCRED)
+	})
+	if cerr != nil {
+		err = cerr
+	}
+	if err != nil {
+		return
+	}
+	cred = &PeerCred{Pid: ucred.Pid, Uid: ucred.Uid, Gid: ucred.Gid}
+	return
+}
@@ -0,0 +1,18 @@
+// Copyright © 2019 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+//go:build !linux
+// +build !linux
+
+package nvram
+
+import (
+	"errors"
+	"net"
+)
+
+func peerCred(conn *net.UnixConn) (cred *PeerCred, err error) {
+	err = errors.New("nvram: Peer credentials are not supported on this system.")
+	return
+}
@@ -8,16 +8,17 @@ import (
 	"fmt"
 	"github.com/platinasystems/nvram/debug"
 	"os"
-	"syscall"
-)
-
-const (
-	sys_iopl   = 172 //amd64
-	sys_ioperm = 173 //amd64
 )
 
 type CMOSHW struct {
 	port_file *os.File
+	ports     CMOSPorts
+	nmi       NMIMode
+	nmiBit    byte
+	direct    bool
+	thread    *portThread
+	priv      IOPrivilege
+	raised    bool
 }
 
 func (c *CMOSHW) Open() (err error) {
@@ -33,11 +34,15 @@ func (c *CMOSHW) Open() (err error) {
 
 	debug.Trace(debug.LevelMSG1, "Opening CMOS HW\n")
 
-	// Set IO privilege level to 3. 
-	if _, _, errno := syscall.Syscall(sys_iopl,
-		uintptr(3), 0, 0); errno != 0 {
-		return errno
+	// Raise IO privilege level, Close drops it again.
+	if c.priv == nil {
+		c.priv = defaultIOPrivilege()
 	}
+	err = c.priv.Raise()
+	if err != nil {
+		return
+	}
+	c.raised = true
 
 	// Open device ports for access to CMOS NVRAM
 	c.port_file, err = os.OpenFile("/dev/port", os.O_RDWR|os.O_SYNC, 0755)
@@ -45,17 +50,29 @@ func (c *CMOSHW) Open() (err error) {
 		return
 	}
 
+	// Select NMI bit of index port writes
+	err = c.initNMI()
+	if err != nil {
+		return
+	}
+
+	// Start thread for direct port access if selected
+	err = c.startDirectIO()
 	return
 }
 
+func (c *CMOSHW) String() string {
+	return "/dev/port"
+}
+
 func (c *CMOSHW) Close() error {
 
 	debug.Trace(debug.LevelMSG1, "Closing CMOS HW\n")
 
-	// Set IO privilege level to normal
-	if _, _, errno := syscall.Syscall(sys_iopl,
-		uintptr(0), 0, 0); errno != 0 {
-		return errno
+	// Stop direct port access thread if started
+	if c.thread != nil {
+		c.thread.stop()
+		c.thread = nil
 	}
 
 	// Close port file if opened
@@ -64,6 +81,12 @@ func (c *CMOSHW) Close() error {
 		c.port_file = nil
 	}
 
+	// Set IO privilege level to normal if raised
+	if c.raised {
+		c.raised = false
+		return c.priv.Drop()
+	}
+
 	return nil
 }
 
@@ -76,22 +99,10 @@ func (c *CMOSHW) ReadByte(off uint) (byte, error) {
 	}
 
 	// Find port0 and 1 to set CMOS data offset
-	var port_0, port_1 int64
-	if off < 128 {
-		port_0 = 0x70
-		port_1 = 0x71
-	} else {
-		port_0 = 0x72
-		port_1 = 0x73
-	}
-
-	// Set offset
-	if err := c.ioWriteReg8(port_0, byte(off)); err != nil {
-		return 0, err
-	}
+	port_0, port_1 := c.bankPorts(off)
 
-	// Read data from NVRAM at offset
-	return c.ioReadReg8(port_1)
+	// Set offset and read data from NVRAM at offset
+	return c.readPair(port_0, c.indexByte(off), port_1)
 }
 
 func (c *CMOSHW) WriteByte(off uint, b byte) error {
@@ -104,26 +115,23 @@ func (c *CMOSHW) WriteByte(off uint, b byte) error {
 	}
 
 	// Find port0 and 1 to set CMOS data offset
-	var port_0, port_1 int64
-	if off < 128 {
-		port_0 = 0x70
-		port_1 = 0x71
-	} else {
-		port_0 = 0x72
-		port_1 = 0x73
-	}
+	port_0, port_1 := c.bankPorts(off)
 
-	// Set offset
-	if err := c.ioWriteReg8(port_0, byte(off)); err != nil {
-		return err
-	}
+	// Set offset and write data to NVRAM at offset
+	return c.writePair(port_0, c.indexByte(off), port_1, b)
+}
 
-	// Write data to NVRAM at offset
-	if err := c.ioWriteReg8(port_1, b); err != nil {
-		return err
+func (c *CMOSHW) ReadRTCRegister(reg uint) (byte, error) {
+	if c.port_file == nil {
+		return 0, ErrCMOSNotOpen
+	}
+	if !verifyRTCRegister(reg) {
+		return 0, ErrInvalidRTCRegister
 	}
 
-	return nil
+	// Set RTC register index and read RTC register
+	port_0, port_1 := c.bankPorts(reg)
+	return c.readPair(port_0, c.indexByte(reg), port_1)
 }
 
 func (c *CMOSHW) ioReadReg8(addr int64) (b byte, err error) {
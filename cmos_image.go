@@ -0,0 +1,68 @@
+// Copyright © 2019 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+package nvram
+
+import (
+	"fmt"
+)
+
+// CMOSImage accesses a CMOS image held in memory, such as a dump read
+// from a file.
+type CMOSImage struct {
+	mem []byte
+}
+
+func (c *CMOSImage) Open(image []byte) (err error) {
+	if len(image) < int(cmosSize) {
+		return fmt.Errorf("nvram: CMOS image smaller than %d bytes.", cmosSize)
+	}
+	c.mem = image
+	return
+}
+
+func (c *CMOSImage) String() string {
+	return "image"
+}
+
+func (c *CMOSImage) Close() (err error) {
+	c.mem = nil
+	return
+}
+
+func (c *CMOSImage) ReadByte(off uint) (byte, error) {
+	if len(c.mem) == 0 {
+		return 0, ErrCMOSNotOpen
+	}
+	if !verifyCMOSByteIndex(off) {
+		return 0, ErrInvalidCMOSIndex
+	}
+	return c.mem[off], nil
+}
+
+func (c *CMOSImage) WriteByte(off uint, b byte) error {
+	if len(c.mem) == 0 {
+		return ErrCMOSNotOpen
+	}
+	if !verifyCMOSByteIndex(off) {
+		return ErrInvalidCMOSIndex
+	}
+	c.mem[off] = b
+	return nil
+}
+
+// openImage returns an NVRAM decoding parameters of layout from a CMOS
+// image without taking the NVRAM access lock.
+func openImage(layout *Layout, image []byte) (nv *NVRAM, err error) {
+	accessor := new(CMOSImage)
+	err = accessor.Open(image)
+	if err != nil {
+		return
+	}
+
+	nv = &NVRAM{Layout: layout}
+	nv.CMOS.setAccessor(accessor)
+	nv.CMOS.checksum = *layout.cmosChecksum
+	return
+}
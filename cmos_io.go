@@ -0,0 +1,85 @@
+// Copyright © 2019 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+package nvram
+
+import (
+	"fmt"
+	"io"
+)
+
+// readerWriterAt is a CMOS accessor over random access byte storage.
+type readerWriterAt struct {
+	r    io.ReaderAt
+	w    io.WriterAt
+	size uint
+}
+
+// NewCMOSFromReaderWriterAt returns a CMOS accessor reading CMOS byte off at
+// offset off of r and writing it at the same offset of w. The storage holds
+// size bytes, at most 256. If w is nil the CMOS is read only. Use it with
+// WithAccessor.
+func NewCMOSFromReaderWriterAt(r io.ReaderAt, w io.WriterAt, size uint) CMOSer {
+	if size > cmosSize {
+		size = cmosSize
+	}
+	return &readerWriterAt{r: r, w: w, size: size}
+}
+
+func (c *readerWriterAt) String() string {
+	if s, ok := c.r.(fmt.Stringer); ok {
+		return s.String()
+	}
+	return fmt.Sprintf("%T", c.r)
+}
+
+func (c *readerWriterAt) Close() error {
+	return nil
+}
+
+func (c *readerWriterAt) verify(off uint) error {
+	if !verifyCMOSByteIndex(off) || off >= c.size {
+		return ErrInvalidCMOSIndex
+	}
+	return nil
+}
+
+func (c *readerWriterAt) ReadByte(off uint) (byte, error) {
+	if err := c.verify(off); err != nil {
+		return 0, err
+	}
+	b := make([]byte, 1)
+	if _, err := c.r.ReadAt(b, int64(off)); err != nil {
+		return 0, err
+	}
+	return b[0], nil
+}
+
+func (c *readerWriterAt) WriteByte(off uint, b byte) error {
+	if err := c.verify(off); err != nil {
+		return err
+	}
+	if c.w == nil {
+		return ErrCMOSReadOnly
+	}
+	_, err := c.w.WriteAt([]byte{b}, int64(off))
+	return err
+}
+
+// WithAccessor makes Open use accessor for CMOS access when no CMOS memory
+// file is given, e.g. one returned by NewCMOSFromReaderWriterAt. Open takes
+// ownership and closes it on Close.
+func WithAccessor(accessor CMOSer) Option {
+	return func(nv *NVRAM) {
+		nv.accessor = accessor
+	}
+}
+
+// OpenAccessor uses an accessor that is already open.
+func (c *CMOS) OpenAccessor(accessor CMOSer) {
+	// Close in case it is already opened
+	c.Close()
+
+	c.setAccessor(accessor)
+}
@@ -0,0 +1,28 @@
+// Copyright © 2019 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+package nvram
+
+// IOPrivilege raises the I/O privilege level of the process for CMOS
+// hardware access and drops it again. The default is iopl on Linux amd64
+// and 386 and NoIOPrivilege elsewhere.
+type IOPrivilege interface {
+	Raise() error
+	Drop() error
+}
+
+// NoIOPrivilege is an IOPrivilege that does nothing, for platforms and CI
+// runs where /dev/port access needs no raised privilege level.
+type NoIOPrivilege struct{}
+
+func (NoIOPrivilege) Raise() error { return nil }
+func (NoIOPrivilege) Drop() error  { return nil }
+
+// WithIOPrivilege sets how CMOS hardware access raises the I/O privilege
+// level.
+func WithIOPrivilege(p IOPrivilege) Option {
+	return func(nv *NVRAM) {
+		nv.CMOS.ioPriv = p
+	}
+}
@@ -0,0 +1,30 @@
+// Copyright © 2019 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+//go:build linux && (amd64 || 386)
+// +build linux
+// +build amd64 386
+
+package nvram
+
+import (
+	"syscall"
+)
+
+// ioplPrivilege sets the I/O privilege level with iopl.
+type ioplPrivilege struct{}
+
+func iopl(level uintptr) error {
+	if _, _, errno := syscall.Syscall(syscall.SYS_IOPL, level, 0, 0); errno != 0 {
+		return errno
+	}
+	return nil
+}
+
+func (ioplPrivilege) Raise() error { return iopl(3) }
+func (ioplPrivilege) Drop() error  { return iopl(0) }
+
+func defaultIOPrivilege() IOPrivilege {
+	return ioplPrivilege{}
+}
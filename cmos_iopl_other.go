@@ -0,0 +1,12 @@
+// Copyright © 2019 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+//go:build !linux || (!amd64 && !386)
+// +build !linux !amd64,!386
+
+package nvram
+
+func defaultIOPrivilege() IOPrivilege {
+	return NoIOPrivilege{}
+}
@@ -0,0 +1,126 @@
+// Copyright © 2019 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+package nvram
+
+import (
+	"fmt"
+	"github.com/platinasystems/nvram/debug"
+	"os"
+	"syscall"
+)
+
+// CMOSMapped accesses RTC RAM that a SoC exposes memory mapped, such as
+// behind an LPC or eSPI bridge, through /dev/mem.
+type CMOSMapped struct {
+	mem_file *os.File
+	mapping  []byte
+	mem      []byte
+	addr     uint64
+}
+
+// WithMappedRTCRAM makes Open use the RTC RAM mapped at physical address
+// addr instead of port I/O, when no CMOS memory file is given. Size is the
+// number of bytes mapped, at most 256.
+func WithMappedRTCRAM(addr uint64, size uint) Option {
+	return func(nv *NVRAM) {
+		nv.mappedAddr, nv.mappedSize = addr, size
+	}
+}
+
+func (c *CMOSMapped) Open(addr uint64, size uint) (err error) {
+	// Close in case it is already opened
+	c.Close()
+
+	// Close on any error
+	defer func() {
+		if err != nil {
+			c.Close()
+		}
+	}()
+
+	debug.Trace(debug.LevelMSG1, "Opening CMOS mapped at 0x%X\n", addr)
+
+	if size == 0 || size > cmosSize {
+		return fmt.Errorf("nvram: Mapped RTC RAM size %d not in 1..%d.", size, cmosSize)
+	}
+	c.addr = addr
+
+	c.mem_file, err = os.OpenFile("/dev/mem", os.O_RDWR|os.O_SYNC, 0)
+	if err != nil {
+		return
+	}
+
+	// Map the pages holding the range.
+	pagesize := uint64(os.Getpagesize())
+	base := addr &^ (pagesize - 1)
+	length := (addr - base + uint64(size) + pagesize - 1) &^ (pagesize - 1)
+	c.mapping, err = syscall.Mmap(int(c.mem_file.Fd()), int64(base), int(length),
+		syscall.PROT_READ|syscall.PROT_WRITE, syscall.MAP_SHARED)
+	if err != nil {
+		return
+	}
+	c.mem = c.mapping[addr-base : addr-base+uint64(size)]
+	return
+}
+
+func (c *CMOSMapped) String() string {
+	return fmt.Sprintf("mem:0x%X", c.addr)
+}
+
+func (c *CMOSMapped) Close() (err error) {
+	debug.Trace(debug.LevelMSG1, "Closing CMOS mapped\n")
+
+	c.mem = nil
+	if len(c.mapping) > 0 {
+		syscall.Munmap(c.mapping)
+		c.mapping = nil
+	}
+	if c.mem_file != nil {
+		c.mem_file.Close()
+		c.mem_file = nil
+	}
+	return
+}
+
+func (c *CMOSMapped) ReadByte(off uint) (byte, error) {
+	if err := verifyMappedByte(c.mem, off); err != nil {
+		return 0, err
+	}
+	return c.mem[off], nil
+}
+
+func (c *CMOSMapped) WriteByte(off uint, b byte) error {
+	if err := verifyMappedByte(c.mem, off); err != nil {
+		return err
+	}
+	c.mem[off] = b
+	return nil
+}
+
+// verifyMappedByte checks a CMOS byte access to mapped memory.
+func verifyMappedByte(mem []byte, off uint) error {
+	if len(mem) == 0 {
+		return ErrCMOSNotOpen
+	}
+	if !verifyCMOSByteIndex(off) || off >= uint(len(mem)) {
+		return ErrInvalidCMOSIndex
+	}
+	return nil
+}
+
+func (c *CMOS) OpenMapped(addr uint64, size uint) (err error) {
+	// Close in case it is already opened
+	c.Close()
+
+	// Open mapped CMOS accessor.
+	accessor := new(CMOSMapped)
+	err = accessor.Open(addr, size)
+	if err != nil {
+		return
+	}
+
+	c.setAccessor(accessor)
+	return
+}
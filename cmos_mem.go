@@ -9,11 +9,33 @@ import (
 	"github.com/platinasystems/nvram/debug"
 	"os"
 	"syscall"
+	"unsafe"
 )
 
+// WithMemFileSize sets the size CMOS memory files shorter than it are
+// extended to with zeros when opened. The default is the 256 byte CMOS
+// size.
+func WithMemFileSize(size uint) Option {
+	return func(nv *NVRAM) {
+		nv.CMOS.memSize = size
+	}
+}
+
+// WithCreateMemFile makes Open create a missing CMOS memory file, zero
+// filled to the CMOS size, for bootstrapping simulation files.
+func WithCreateMemFile() Option {
+	return func(nv *NVRAM) {
+		nv.CMOS.memCreate = true
+	}
+}
+
 type CMOSMem struct {
 	mem_file *os.File
 	mem      []byte
+	filename string
+	sync     bool
+	size     uint
+	create   bool
 }
 
 func (c *CMOSMem) Open(filename string) (err error) {
@@ -29,8 +51,14 @@ func (c *CMOSMem) Open(filename string) (err error) {
 
 	debug.Trace(debug.LevelMSG1, "Opening CMOS Mem file %s\n", filename)
 
-	// Open CMOS data file
-	c.mem_file, err = os.OpenFile(filename, os.O_RDWR|os.O_SYNC, 0)
+	c.filename = filename
+
+	// Open CMOS data file, creating it if selected
+	flags := os.O_RDWR | os.O_SYNC
+	if c.create {
+		flags |= os.O_CREATE
+	}
+	c.mem_file, err = os.OpenFile(filename, flags, 0644)
 	if err != nil {
 		return
 	}
@@ -46,6 +74,21 @@ func (c *CMOSMem) Open(filename string) (err error) {
 		return
 	}
 
+	// Extend short files with zeros to the CMOS size
+	want := int64(c.size)
+	if want == 0 {
+		want = int64(cmosSize)
+	}
+	if size < want {
+		debug.Trace(debug.LevelMSG1, "Extending CMOS Mem file %s from %d to %d bytes\n",
+			filename, size, want)
+		err = c.mem_file.Truncate(want)
+		if err != nil {
+			return
+		}
+		size = want
+	}
+
 	// Memory map file for access.
 	c.mem, err = syscall.Mmap(int(c.mem_file.Fd()), 0, int(size),
 		syscall.PROT_READ|syscall.PROT_WRITE, syscall.MAP_SHARED)
@@ -58,10 +101,17 @@ func (c *CMOSMem) Open(filename string) (err error) {
 	return
 }
 
+func (c *CMOSMem) String() string {
+	return c.filename
+}
+
 func (c *CMOSMem) Close() (err error) {
 
 	debug.Trace(debug.LevelMSG1, "Closing CMOS Mem\n")
 
+	// Write any changes to the file before unmapping it
+	err = c.Sync()
+
 	// Unmap file if it has been mapped
 	if len(c.mem) > 0 {
 		syscall.Munmap(c.mem)
@@ -78,22 +128,49 @@ func (c *CMOSMem) Close() (err error) {
 }
 
 func (c *CMOSMem) ReadByte(off uint) (byte, error) {
-	if len(c.mem) == 0 {
-		return 0, ErrCMOSNotOpen
-	}
-	if !verifyCMOSByteIndex(off) {
-		return 0, ErrInvalidCMOSIndex
+	if err := verifyMappedByte(c.mem, off); err != nil {
+		return 0, err
 	}
 	return c.mem[off], nil
 }
 
 func (c *CMOSMem) WriteByte(off uint, b byte) error {
-	if len(c.mem) == 0 {
-		return ErrCMOSNotOpen
-	}
-	if !verifyCMOSByteIndex(off) {
-		return ErrInvalidCMOSIndex
+	if err := verifyMappedByte(c.mem, off); err != nil {
+		return err
 	}
 	c.mem[off] = b
+	if c.sync {
+		return c.Sync()
+	}
 	return nil
 }
+
+// SetSize sets the size short files are extended to on Open. Zero selects
+// the 256 byte CMOS size.
+func (c *CMOSMem) SetSize(size uint) {
+	c.size = size
+}
+
+// SetCreate makes Open create a missing file.
+func (c *CMOSMem) SetCreate(create bool) {
+	c.create = create
+}
+
+// SetSynchronous makes every write reach the file on stable storage before
+// it returns.
+func (c *CMOSMem) SetSynchronous(sync bool) {
+	c.sync = sync
+}
+
+// Sync writes the mapped CMOS memory to the file with msync and flushes the
+// file to stable storage with fsync.
+func (c *CMOSMem) Sync() (err error) {
+	if len(c.mem) == 0 || c.mem_file == nil {
+		return
+	}
+	if _, _, errno := syscall.Syscall(syscall.SYS_MSYNC,
+		uintptr(unsafe.Pointer(&c.mem[0])), uintptr(len(c.mem)), syscall.MS_SYNC); errno != 0 {
+		return fmt.Errorf("nvram: msync %s failed: %v", c.filename, errno)
+	}
+	return c.mem_file.Sync()
+}
@@ -0,0 +1,97 @@
+// Copyright © 2019 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+package nvram
+
+import (
+	"fmt"
+)
+
+// NMIMode selects bit 7 of the values written to the lower bank index
+// port. Many chipsets use the bit to disable the NMI.
+type NMIMode int
+
+const (
+	// NMIEnable writes the offset as is, with bit 7 clear, enabling the
+	// NMI on chipsets that use the bit. This is the default.
+	NMIEnable NMIMode = iota
+	// NMIDisable sets bit 7, disabling the NMI.
+	NMIDisable
+	// NMIPreserve keeps bit 7 as read from the index port when the
+	// hardware is opened. It relies on the chipset returning the last
+	// index written, chipsets with a write only index port read 0xFF and
+	// so disable the NMI.
+	NMIPreserve
+)
+
+var nmiModes = []string{"enable", "disable", "preserve"}
+
+func (m NMIMode) String() string {
+	if m >= 0 && int(m) < len(nmiModes) {
+		return nmiModes[m]
+	}
+	return fmt.Sprintf("NMIMode(%d)", int(m))
+}
+
+// ParseNMIMode parses the form written by NMIMode.String.
+func ParseNMIMode(s string) (m NMIMode, err error) {
+	for i, name := range nmiModes {
+		if s == name {
+			return NMIMode(i), nil
+		}
+	}
+	err = fmt.Errorf("Unknown NMI mode %s", s)
+	return
+}
+
+// WithNMIMode sets how CMOS hardware access writes the NMI bit of the
+// lower bank index port.
+func WithNMIMode(m NMIMode) Option {
+	return func(nv *NVRAM) {
+		nv.CMOS.nmi = m
+	}
+}
+
+// SetNMIMode sets the NMI mode used from the next Open.
+func (c *CMOSHW) SetNMIMode(m NMIMode) {
+	c.nmi = m
+}
+
+// NMIMode returns the NMI mode of the accessor.
+func (c *CMOSHW) NMIMode() NMIMode {
+	return c.nmi
+}
+
+// NMIDisabled returns true if index port writes set the NMI disable bit.
+// For NMIPreserve it reports the bit read at Open.
+func (c *CMOSHW) NMIDisabled() bool {
+	return c.nmiBit != 0
+}
+
+// initNMI sets the NMI bit for the accessor's mode.
+func (c *CMOSHW) initNMI() (err error) {
+	switch c.nmi {
+	case NMIEnable:
+		c.nmiBit = 0
+	case NMIDisable:
+		c.nmiBit = 0x80
+	case NMIPreserve:
+		index, _ := c.bankPorts(0)
+		var b byte
+		b, err = c.ioReadReg8(index)
+		c.nmiBit = b & 0x80
+	default:
+		err = fmt.Errorf("nvram: Unknown NMI mode %v.", c.nmi)
+	}
+	return
+}
+
+// indexByte returns the value written to the index port for a CMOS byte or
+// RTC register.
+func (c *CMOSHW) indexByte(off uint) byte {
+	if off < 128 {
+		return byte(off) | c.nmiBit
+	}
+	return byte(off)
+}
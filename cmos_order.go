@@ -0,0 +1,65 @@
+// Copyright © 2019 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+package nvram
+
+// WithSafeWriteOrder orders writes so an interrupted write degrades
+// gracefully. Bits are cleared before bits are set within a CMOS byte and
+// WriteCMOSParameters writes parameters marked critical in the layout
+// last, e.g. boot_option after the settings of the boot target it selects.
+func WithSafeWriteOrder() Option {
+	return func(nv *NVRAM) {
+		nv.CMOS.safeOrder = true
+	}
+}
+
+// updateByte writes an entry byte. With safe ordering a byte that both
+// clears and sets bits is first written with only the bits cleared.
+func (c *CMOS) updateByte(off uint, b byte) (err error) {
+	if !c.safeOrder {
+		return c.WriteByte(off, b)
+	}
+
+	n, err := c.ReadByte(off)
+	if err != nil {
+		return
+	}
+	if n&^b != 0 && b&^n != 0 {
+		err = c.WriteByte(off, n&b)
+		if err != nil {
+			return
+		}
+	}
+	return c.WriteByte(off, b)
+}
+
+// orderParameters returns params with parameters of critical entries moved
+// last if safe ordering is enabled, keeping the order within each group.
+func (nv *NVRAM) orderParameters(params []Parameter) []Parameter {
+	if !nv.CMOS.safeOrder {
+		return params
+	}
+
+	ordered := make([]Parameter, 0, len(params))
+	var critical []Parameter
+	for _, p := range params {
+		if nv.isCritical(p.Name) {
+			critical = append(critical, p)
+		} else {
+			ordered = append(ordered, p)
+		}
+	}
+	return append(ordered, critical...)
+}
+
+// isCritical returns true if the named parameter, or the parent of a
+// subfield, is marked critical.
+func (nv *NVRAM) isCritical(name string) bool {
+	name = nv.resolveName(name)
+	if s, ok := nv.FindCMOSSubfield(name); ok {
+		name = s.parent.name
+	}
+	e, ok := nv.entry(name)
+	return ok && e.meta.critical
+}
@@ -0,0 +1,126 @@
+// Copyright © 2019 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+package nvram
+
+import (
+	"runtime"
+)
+
+// WithDirectPortIO makes CMOS hardware access use in and out instructions
+// with ioperm port permissions instead of /dev/port. Accesses run on one
+// goroutine locked to its OS thread, which holds the permissions, and the
+// index and data port of a byte are accessed back to back without system
+// calls in between. This narrows the window in which other software
+// touching the index port can corrupt an access. Only supported on amd64
+// and 386.
+func WithDirectPortIO() Option {
+	return func(nv *NVRAM) {
+		nv.CMOS.directIO = true
+	}
+}
+
+// SetDirectPortIO selects direct port I/O from the next Open.
+func (c *CMOSHW) SetDirectPortIO(direct bool) {
+	c.direct = direct
+}
+
+type portRequest struct {
+	index, data uint16
+	indexValue  byte
+	write       bool
+	value       byte
+	reply       chan byte
+}
+
+// portThread serves index and data port accesses on a locked OS thread.
+type portThread struct {
+	requests chan portRequest
+}
+
+// startPortThread starts a thread with permission to access ports.
+func startPortThread(ports []uint16) (t *portThread, err error) {
+	t = &portThread{requests: make(chan portRequest)}
+	started := make(chan error)
+	go func() {
+		// The permissions granted by ioperm only apply to this thread.
+		runtime.LockOSThread()
+		for _, port := range ports {
+			if err := ioperm(port, true); err != nil {
+				started <- err
+				return
+			}
+		}
+		started <- nil
+
+		for r := range t.requests {
+			outb(r.index, r.indexValue)
+			if r.write {
+				outb(r.data, r.value)
+				r.reply <- 0
+			} else {
+				r.reply <- inb(r.data)
+			}
+		}
+		for _, port := range ports {
+			ioperm(port, false)
+		}
+		runtime.UnlockOSThread()
+	}()
+
+	err = <-started
+	if err != nil {
+		t = nil
+	}
+	return
+}
+
+func (t *portThread) access(r portRequest) byte {
+	r.reply = make(chan byte, 1)
+	t.requests <- r
+	return <-r.reply
+}
+
+func (t *portThread) stop() {
+	close(t.requests)
+}
+
+// readPair writes the index port and reads the data port.
+func (c *CMOSHW) readPair(index int64, indexValue byte, data int64) (byte, error) {
+	if c.thread != nil {
+		return c.thread.access(portRequest{index: uint16(index), indexValue: indexValue,
+			data: uint16(data)}), nil
+	}
+	if err := c.ioWriteReg8(index, indexValue); err != nil {
+		return 0, err
+	}
+	return c.ioReadReg8(data)
+}
+
+// writePair writes the index port and the data port.
+func (c *CMOSHW) writePair(index int64, indexValue byte, data int64, b byte) error {
+	if c.thread != nil {
+		c.thread.access(portRequest{index: uint16(index), indexValue: indexValue,
+			data: uint16(data), write: true, value: b})
+		return nil
+	}
+	if err := c.ioWriteReg8(index, indexValue); err != nil {
+		return err
+	}
+	return c.ioWriteReg8(data, b)
+}
+
+// startDirectIO starts the port thread if direct port I/O is selected.
+func (c *CMOSHW) startDirectIO() (err error) {
+	if !c.direct {
+		return
+	}
+	p := c.ports
+	if p == (CMOSPorts{}) {
+		p = DefaultCMOSPorts
+	}
+	c.thread, err = startPortThread([]uint16{p.LowerIndex, p.LowerData,
+		p.UpperIndex, p.UpperData})
+	return
+}
@@ -0,0 +1,22 @@
+// Copyright © 2019 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+//go:build linux
+// +build linux
+
+#include "textflag.h"
+
+// func inb(port uint16) byte
+TEXT ·inb(SB), NOSPLIT, $0-5
+	MOVW port+0(FP), DX
+	INB
+	MOVB AX, ret+4(FP)
+	RET
+
+// func outb(port uint16, b byte)
+TEXT ·outb(SB), NOSPLIT, $0-3
+	MOVW port+0(FP), DX
+	MOVB b+2(FP), AX
+	OUTB
+	RET
@@ -0,0 +1,20 @@
+// Copyright © 2019 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+//go:build !linux || (!amd64 && !386)
+// +build !linux !amd64,!386
+
+package nvram
+
+import (
+	"fmt"
+	"runtime"
+)
+
+func inb(port uint16) byte     { return 0xFF }
+func outb(port uint16, b byte) {}
+
+func ioperm(port uint16, on bool) error {
+	return fmt.Errorf("nvram: Direct port I/O not supported on %s/%s.", runtime.GOOS, runtime.GOARCH)
+}
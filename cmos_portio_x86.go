@@ -0,0 +1,28 @@
+// Copyright © 2019 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+//go:build linux && (amd64 || 386)
+// +build linux
+// +build amd64 386
+
+package nvram
+
+import (
+	"syscall"
+)
+
+// inb and outb are implemented in assembly.
+func inb(port uint16) byte
+func outb(port uint16, b byte)
+
+func ioperm(port uint16, on bool) error {
+	turnOn := uintptr(0)
+	if on {
+		turnOn = 1
+	}
+	if _, _, errno := syscall.Syscall(syscall.SYS_IOPERM, uintptr(port), 1, turnOn); errno != 0 {
+		return errno
+	}
+	return nil
+}
@@ -0,0 +1,75 @@
+// Copyright © 2019 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+package nvram
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// CMOSPorts are the I/O port pairs used to access the lower 128 CMOS bytes,
+// which include the RTC registers, and the upper 128 bytes.
+type CMOSPorts struct {
+	LowerIndex, LowerData uint16
+	UpperIndex, UpperData uint16
+}
+
+// DefaultCMOSPorts are the standard RTC ports.
+var DefaultCMOSPorts = CMOSPorts{LowerIndex: 0x70, LowerData: 0x71,
+	UpperIndex: 0x72, UpperData: 0x73}
+
+func (p CMOSPorts) String() string {
+	return fmt.Sprintf("0x%X,0x%X,0x%X,0x%X", p.LowerIndex, p.LowerData,
+		p.UpperIndex, p.UpperData)
+}
+
+// ParseCMOSPorts parses ports in the form written by CMOSPorts.String, the
+// lower bank index and data port followed by the upper bank ports, e.g.
+// "0x70,0x71,0x74,0x75".
+func ParseCMOSPorts(s string) (p CMOSPorts, err error) {
+	fields := strings.Split(s, ",")
+	if len(fields) != 4 {
+		err = fmt.Errorf("Expected four CMOS ports in %q", s)
+		return
+	}
+	ports := []*uint16{&p.LowerIndex, &p.LowerData, &p.UpperIndex, &p.UpperData}
+	for i, field := range fields {
+		var n uint64
+		n, err = strconv.ParseUint(strings.TrimSpace(field), 0, 16)
+		if err != nil {
+			err = fmt.Errorf("Bad CMOS port %q", field)
+			return
+		}
+		*ports[i] = uint16(n)
+	}
+	return
+}
+
+// WithCMOSPorts sets the I/O ports used for CMOS hardware access, for
+// platforms with the upper bank on alternate ports such as 0x74/0x75.
+func WithCMOSPorts(p CMOSPorts) Option {
+	return func(nv *NVRAM) {
+		nv.CMOS.ports = p
+	}
+}
+
+// SetPorts sets the I/O ports used by the accessor. The zero value selects
+// DefaultCMOSPorts.
+func (c *CMOSHW) SetPorts(p CMOSPorts) {
+	c.ports = p
+}
+
+// bankPorts returns the index and data port for a CMOS byte.
+func (c *CMOSHW) bankPorts(off uint) (index, data int64) {
+	p := c.ports
+	if p == (CMOSPorts{}) {
+		p = DefaultCMOSPorts
+	}
+	if off < 128 {
+		return int64(p.LowerIndex), int64(p.LowerData)
+	}
+	return int64(p.UpperIndex), int64(p.UpperData)
+}
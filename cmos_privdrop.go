@@ -0,0 +1,69 @@
+// Copyright © 2019 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+package nvram
+
+import (
+	"github.com/platinasystems/nvram/debug"
+	"os"
+)
+
+// PortHelperFD is the file descriptor on which a command run by
+// ReceivePortFile finds the socket to send the /dev/port file on.
+const PortHelperFD = 3
+
+// privDrop holds the ids to switch to after opening CMOS hardware.
+type privDrop struct {
+	uid, gid int
+}
+
+// WithDropPrivileges makes Open drop privileges once the CMOS hardware is
+// open: the I/O privilege level is lowered and the process switches to
+// group gid and user uid, keeping the open /dev/port file. Switching from
+// root drops all capabilities, so the privileges can not be regained. A
+// negative id keeps the current one, WithDropPrivileges(-1, -1) only
+// lowers the I/O privilege level. A later Open of the hardware fails.
+func WithDropPrivileges(uid, gid int) Option {
+	return func(nv *NVRAM) {
+		nv.CMOS.drop = &privDrop{uid: uid, gid: gid}
+	}
+}
+
+// WithPortFile makes Open access the CMOS hardware through f, a /dev/port
+// file opened by a privileged process, for example one received with
+// ReceivePortFile. The I/O privilege level is not raised. Open takes
+// ownership of f and closes it on Close.
+func WithPortFile(f *os.File) Option {
+	return func(nv *NVRAM) {
+		nv.CMOS.portFile = f
+	}
+}
+
+// OpenFile opens CMOS hardware access through f, an open /dev/port file,
+// without raising the I/O privilege level.
+func (c *CMOSHW) OpenFile(f *os.File) (err error) {
+	// Close in case it is already opened
+	c.Close()
+
+	// Close on any error
+	defer func() {
+		if err != nil {
+			c.Close()
+		}
+	}()
+
+	debug.Trace(debug.LevelMSG1, "Opening CMOS HW on %s\n", f.Name())
+
+	c.port_file = f
+
+	// Select NMI bit of index port writes
+	err = c.initNMI()
+	if err != nil {
+		return
+	}
+
+	// Start thread for direct port access if selected
+	err = c.startDirectIO()
+	return
+}
@@ -0,0 +1,120 @@
+// Copyright © 2019 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+//go:build linux
+// +build linux
+
+package nvram
+
+import (
+	"fmt"
+	"github.com/platinasystems/nvram/debug"
+	"os"
+	"os/exec"
+	"syscall"
+)
+
+// DropPrivileges lowers the I/O privilege level and switches to group gid
+// and user uid, keeping the open port file. Negative ids are kept. It
+// needs Go 1.16 or later, earlier releases can not change the ids of all
+// threads and fail with EOPNOTSUPP.
+func (c *CMOSHW) DropPrivileges(uid, gid int) (err error) {
+	if c.port_file == nil {
+		return ErrCMOSNotOpen
+	}
+
+	debug.Trace(debug.LevelMSG1, "Dropping CMOS HW privileges to %d:%d\n", uid, gid)
+
+	// Port file access needs no raised privilege level.
+	if c.raised {
+		c.raised = false
+		err = c.priv.Drop()
+		if err != nil {
+			return
+		}
+	}
+
+	// Drop supplementary groups and the group before the user, which
+	// takes the right to change them.
+	if gid >= 0 {
+		if err = syscall.Setgroups(nil); err != nil {
+			return fmt.Errorf("nvram: Dropping groups failed: %v", err)
+		}
+		if err = syscall.Setgid(gid); err != nil {
+			return fmt.Errorf("nvram: Setting group %d failed: %v", gid, err)
+		}
+	}
+	if uid >= 0 {
+		if err = syscall.Setuid(uid); err != nil {
+			return fmt.Errorf("nvram: Setting user %d failed: %v", uid, err)
+		}
+	}
+	return
+}
+
+// ReceivePortFile runs a privileged helper command, such as
+// "sudo nvram-helper -send-port", and returns the /dev/port file it sends
+// with SendPortFile. Only the helper runs privileged, the caller can use
+// the file WithPortFile.
+func ReceivePortFile(name string, args ...string) (f *os.File, err error) {
+	fds, err := syscall.Socketpair(syscall.AF_UNIX, syscall.SOCK_STREAM|syscall.SOCK_CLOEXEC, 0)
+	if err != nil {
+		return
+	}
+	local := os.NewFile(uintptr(fds[0]), "port-helper")
+	remote := os.NewFile(uintptr(fds[1]), "port-helper")
+	defer local.Close()
+
+	// Start helper with the remote end of the socket.
+	cmd := exec.Command(name, args...)
+	cmd.Stderr = os.Stderr
+	cmd.ExtraFiles = []*os.File{remote} // PortHelperFD
+	err = cmd.Start()
+	remote.Close()
+	if err != nil {
+		return
+	}
+
+	// Receive the file, then wait for the helper to exit.
+	buf := make([]byte, 1)
+	oob := make([]byte, syscall.CmsgSpace(4))
+	_, oobn, _, _, rerr := syscall.Recvmsg(int(local.Fd()), buf, oob, 0)
+	werr := cmd.Wait()
+	if rerr != nil {
+		return nil, fmt.Errorf("nvram: Receiving port file failed: %v", rerr)
+	}
+	if werr != nil {
+		return nil, fmt.Errorf("nvram: Port helper %s failed: %v", name, werr)
+	}
+
+	msgs, err := syscall.ParseSocketControlMessage(oob[:oobn])
+	if err != nil {
+		return
+	}
+	for _, m := range msgs {
+		var rights []int
+		rights, err = syscall.ParseUnixRights(&m)
+		if err == nil && len(rights) == 1 {
+			syscall.CloseOnExec(rights[0])
+			return os.NewFile(uintptr(rights[0]), "/dev/port"), nil
+		}
+	}
+	return nil, fmt.Errorf("nvram: Port helper %s sent no file.", name)
+}
+
+// SendPortFile opens /dev/port and sends it on the socket a command run by
+// ReceivePortFile finds at PortHelperFD.
+func SendPortFile() (err error) {
+	f, err := os.OpenFile("/dev/port", os.O_RDWR|os.O_SYNC, 0)
+	if err != nil {
+		return
+	}
+	defer f.Close()
+
+	s := os.NewFile(PortHelperFD, "port-helper")
+	defer s.Close()
+
+	rights := syscall.UnixRights(int(f.Fd()))
+	return syscall.Sendmsg(int(s.Fd()), []byte{0}, rights, nil, 0)
+}
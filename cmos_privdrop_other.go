@@ -0,0 +1,30 @@
+// Copyright © 2019 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+//go:build !linux
+// +build !linux
+
+package nvram
+
+import (
+	"errors"
+	"os"
+)
+
+var errPrivDropNotSupported = errors.New("nvram: Privilege dropping and port file passing are not supported on this system.")
+
+// DropPrivileges is not supported on this system.
+func (c *CMOSHW) DropPrivileges(uid, gid int) error {
+	return errPrivDropNotSupported
+}
+
+// ReceivePortFile is not supported on this system.
+func ReceivePortFile(name string, args ...string) (*os.File, error) {
+	return nil, errPrivDropNotSupported
+}
+
+// SendPortFile is not supported on this system.
+func SendPortFile() error {
+	return errPrivDropNotSupported
+}
@@ -0,0 +1,70 @@
+// Copyright © 2019 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+package nvram
+
+import (
+	"errors"
+	"github.com/platinasystems/nvram/debug"
+	"syscall"
+	"time"
+)
+
+// RetryPolicy controls retrying CMOS byte accesses that fail with a
+// transient error.
+type RetryPolicy struct {
+	// Count is the number of retries after the first attempt.
+	Count int
+	// Delay is the wait before the first retry. It doubles for every
+	// further retry up to MaxDelay if that is set.
+	Delay    time.Duration
+	MaxDelay time.Duration
+	// Retryable classifies errors worth retrying. IsTransientError is
+	// used if it is nil.
+	Retryable func(err error) bool
+}
+
+// IsTransientError reports errors that /dev/port accesses return
+// occasionally under load and that usually succeed when retried.
+func IsTransientError(err error) bool {
+	for _, errno := range []syscall.Errno{syscall.EINTR, syscall.EAGAIN,
+		syscall.EBUSY, syscall.EIO} {
+		if errors.Is(err, errno) {
+			return true
+		}
+	}
+	return false
+}
+
+// WithRetryPolicy retries failed CMOS byte reads and writes according to
+// the policy.
+func WithRetryPolicy(p RetryPolicy) Option {
+	return func(nv *NVRAM) {
+		nv.CMOS.retry = p
+	}
+}
+
+func (c *CMOS) withRetry(op func() error) (err error) {
+	retryable := c.retry.Retryable
+	if retryable == nil {
+		retryable = IsTransientError
+	}
+
+	delay := c.retry.Delay
+	for attempt := 0; ; attempt++ {
+		err = op()
+		if err == nil || attempt >= c.retry.Count || !retryable(err) {
+			return
+		}
+
+		debug.Trace(debug.LevelMSG2, "Retrying CMOS access after %v\n", err)
+
+		// Back off before the next attempt
+		time.Sleep(delay)
+		delay *= 2
+		if c.retry.MaxDelay > 0 && delay > c.retry.MaxDelay {
+			delay = c.retry.MaxDelay
+		}
+	}
+}
@@ -0,0 +1,81 @@
+// Copyright © 2019 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+package nvram
+
+const (
+	rtcRegStatusA uint = 0x0A
+	rtcRegStatusB uint = 0x0B
+	rtcRegStatusC uint = 0x0C
+	rtcRegStatusD uint = 0x0D
+
+	// Valid RAM and Time bit in status register D. It is clear after
+	// the RTC lost power.
+	rtcStatusDVRT byte = 0x80
+)
+
+// RTCReader is implemented by CMOS accessors that can read the RTC
+// registers below the CMOS RAM.
+type RTCReader interface {
+	ReadRTCRegister(reg uint) (byte, error)
+}
+
+// verifyRTCRegister allows the RTC status registers that have no side
+// effects when read. Reading status register C acknowledges interrupts.
+func verifyRTCRegister(reg uint) bool {
+	return reg == rtcRegStatusA || reg == rtcRegStatusB || reg == rtcRegStatusD
+}
+
+// RTCStatus holds the RTC status registers A, B and D.
+type RTCStatus struct {
+	A byte
+	B byte
+	D byte
+}
+
+// BatteryGood reports the Valid RAM and Time bit. When clear, the RTC and
+// CMOS RAM lost power and any CMOS settings have been lost.
+func (s RTCStatus) BatteryGood() bool {
+	return s.D&rtcStatusDVRT != 0
+}
+
+func (c *CMOS) ReadRTCStatus() (s RTCStatus, err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.accessor == nil {
+		err = ErrCMOSNotOpen
+		return
+	}
+	r, ok := c.accessor.(RTCReader)
+	if !ok {
+		err = ErrRTCNotSupported
+		return
+	}
+
+	if s.A, err = r.ReadRTCRegister(rtcRegStatusA); err != nil {
+		return
+	}
+	if s.B, err = r.ReadRTCRegister(rtcRegStatusB); err != nil {
+		return
+	}
+	s.D, err = r.ReadRTCRegister(rtcRegStatusD)
+	return
+}
+
+// RTCStatus reads the RTC status registers. It returns ErrRTCNotSupported
+// for CMOS backends without an RTC such as memory files.
+func (nv *NVRAM) RTCStatus() (RTCStatus, error) {
+	return nv.CMOS.ReadRTCStatus()
+}
+
+// BatteryGood reports whether the CMOS battery kept the CMOS contents.
+func (nv *NVRAM) BatteryGood() (ok bool, err error) {
+	s, err := nv.RTCStatus()
+	if err != nil {
+		return
+	}
+	ok = s.BatteryGood()
+	return
+}
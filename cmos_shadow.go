@@ -0,0 +1,92 @@
+// Copyright © 2019 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+package nvram
+
+// ByteRange is an inclusive range of CMOS byte offsets.
+type ByteRange struct {
+	Start uint
+	End   uint
+}
+
+// startShadow keeps a copy of the checksummed area and checksum bytes that
+// is updated by every write through this CMOS, so writes by anybody else
+// can be detected.
+func (c *CMOS) startShadow() (err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	shadow := make([]byte, cmosSize)
+	for _, i := range c.shadowIndexes() {
+		shadow[i], err = c.accessor.ReadByte(i)
+		if err != nil {
+			return
+		}
+	}
+	c.shadow = shadow
+	return
+}
+
+func (c *CMOS) stopShadow() {
+	c.mu.Lock()
+	c.shadow = nil
+	c.mu.Unlock()
+}
+
+func (c *CMOS) shadowIndexes() (indexes []uint) {
+	for i := c.checksum.start; i <= c.checksum.end; i++ {
+		indexes = append(indexes, i)
+	}
+	return append(indexes, c.checksum.index, c.checksum.index+1)
+}
+
+// compareShadow returns the ranges of bytes that no longer match the
+// shadow copy.
+func (c *CMOS) compareShadow() (ranges []ByteRange, err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.shadow == nil {
+		return
+	}
+
+	for _, i := range c.shadowIndexes() {
+		var b byte
+		b, err = c.accessor.ReadByte(i)
+		if err != nil {
+			return
+		}
+		if b == c.shadow[i] {
+			continue
+		}
+
+		// Extend last range or start a new one
+		if n := len(ranges); n > 0 && ranges[n-1].End+1 == i {
+			ranges[n-1].End = i
+		} else {
+			ranges = append(ranges, ByteRange{Start: i, End: i})
+		}
+	}
+	return
+}
+
+// restoreShadow writes the shadow copy back over the given ranges.
+func (c *CMOS) restoreShadow(ranges []ByteRange) (err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.shadow == nil {
+		return
+	}
+
+	for _, r := range ranges {
+		for i := r.Start; i <= r.End; i++ {
+			err = c.accessor.WriteByte(i, c.shadow[i])
+			if err != nil {
+				return
+			}
+		}
+	}
+	return
+}
@@ -0,0 +1,91 @@
+// Copyright © 2019 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+package nvram
+
+import (
+	"fmt"
+	"time"
+)
+
+// OpStats counts operations of one kind and their cumulative latency.
+type OpStats struct {
+	Count  uint64
+	Errors uint64
+	Total  time.Duration
+}
+
+func (s *OpStats) record(start time.Time, err error) {
+	s.Count++
+	if err != nil {
+		s.Errors++
+	}
+	s.Total += time.Since(start)
+}
+
+// Stats holds operation statistics of one CMOS backend.
+type Stats struct {
+	ByteReads  OpStats
+	ByteWrites OpStats
+	Checksums  OpStats
+	Dumps      OpStats
+}
+
+func backendName(a CMOSer) string {
+	if s, ok := a.(fmt.Stringer); ok {
+		return s.String()
+	}
+	return fmt.Sprintf("%T", a)
+}
+
+func (c *CMOS) setAccessor(a CMOSer) {
+	c.accessor = a
+	c.backend = backendName(a)
+}
+
+// recordStats adds an operation to the statistics of the current backend.
+func (c *CMOS) recordStats(start time.Time, err error, op func(s *Stats) *OpStats) {
+	c.statsMu.Lock()
+	defer c.statsMu.Unlock()
+
+	if c.stats == nil {
+		c.stats = make(map[string]*Stats)
+	}
+	s, ok := c.stats[c.backend]
+	if !ok {
+		s = new(Stats)
+		c.stats[c.backend] = s
+	}
+	op(s).record(start, err)
+}
+
+// Stats returns operation statistics for each CMOS backend used since the
+// statistics were last reset.
+func (c *CMOS) Stats() map[string]Stats {
+	c.statsMu.Lock()
+	defer c.statsMu.Unlock()
+
+	stats := make(map[string]Stats)
+	for backend, s := range c.stats {
+		stats[backend] = *s
+	}
+	return stats
+}
+
+func (c *CMOS) ResetStats() {
+	c.statsMu.Lock()
+	c.stats = nil
+	c.statsMu.Unlock()
+}
+
+// Stats returns counts and cumulative latencies of CMOS byte reads and
+// writes, checksum computations and full dumps for each backend.
+func (nv *NVRAM) Stats() map[string]Stats {
+	return nv.CMOS.Stats()
+}
+
+// ResetStats clears the statistics returned by Stats.
+func (nv *NVRAM) ResetStats() {
+	nv.CMOS.ResetStats()
+}
@@ -0,0 +1,45 @@
+// Copyright © 2019 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+package nvram
+
+// Syncer is implemented by CMOS accessors that buffer writes, such as
+// CMOSMem, to write them to stable storage.
+type Syncer interface {
+	Sync() error
+}
+
+// WithSyncWrites makes every write to a CMOS memory file reach stable
+// storage before it returns, for files used as the CMOS of virtual machines
+// that must survive a host crash. Without it writes reach the file on
+// Flush and Close.
+func WithSyncWrites() Option {
+	return func(nv *NVRAM) {
+		nv.CMOS.syncMem = true
+	}
+}
+
+// Flush writes buffered CMOS writes to stable storage if the accessor
+// buffers them.
+func (c *CMOS) Flush() (err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.accessor == nil {
+		return ErrCMOSNotOpen
+	}
+	if s, ok := c.accessor.(Syncer); ok {
+		err = s.Sync()
+	}
+	return
+}
+
+// Flush writes the CMOS data written so far to stable storage. The
+// checksum is not updated, Close still does that.
+func (nv *NVRAM) Flush() (err error) {
+	span := nv.startSpan("nvram.Flush")
+	defer nv.endSpan(span, &err)
+
+	return nv.CMOS.Flush()
+}
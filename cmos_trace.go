@@ -0,0 +1,96 @@
+// Copyright © 2019 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+package nvram
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+)
+
+const (
+	TraceOpRead  = "read"
+	TraceOpWrite = "write"
+)
+
+// TraceRecord is one CMOS byte access in a trace written by WithTrace.
+// Traces hold one JSON encoded record per line.
+type TraceRecord struct {
+	Time    time.Time `json:"time"`
+	Backend string    `json:"backend"`
+	Op      string    `json:"op"`
+	Offset  uint      `json:"offset"`
+	Value   byte      `json:"value"`
+	Err     string    `json:"err,omitempty"`
+}
+
+// WithTrace records every CMOS byte read and write to w. The trace can be
+// applied to a CMOS memory file with ReplayTrace.
+func WithTrace(w io.Writer) Option {
+	return func(nv *NVRAM) {
+		nv.CMOS.trace = json.NewEncoder(w)
+	}
+}
+
+func (c *CMOS) traceOp(op string, off uint, b byte, err error) {
+	if c.trace == nil {
+		return
+	}
+	rec := TraceRecord{Time: time.Now(), Backend: c.backend, Op: op,
+		Offset: off, Value: b}
+	if err != nil {
+		rec.Err = err.Error()
+	}
+	c.trace.Encode(&rec)
+}
+
+// ReplayTrace applies the successful writes of a trace to a CMOS accessor,
+// usually a CMOSMem, in their recorded order. If checkReads is set the
+// successful reads of the trace are compared against the accessor and the
+// replay stops at the first mismatch.
+func ReplayTrace(r io.Reader, accessor CMOSer, checkReads bool) (err error) {
+	dec := json.NewDecoder(r)
+	for n := 1; ; n++ {
+		var rec TraceRecord
+		err = dec.Decode(&rec)
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return
+		}
+
+		// Failed accesses did not change the CMOS
+		if rec.Err != "" {
+			continue
+		}
+
+		switch rec.Op {
+		case TraceOpWrite:
+			err = accessor.WriteByte(rec.Offset, rec.Value)
+			if err != nil {
+				return
+			}
+		case TraceOpRead:
+			if !checkReads {
+				continue
+			}
+			var b byte
+			b, err = accessor.ReadByte(rec.Offset)
+			if err != nil {
+				return
+			}
+			if b != rec.Value {
+				err = fmt.Errorf("nvram: Trace record %d read 0x%02X from CMOS byte 0x%02X, replay read 0x%02X.",
+					n, rec.Value, rec.Offset, b)
+				return
+			}
+		default:
+			err = fmt.Errorf("nvram: Trace record %d has unknown operation %s.", n, rec.Op)
+			return
+		}
+	}
+}
@@ -0,0 +1,48 @@
+// Copyright © 2019 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+package nvram
+
+import (
+	"fmt"
+)
+
+// VerifyError is returned when a CMOS byte read back after a write does
+// not match the value written, e.g. for a locked CMOS range.
+type VerifyError struct {
+	Offset uint
+	Wrote  byte
+	Read   byte
+}
+
+func (e *VerifyError) Error() string {
+	return fmt.Sprintf("nvram: Wrote 0x%02X to CMOS byte 0x%02X but read back 0x%02X.",
+		e.Wrote, e.Offset, e.Read)
+}
+
+// WithVerifyWrites reads back every CMOS byte written and returns a
+// *VerifyError if it does not match. Mismatches are retried like other
+// errors if the RetryPolicy's Retryable function accepts them.
+func WithVerifyWrites() Option {
+	return func(nv *NVRAM) {
+		nv.CMOS.verify = true
+	}
+}
+
+func (c *CMOS) writeByteVerified(off uint, b byte) (err error) {
+	err = c.accessor.WriteByte(off, b)
+	if err != nil || !c.verify {
+		return
+	}
+
+	// Read back and compare
+	n, err := c.accessor.ReadByte(off)
+	if err != nil {
+		return
+	}
+	if n != b {
+		err = &VerifyError{Offset: off, Wrote: b, Read: n}
+	}
+	return
+}
@@ -0,0 +1,139 @@
+// Copyright © 2019 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+package nvram
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Roots of the proc and sys file systems scanned by Conflicts.
+var (
+	procRoot = "/proc"
+	sysRoot  = "/sys"
+)
+
+// cmosDevices are the device files giving access to CMOS.
+var cmosDevices = []string{"/dev/nvram", "/dev/port"}
+
+// Conflict is another user of CMOS that may change CMOS bytes behind the
+// NVRAM's back.
+type Conflict struct {
+	// PID and Command identify a process holding Path open. PID is 0
+	// for kernel drivers.
+	PID     int
+	Command string
+	Path    string
+	Reason  string
+}
+
+func (c Conflict) String() string {
+	if c.PID == 0 {
+		return fmt.Sprintf("%s: %s", c.Path, c.Reason)
+	}
+	return fmt.Sprintf("process %d (%s) has %s open", c.PID, c.Command, c.Path)
+}
+
+// Conflicts makes a best effort search for other CMOS users: processes
+// holding /dev/nvram or /dev/port open and rtc-cmos RTCs with a wake alarm
+// set, which the kernel writes to the RTC alarm registers. Processes that
+// can not be inspected are skipped.
+func Conflicts() (conflicts []Conflict, err error) {
+	procs, err := ioutil.ReadDir(procRoot)
+	if err != nil {
+		return
+	}
+
+	self := os.Getpid()
+	for _, p := range procs {
+		pid, perr := strconv.Atoi(p.Name())
+		if perr != nil || pid == self {
+			continue
+		}
+		fdDir := filepath.Join(procRoot, p.Name(), "fd")
+		fds, ferr := ioutil.ReadDir(fdDir)
+		if ferr != nil {
+			continue
+		}
+		for _, path := range openCMOSDevices(fdDir, fds) {
+			comm, _ := ioutil.ReadFile(filepath.Join(procRoot, p.Name(), "comm"))
+			conflicts = append(conflicts, Conflict{PID: pid,
+				Command: strings.TrimSpace(string(comm)), Path: path})
+		}
+	}
+
+	conflicts = append(conflicts, rtcAlarmConflicts()...)
+	return
+}
+
+// openCMOSDevices returns the CMOS devices among the open files of a
+// process, each once.
+func openCMOSDevices(fdDir string, fds []os.FileInfo) (paths []string) {
+	seen := make(map[string]bool)
+	for _, fd := range fds {
+		target, err := os.Readlink(filepath.Join(fdDir, fd.Name()))
+		if err != nil || seen[target] {
+			continue
+		}
+		for _, dev := range cmosDevices {
+			if target == dev {
+				seen[target] = true
+				paths = append(paths, target)
+			}
+		}
+	}
+	return
+}
+
+func rtcAlarmConflicts() (conflicts []Conflict) {
+	rtcs, _ := filepath.Glob(filepath.Join(sysRoot, "class/rtc/rtc*"))
+	for _, rtc := range rtcs {
+		name, err := ioutil.ReadFile(filepath.Join(rtc, "name"))
+		if err != nil || !strings.HasPrefix(strings.TrimSpace(string(name)), "rtc_cmos") {
+			continue
+		}
+		alarm, err := ioutil.ReadFile(filepath.Join(rtc, "wakealarm"))
+		if err != nil || strings.TrimSpace(string(alarm)) == "" {
+			continue
+		}
+		conflicts = append(conflicts, Conflict{Path: rtc,
+			Reason: "rtc-cmos wake alarm set, the kernel writes the RTC alarm registers"})
+	}
+	return
+}
+
+// hardwareBackend returns true if the NVRAM accesses the CMOS hardware,
+// directly or through the helper.
+func (nv *NVRAM) hardwareBackend() bool {
+	return nv.lockKey == backendLockKey("")
+}
+
+// conflicts returns the Conflicts of a hardware backend, without the
+// helper process if the NVRAM uses it.
+func (nv *NVRAM) conflicts() (conflicts []Conflict, err error) {
+	if !nv.hardwareBackend() {
+		return
+	}
+	all, err := Conflicts()
+	for _, c := range all {
+		if nv.helperSocket != "" && c.Command == "nvram-helper" {
+			continue
+		}
+		conflicts = append(conflicts, c)
+	}
+	return
+}
+
+// warnConflicts logs the conflicting CMOS users of a hardware backend.
+func (nv *NVRAM) warnConflicts() {
+	conflicts, _ := nv.conflicts()
+	for _, c := range conflicts {
+		nv.logf("nvram: Warning: other CMOS user, %v.", c)
+	}
+}
@@ -0,0 +1,213 @@
+// Copyright © 2019 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+package nvram
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+const (
+	lbTagSPIFlash        = 0x29
+	lbTagBootMediaParams = 0x30
+	lbTagSMMStoreV2      = 0x39
+)
+
+// BootMediaParams locates the FMAP and CBFS on the boot media, from the
+// LB_TAG_BOOT_MEDIA_PARAMS record. Offsets are from the start of the boot
+// media.
+type BootMediaParams struct {
+	FMAPOffset    uint64
+	CBFSOffset    uint64
+	CBFSSize      uint64
+	BootMediaSize uint64
+}
+
+// FlashMmapWindow maps a range of flash into the host address space.
+type FlashMmapWindow struct {
+	FlashBase uint32
+	HostBase  uint32
+	Size      uint32
+}
+
+// SPIFlash describes the boot flash chip, from the LB_TAG_SPI_FLASH record.
+type SPIFlash struct {
+	FlashSize  uint32
+	SectorSize uint32
+	EraseCmd   uint32
+	Windows    []FlashMmapWindow
+}
+
+// FlashToHost returns the host address of a flash offset if a window maps
+// it.
+func (f SPIFlash) FlashToHost(off uint32) (addr uint32, ok bool) {
+	for _, w := range f.Windows {
+		if off >= w.FlashBase && off-w.FlashBase < w.Size {
+			return w.HostBase + off - w.FlashBase, true
+		}
+	}
+	return 0, false
+}
+
+// SMMStore describes the SMM backed flash variable store used as option
+// backend, from the LB_TAG_SMMSTOREV2 record.
+type SMMStore struct {
+	NumBlocks     uint32
+	BlockSize     uint32
+	MmapAddr      uint32
+	ComBuffer     uint32
+	ComBufferSize uint32
+	APMCmd        uint8
+}
+
+// Size returns the size of the store in bytes.
+func (s SMMStore) Size() uint64 {
+	return uint64(s.NumBlocks) * uint64(s.BlockSize)
+}
+
+func lbRecordPayload(rec *lbRecord, name string, min int) (b []byte, err error) {
+	const header = 8
+	b = recordBytes(rec)
+	if len(b) < header+min {
+		err = fmt.Errorf("Coreboot %s record has bad size %d.", name, len(b))
+		return
+	}
+	return b[header:], nil
+}
+
+func decodeBootMediaParams(b []byte) (p BootMediaParams) {
+	u64 := func(b []byte) uint64 {
+		return uint64(binary.LittleEndian.Uint32(b)) | uint64(binary.LittleEndian.Uint32(b[4:]))<<32
+	}
+	p.FMAPOffset = u64(b[0:])
+	p.CBFSOffset = u64(b[8:])
+	p.CBFSSize = u64(b[16:])
+	p.BootMediaSize = u64(b[24:])
+	return
+}
+
+// decodeSPIFlash decodes the record payload. Older coreboot versions end
+// the record after the erase command, without mmap windows.
+func decodeSPIFlash(b []byte) (f SPIFlash, err error) {
+	f.FlashSize = binary.LittleEndian.Uint32(b[0:])
+	f.SectorSize = binary.LittleEndian.Uint32(b[4:])
+	f.EraseCmd = binary.LittleEndian.Uint32(b[8:])
+	if len(b) < 16 {
+		return
+	}
+	count := int(binary.LittleEndian.Uint32(b[12:]))
+	b = b[16:]
+	if len(b) < count*12 {
+		err = fmt.Errorf("Coreboot SPI flash record too short for %d mmap windows.", count)
+		return
+	}
+	for i := 0; i < count; i++ {
+		w := b[i*12:]
+		f.Windows = append(f.Windows, FlashMmapWindow{
+			FlashBase: binary.LittleEndian.Uint32(w[0:]),
+			HostBase:  binary.LittleEndian.Uint32(w[4:]),
+			Size:      binary.LittleEndian.Uint32(w[8:]),
+		})
+	}
+	return
+}
+
+func decodeSMMStore(b []byte) (s SMMStore) {
+	s.NumBlocks = binary.LittleEndian.Uint32(b[0:])
+	s.BlockSize = binary.LittleEndian.Uint32(b[4:])
+	s.MmapAddr = binary.LittleEndian.Uint32(b[8:])
+	s.ComBuffer = binary.LittleEndian.Uint32(b[12:])
+	s.ComBufferSize = binary.LittleEndian.Uint32(b[16:])
+	s.APMCmd = b[20]
+	return
+}
+
+// BootMediaParams decodes the boot media params record of the coreboot
+// table.
+func (t *CoreBootTable) BootMediaParams() (p BootMediaParams, err error) {
+	rec, ok := t.findRecord(lbTagBootMediaParams)
+	if !ok {
+		err = fmt.Errorf("Coreboot boot media params not found.")
+		return
+	}
+	b, err := lbRecordPayload(rec, "boot media params", 32)
+	if err == nil {
+		p = decodeBootMediaParams(b)
+	}
+	return
+}
+
+// SPIFlash decodes the SPI flash record of the coreboot table.
+func (t *CoreBootTable) SPIFlash() (f SPIFlash, err error) {
+	rec, ok := t.findRecord(lbTagSPIFlash)
+	if !ok {
+		err = fmt.Errorf("Coreboot SPI flash record not found.")
+		return
+	}
+	b, err := lbRecordPayload(rec, "SPI flash", 12)
+	if err == nil {
+		f, err = decodeSPIFlash(b)
+	}
+	return
+}
+
+// SMMStore decodes the SMMSTORE v2 record of the coreboot table.
+func (t *CoreBootTable) SMMStore() (s SMMStore, err error) {
+	rec, ok := t.findRecord(lbTagSMMStoreV2)
+	if !ok {
+		err = fmt.Errorf("Coreboot SMMSTORE record not found.")
+		return
+	}
+	b, err := lbRecordPayload(rec, "SMMSTORE", 21)
+	if err == nil {
+		s = decodeSMMStore(b)
+	}
+	return
+}
+
+// OptionBackend is the location of the firmware's option storage as
+// described by the coreboot table.
+type OptionBackend struct {
+	// CMOS is set if the table has a CMOS option table.
+	CMOS bool
+	// Flash holds the SMMSTORE of a flash backed option store, nil if
+	// there is none.
+	Flash *SMMStore
+	// Media and SPI describe the boot media, if the table has them.
+	Media *BootMediaParams
+	SPI   *SPIFlash
+}
+
+// OptionBackend collects the records describing where options are stored.
+// Missing records leave their fields unset.
+func (t *CoreBootTable) OptionBackend() (b OptionBackend, err error) {
+	_, b.CMOS = t.FindCMOSOptionTable()
+
+	if _, ok := t.findRecord(lbTagSMMStoreV2); ok {
+		var s SMMStore
+		s, err = t.SMMStore()
+		if err != nil {
+			return
+		}
+		b.Flash = &s
+	}
+	if _, ok := t.findRecord(lbTagBootMediaParams); ok {
+		var p BootMediaParams
+		p, err = t.BootMediaParams()
+		if err != nil {
+			return
+		}
+		b.Media = &p
+	}
+	if _, ok := t.findRecord(lbTagSPIFlash); ok {
+		var f SPIFlash
+		f, err = t.SPIFlash()
+		if err != nil {
+			return
+		}
+		b.SPI = &f
+	}
+	return
+}
@@ -0,0 +1,134 @@
+// Copyright © 2019 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+package nvram
+
+import (
+	"encoding/binary"
+	"fmt"
+	"unsafe"
+)
+
+// MemType is the type of a coreboot memory range.
+type MemType uint32
+
+const (
+	MemRAM            MemType = 1
+	MemReserved       MemType = 2
+	MemACPI           MemType = 3
+	MemNVS            MemType = 4
+	MemUnusable       MemType = 5
+	MemVendorReserved MemType = 6
+	MemTable          MemType = 16
+)
+
+func (t MemType) String() string {
+	switch t {
+	case MemRAM:
+		return "ram"
+	case MemReserved:
+		return "reserved"
+	case MemACPI:
+		return "acpi"
+	case MemNVS:
+		return "nvs"
+	case MemUnusable:
+		return "unusable"
+	case MemVendorReserved:
+		return "vendor-reserved"
+	case MemTable:
+		return "table"
+	}
+	return fmt.Sprintf("type-%d", uint32(t))
+}
+
+// MemRange is a range of physical memory from the coreboot memory map.
+type MemRange struct {
+	Start uint64
+	Size  uint64
+	Type  MemType
+}
+
+func (r MemRange) String() string {
+	return fmt.Sprintf("0x%016X-0x%016X %s", r.Start, r.Start+r.Size-1, r.Type)
+}
+
+// Contains returns true if the range holds addr.
+func (r MemRange) Contains(addr uint64) bool {
+	return addr >= r.Start && addr-r.Start < r.Size
+}
+
+const (
+	lbTagMemory     = 0x01
+	lbMemRangeBytes = 20
+)
+
+func (t *CoreBootTable) findRecord(tag uint32) (rec *lbRecord, ok bool) {
+	for _, lbrec := range t.recs {
+		if lbrec.tag == tag {
+			return lbrec, true
+		}
+	}
+	return nil, false
+}
+
+// recordBytes returns the mapped bytes of a table record.
+func recordBytes(rec *lbRecord) []byte {
+	return (*[1 << 20]byte)(unsafe.Pointer(rec))[:rec.size:rec.size]
+}
+
+// MemoryMap decodes the memory map record of the coreboot table.
+func (t *CoreBootTable) MemoryMap() (ranges []MemRange, err error) {
+	rec, ok := t.findRecord(lbTagMemory)
+	if !ok {
+		err = fmt.Errorf("Coreboot memory map not found.")
+		return
+	}
+	return decodeMemoryMap(recordBytes(rec))
+}
+
+// decodeMemoryMap decodes an LB_TAG_MEMORY record of packed ranges with
+// 64-bit start and size split in 32-bit halves followed by a 32-bit type.
+func decodeMemoryMap(b []byte) (ranges []MemRange, err error) {
+	const header = 8
+	if len(b) < header || (len(b)-header)%lbMemRangeBytes != 0 {
+		err = fmt.Errorf("Coreboot memory map record has bad size %d.", len(b))
+		return
+	}
+
+	u64 := func(b []byte) uint64 {
+		return uint64(binary.LittleEndian.Uint32(b)) | uint64(binary.LittleEndian.Uint32(b[4:]))<<32
+	}
+	for b = b[header:]; len(b) > 0; b = b[lbMemRangeBytes:] {
+		ranges = append(ranges, MemRange{
+			Start: u64(b[0:]),
+			Size:  u64(b[8:]),
+			Type:  MemType(binary.LittleEndian.Uint32(b[16:])),
+		})
+	}
+	return
+}
+
+// verifyForward checks that a forwarded table address is sane. If the
+// current table has a memory map the address must not be in ordinary RAM
+// or outside the map.
+func (t *CoreBootTable) verifyForward(addr uint64) (err error) {
+	if addr == 0 || addr >= 1<<32 {
+		return fmt.Errorf("Coreboot table forward address 0x%X out of range.", addr)
+	}
+
+	ranges, merr := t.MemoryMap()
+	if merr != nil {
+		return
+	}
+	for _, r := range ranges {
+		if r.Contains(addr) {
+			if r.Type == MemRAM {
+				return fmt.Errorf("Coreboot table forward address 0x%X in RAM range %v.", addr, r)
+			}
+			return
+		}
+	}
+	return fmt.Errorf("Coreboot table forward address 0x%X not in memory map.", addr)
+}
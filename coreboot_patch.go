@@ -0,0 +1,163 @@
+// Copyright © 2019 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+package nvram
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"unsafe"
+)
+
+// capSysRawIO is the capability required to write /dev/mem.
+const capSysRawIO = 17
+
+// hasCapability reports whether the process has an effective capability.
+func hasCapability(capability uint) (ok bool, err error) {
+	f, err := os.Open("/proc/self/status")
+	if err != nil {
+		return
+	}
+	defer f.Close()
+
+	s := bufio.NewScanner(f)
+	for s.Scan() {
+		line := s.Text()
+		if !strings.HasPrefix(line, "CapEff:") {
+			continue
+		}
+		var caps uint64
+		caps, err = strconv.ParseUint(strings.TrimSpace(line[len("CapEff:"):]), 16, 64)
+		if err != nil {
+			return
+		}
+		return caps&(1<<capability) != 0, nil
+	}
+	return false, fmt.Errorf("nvram: No effective capabilities in /proc/self/status.")
+}
+
+// OpenWritable opens the coreboot table like Open, but with /dev/mem
+// mapped read write so the table can be patched in memory. This is unsafe
+// and only meant for bringing up development boards with a broken table:
+// firmware and other software may rely on the table as it is, and a wrong
+// write corrupts memory. It requires CAP_SYS_RAWIO and a kernel allowing
+// /dev/mem writes to the table.
+func (t *CoreBootTable) OpenWritable() (err error) {
+	ok, err := hasCapability(capSysRawIO)
+	if err != nil {
+		return
+	}
+	if !ok {
+		return fmt.Errorf("nvram: Writing the coreboot table requires CAP_SYS_RAWIO.")
+	}
+	t.writable = true
+	return t.Open()
+}
+
+// tableBytes returns the mapped bytes of the open table, its header
+// followed by its records.
+func (t *CoreBootTable) tableBytes() []byte {
+	n := t.header.headerBytes + t.header.tableBytes
+	return (*[1 << 20]byte)(unsafe.Pointer(t.header))[:n:n]
+}
+
+// PatchCMOSChecksum rewrites the checksum record of the CMOS option table
+// in memory with a checksum over bits start to end stored at bit index, as
+// in the checksums section of a layout file, and updates the coreboot table
+// checksum. The table must be opened with OpenWritable.
+func (t *CoreBootTable) PatchCMOSChecksum(start, end, index uint, kind ChecksumType) (err error) {
+	if !t.writable || t.header == nil {
+		return fmt.Errorf("nvram: Coreboot table not opened writable.")
+	}
+	c, err := NewCMOSChecksum(start, end, index)
+	if err != nil {
+		return
+	}
+	err = c.setType(kind)
+	if err != nil {
+		return
+	}
+
+	// Find the checksum record of the option table.
+	table, ok := t.FindCMOSOptionTable()
+	if !ok {
+		return fmt.Errorf("CMOS Option Table not found")
+	}
+	b := recordBytes(&table.lbRecord)
+	le := binary.LittleEndian
+	var rec []byte
+	for off := int(table.headerLength); off+8 <= len(b); {
+		size := int(le.Uint32(b[off+4:]))
+		if size < 8 || off+size > len(b) {
+			break
+		}
+		if le.Uint32(b[off:]) == cmosTagChecksum && size >= cmosChecksumRecordSize {
+			rec = b[off : off+size]
+			break
+		}
+		off += size
+	}
+	if rec == nil {
+		return fmt.Errorf("nvram: CMOS Option Table has no checksum record to patch.")
+	}
+
+	// Patch the record, then the table checksum covering it.
+	le.PutUint32(rec[8:], uint32(c.start*8))
+	le.PutUint32(rec[12:], uint32(c.end*8+7))
+	le.PutUint32(rec[16:], uint32(c.index*8))
+	le.PutUint32(rec[20:], uint32(c.kind))
+	tb := t.tableBytes()
+	t.header.tableChecksum = uint32(ComputeIPChecksum(tb[t.header.headerBytes:]))
+	t.header.headerChecksum = 0
+	t.header.headerChecksum = uint32(ComputeIPChecksum(tb[:t.header.headerBytes]))
+	return
+}
+
+// WithUnsafeTableWrites allows PatchCoreBootChecksum to write the coreboot
+// table in memory. See CoreBootTable.OpenWritable for the risks.
+func WithUnsafeTableWrites() Option {
+	return func(nv *NVRAM) {
+		nv.tableWrites = true
+	}
+}
+
+// PatchCoreBootChecksum fixes the checksum record of the machine's CMOS
+// option table in memory, for boards whose firmware ships a wrong one, and
+// uses the new checksum for the open NVRAM. It requires an NVRAM configured
+// WithUnsafeTableWrites and CAP_SYS_RAWIO. start, end and index are bit
+// positions as in the checksums section of a layout file.
+func (nv *NVRAM) PatchCoreBootChecksum(start, end, index uint, kind ChecksumType) (err error) {
+	span := nv.startSpan("nvram.PatchCoreBootChecksum")
+	defer nv.endSpan(span, &err)
+
+	if !nv.tableWrites {
+		return ErrTableWritesDisabled
+	}
+
+	var t CoreBootTable
+	defer t.Close()
+
+	err = t.OpenWritable()
+	if err != nil {
+		return
+	}
+	err = t.PatchCMOSChecksum(start, end, index, kind)
+	if err != nil {
+		return
+	}
+	nv.logf("nvram: Patched coreboot CMOS checksum record to %d %d %d.", start, end, index)
+
+	// Use the patched checksum from now on.
+	if nv.Layout != nil {
+		c, _ := NewCMOSChecksum(start, end, index)
+		c.setType(kind)
+		nv.Layout.cmosChecksum = c
+		nv.CMOS.checksum = *c
+	}
+	return
+}
@@ -0,0 +1,57 @@
+// Copyright © 2019 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+package nvram
+
+// CoreBootRecord is a record of the coreboot table. Tag is the LB_TAG value
+// of the record, as in coreboot's coreboot_tables.h, and Data a copy of its
+// payload after the tag and size, which stays valid after the table is
+// closed.
+type CoreBootRecord struct {
+	Tag  uint32
+	Data []byte
+}
+
+// OpenCoreBootTable finds and maps the machine's coreboot table, following
+// a forwarding record to the full table. The table must be closed with
+// Close, after which the records and typed accessors of the table find
+// nothing. A CoreBootTable is not safe for concurrent use.
+func OpenCoreBootTable() (t *CoreBootTable, err error) {
+	t = new(CoreBootTable)
+	err = t.Open()
+	if err != nil {
+		return nil, err
+	}
+	return
+}
+
+// IsOpen returns true if the table is open.
+func (t *CoreBootTable) IsOpen() bool {
+	return t.header != nil
+}
+
+// Records returns copies of all records of the table in table order.
+func (t *CoreBootTable) Records() (recs []CoreBootRecord, err error) {
+	if !t.IsOpen() {
+		return nil, ErrCoreBootTableClosed
+	}
+	for _, rec := range t.recs {
+		recs = append(recs, copyRecord(rec))
+	}
+	return
+}
+
+// Record returns a copy of the first record of the table with tag.
+func (t *CoreBootTable) Record(tag uint32) (r CoreBootRecord, ok bool) {
+	rec, ok := t.findRecord(tag)
+	if !ok {
+		return
+	}
+	return copyRecord(rec), true
+}
+
+func copyRecord(rec *lbRecord) CoreBootRecord {
+	b := recordBytes(rec)
+	return CoreBootRecord{Tag: rec.tag, Data: append([]byte(nil), b[8:]...)}
+}
@@ -1,6 +1,7 @@
 package nvram
 
 import (
+	"encoding/binary"
 	"fmt"
 	"github.com/platinasystems/nvram/debug"
 	"os"
@@ -32,15 +33,21 @@ type cmosOptionTable struct {
 	headerLength uint32
 }
 
+// CoreBootTable is the machine's coreboot table mapped from /dev/mem. Open
+// it with OpenCoreBootTable, read records with Records, Record and the typed
+// accessors such as MemoryMap or SPIFlash, then Close it.
 type CoreBootTable struct {
 	mem_file *os.File
 	mem      []byte
-	baseAddr uintptr
+	baseAddr uint64
 
 	header *lbHeader
 	recs   []*lbRecord
+
+	writable bool
 }
 
+// Open finds and maps the machine's coreboot table.
 func (t *CoreBootTable) Open() (err error) {
 	defer func() {
 		if err != nil {
@@ -48,7 +55,11 @@ func (t *CoreBootTable) Open() (err error) {
 		}
 	}()
 
-	t.mem_file, err = os.OpenFile("/dev/mem", os.O_RDONLY, 0)
+	flags := os.O_RDONLY
+	if t.writable {
+		flags = os.O_RDWR | os.O_SYNC
+	}
+	t.mem_file, err = os.OpenFile("/dev/mem", flags, 0)
 	if err != nil {
 		return
 	}
@@ -64,6 +75,7 @@ func (t *CoreBootTable) Open() (err error) {
 	return
 }
 
+// Close unmaps the table. Closing a closed table does nothing.
 func (t *CoreBootTable) Close() (err error) {
 	debug.Trace(debug.LevelMSG1, "Closing Coreboot table\n")
 
@@ -92,7 +104,13 @@ func (t *CoreBootTable) FindCMOSOptionTable() (c *cmosOptionTable, ok bool) {
 	return nil, false
 }
 
-func (t *CoreBootTable) openTable(start, end uintptr) (err error) {
+// lbHeaderSize is the size of the coreboot table header.
+const lbHeaderSize = 24
+
+// openTable looks for the coreboot table in physical memory from start to
+// end. Records are located by offset into the mapping and addresses are
+// kept as uint64, so the walk works with 32 and 64 bit pointers.
+func (t *CoreBootTable) openTable(start, end uint64) (err error) {
 
 	debug.Trace(debug.LevelMSG1, "Looking for table @0x%08X\n", start)
 
@@ -103,52 +121,71 @@ func (t *CoreBootTable) openTable(start, end uintptr) (err error) {
 		}
 	}()
 
-	t.mapPages(start, end)
+	err = t.mapPages(start, end)
+	if err != nil {
+		return
+	}
 
-	for i := 0; i < len(t.mem); i += 16 {
+	for i := int(start - t.baseAddr); i+lbHeaderSize <= len(t.mem); i += 16 {
 		var header = (*lbHeader)(unsafe.Pointer(&t.mem[i]))
 		if header.signature == 0x4f49424c {
-			debug.Trace(debug.LevelMSG1, "Table found @0x%08X\n", unsafe.Pointer(header))
-			if t.computeIpChecksum(uintptr(unsafe.Pointer(header)), uint64(header.headerBytes)) != 0 {
+			phyAddr := t.baseAddr + uint64(i)
+			debug.Trace(debug.LevelMSG1, "Table found @0x%08X\n", phyAddr)
+			headerBytes := int(header.headerBytes)
+			tableBytes := int(header.tableBytes)
+			if headerBytes < lbHeaderSize || i+headerBytes > len(t.mem) ||
+				ComputeIPChecksum(t.mem[i:i+headerBytes]) != 0 {
 				debug.Trace(debug.LevelMSG1, "Header checksum bad\n")
 				continue
 			}
 
-			phyAddr := t.baseAddr + uintptr(i)
-			t.mapPages(phyAddr, phyAddr+uintptr(header.tableBytes))
-			virtAddr := uintptr(unsafe.Pointer(&t.mem[0])) + phyAddr - t.baseAddr
-			header = (*lbHeader)(unsafe.Pointer(virtAddr))
-
-			var lbrec = (*lbRecord)(unsafe.Pointer(virtAddr + uintptr(header.headerBytes)))
+			// Map the whole table, then look at it from its offset.
+			err = t.mapPages(phyAddr, phyAddr+uint64(headerBytes+tableBytes))
+			if err != nil {
+				return
+			}
+			off := int(phyAddr - t.baseAddr)
+			header = (*lbHeader)(unsafe.Pointer(&t.mem[off]))
+			table := t.mem[off+headerBytes : off+headerBytes+tableBytes]
 
-			if t.computeIpChecksum(uintptr(unsafe.Pointer(lbrec)), uint64(header.tableBytes)) != header.tableChecksum {
+			if uint32(ComputeIPChecksum(table)) != header.tableChecksum {
 				debug.Trace(debug.LevelMSG1, "Table checksum bad\n")
-				continue
+				err = t.rescan(start, end, phyAddr)
+				return
 			}
 
 			t.header = header
 			t.recs = nil
 			var lbforward *lbForward
-			for i := uint32(0); i < header.tableBytes; {
+			for i := 0; i+8 <= len(table); {
+				lbrec := (*lbRecord)(unsafe.Pointer(&table[i]))
 				debug.Trace(debug.LevelMSG3, "Found lbRecord tag = %X len = %d\n", lbrec.tag, lbrec.size)
+				if lbrec.size < 8 || i+int(lbrec.size) > len(table) {
+					debug.Trace(debug.LevelMSG1, "Bad table entry size.\n")
+					break
+				}
 
-				if lbforward == nil && lbrec.tag == 0x11 {
+				if lbforward == nil && lbrec.tag == 0x11 && lbrec.size >= 16 {
 					lbforward = (*lbForward)(unsafe.Pointer(lbrec))
 				}
 
 				t.recs = append(t.recs, lbrec)
-				i += lbrec.size
-				lbrec = (*lbRecord)(unsafe.Pointer(uintptr(unsafe.Pointer(lbrec)) + uintptr(lbrec.size)))
+				i += int(lbrec.size)
 			}
 
 			if len(t.recs) != int(header.tableEntries) {
 				debug.Trace(debug.LevelMSG1, "Unexpected number of table entries.\n")
-				continue
+				err = t.rescan(start, end, phyAddr)
+				return
 			}
 
 			if lbforward != nil {
 				debug.Trace(debug.LevelMSG1, "Forwarding table found.\n")
-				err = t.openTable(uintptr(lbforward.forward), uintptr(lbforward.forward)+uintptr(os.Getpagesize()))
+				err = t.verifyForward(lbforward.forward)
+				if err != nil {
+					return
+				}
+				err = t.openTable(lbforward.forward, lbforward.forward+uint64(os.Getpagesize()))
 				return
 			}
 
@@ -160,10 +197,18 @@ func (t *CoreBootTable) openTable(start, end uintptr) (err error) {
 	return
 }
 
-func (t *CoreBootTable) mapPages(start, end uintptr) (err error) {
+// rescan continues looking for the table after a bad one at addr.
+func (t *CoreBootTable) rescan(start, end, addr uint64) error {
+	if addr+16 >= end {
+		return fmt.Errorf("Coreboot table not found.")
+	}
+	return t.openTable(addr+16, end)
+}
+
+func (t *CoreBootTable) mapPages(start, end uint64) (err error) {
 	t.baseAddr = start
 	length := end - start
-	pagesize := uintptr(os.Getpagesize())
+	pagesize := uint64(os.Getpagesize())
 
 	numPages := (length +
 		(t.baseAddr & (pagesize - 1)) +
@@ -175,9 +220,13 @@ func (t *CoreBootTable) mapPages(start, end uintptr) (err error) {
 		t.mem = nil
 	}
 
+	prot := syscall.PROT_READ
+	if t.writable {
+		prot |= syscall.PROT_WRITE
+	}
 	t.mem, err = syscall.Mmap(int(t.mem_file.Fd()),
 		int64(t.baseAddr), int(numPages*pagesize),
-		syscall.PROT_READ, syscall.MAP_SHARED)
+		prot, syscall.MAP_SHARED)
 	if err != nil {
 		return
 	}
@@ -185,13 +234,15 @@ func (t *CoreBootTable) mapPages(start, end uintptr) (err error) {
 	return
 }
 
-func (t *CoreBootTable) computeIpChecksum(start uintptr, length uint64) uint32 {
+// ComputeIPChecksum computes the IP checksum coreboot uses for its tables
+// over b, as if b started at an even address. A block holding its own
+// checksum sums to zero.
+func ComputeIPChecksum(b []byte) uint16 {
 
 	sum := uint32(0)
 
-	for i := start; i < (start + uintptr(length)); i++ {
-		ptr := (*byte)(unsafe.Pointer(i))
-		value := uint32(*ptr)
+	for i, v := range b {
+		value := uint32(v)
 		if (i & 1) != 0 {
 			value <<= 8
 		}
@@ -202,5 +253,40 @@ func (t *CoreBootTable) computeIpChecksum(start uintptr, length uint64) uint32 {
 			sum = (sum + (sum >> 16)) & 0xFFFF
 		}
 	}
-	return (^sum) & 0xFFFF
+	return uint16(^sum)
+}
+
+// SetCoreBootTableChecksums updates the record count, table size and both
+// checksums in the header of a coreboot table image, a header followed by
+// its records, after the records were assembled or patched.
+func SetCoreBootTableChecksums(table []byte) (err error) {
+	le := binary.LittleEndian
+	if len(table) < lbHeaderSize || le.Uint32(table) != 0x4f49424c {
+		return fmt.Errorf("Coreboot table header not found.")
+	}
+	headerBytes := int(le.Uint32(table[4:]))
+	if headerBytes < lbHeaderSize || headerBytes > len(table) {
+		return fmt.Errorf("Coreboot table header has bad size %d.", headerBytes)
+	}
+	records := table[headerBytes:]
+
+	// Count the records.
+	n := 0
+	for i := 0; i < len(records); n++ {
+		if i+8 > len(records) {
+			return fmt.Errorf("Coreboot table ends in a partial record.")
+		}
+		size := int(le.Uint32(records[i+4:]))
+		if size < 8 || i+size > len(records) {
+			return fmt.Errorf("Coreboot table record at %d has bad size %d.", i, size)
+		}
+		i += size
+	}
+
+	le.PutUint32(table[12:], uint32(len(records)))
+	le.PutUint32(table[16:], uint32(ComputeIPChecksum(records)))
+	le.PutUint32(table[20:], uint32(n))
+	le.PutUint32(table[8:], 0)
+	le.PutUint32(table[8:], uint32(ComputeIPChecksum(table[:headerBytes])))
+	return
 }
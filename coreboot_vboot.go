@@ -0,0 +1,286 @@
+// Copyright © 2019 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+package nvram
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+const (
+	lbTagVBNV         = 0x19
+	lbTagVbootWorkbuf = 0x34
+)
+
+// vboot nvdata layout, version 1.
+const (
+	vbnvSize            = 16
+	vbnvOffHeader       = 0
+	vbnvOffRecovery     = 2
+	vbnvOffRecoverySub  = 6
+	vbnvOffBoot2        = 7
+	vbnvOffCRC          = 15
+	vbnvSignatureMask   = 0xC0
+	vbnvSignature       = 0x40
+	vbnvKernelReset     = 0x10
+	vbnvFirmwareReset   = 0x20
+	vbnvBoot2TryNext    = 0x08
+	vbnvBoot2Tried      = 0x04
+	vbnvBoot2ResultMask = 0x03
+)
+
+// vbootSharedDataMagic starts the vboot shared data in the work buffer.
+const vbootSharedDataMagic = 0x44533256
+
+// RecoveryReason is a vboot recovery reason code.
+type RecoveryReason uint8
+
+// recoveryReasons describes the common vboot recovery reason codes.
+var recoveryReasons = map[RecoveryReason]string{
+	0x00: "Recovery not requested",
+	0x01: "Recovery requested from legacy utility",
+	0x02: "User manually requested recovery via recovery button",
+	0x03: "RW firmware failed signature check",
+	0x06: "Shared data error in read-only firmware",
+	0x20: "Firmware problem outside of verified boot",
+	0x21: "TPM requires a system reboot",
+	0x22: "EC software sync error",
+	0x23: "EC software sync unable to determine active EC image",
+	0x26: "EC software sync unable to update EC",
+	0x27: "EC software sync unable to jump to EC-RW",
+	0x28: "EC software sync unable to protect EC",
+	0x29: "EC software sync error obtaining expected EC hash",
+	0x2B: "Firmware secure data initialization error",
+	0x2C: "GBB header is bad",
+	0x2D: "Unable to clear TPM owner",
+	0x2E: "Error determining or updating developer switch",
+	0x2F: "Error determining or updating firmware slot",
+	0x30: "Error updating auxiliary firmware",
+	0x3F: "Unspecified error in read-only firmware",
+	0x43: "OS kernel or rootfs failed signature check",
+	0x45: "Developer mode switch mismatch",
+	0x46: "Shared data error in rewritable firmware",
+	0x49: "TPM error in rewritable firmware",
+	0x50: "TPM setup error in read-only firmware",
+	0x51: "TPM write error in read-only firmware",
+	0x52: "TPM lock error in read-only firmware",
+	0x53: "TPM update error in read-only firmware",
+	0x54: "TPM read error in rewritable firmware",
+	0x55: "TPM write error in rewritable firmware",
+	0x56: "TPM lock error in rewritable firmware",
+	0x57: "EC software sync unable to get EC image hash",
+	0x58: "EC software sync invalid image hash size",
+	0x59: "Unspecified error while trying to load kernel",
+	0x5A: "No bootable storage device in system",
+	0x5B: "No bootable kernel found on disk",
+	0x5D: "Kernel secure data initialization error",
+	0x60: "Failed to disable the TPM",
+	0x62: "FWMP secure data initialization error",
+	0x7F: "Unspecified error in rewritable firmware",
+	0xC1: "Recovery mode test from user mode",
+	0xFF: "Unspecified error from user mode",
+}
+
+// String describes the recovery reason. Codes without a description are
+// described by the firmware stage their range belongs to.
+func (r RecoveryReason) String() string {
+	if s, ok := recoveryReasons[r]; ok {
+		return fmt.Sprintf("0x%02X %s", uint8(r), s)
+	}
+	var stage string
+	switch {
+	case r >= 0x10 && r <= 0x1F:
+		stage = "RW firmware check failed"
+	case r < 0x40:
+		stage = "Read-only firmware error"
+	case r < 0x80:
+		stage = "Rewritable firmware error"
+	case r < 0xC0:
+		stage = "Kernel error"
+	default:
+		stage = "User mode error"
+	}
+	return fmt.Sprintf("0x%02X %s", uint8(r), stage)
+}
+
+// VbootNV is the decoded vboot nvdata stored in CMOS on ChromeOS derived
+// platforms.
+type VbootNV struct {
+	// RecoveryRequest is the recovery reason requested for the next
+	// boot. vboot moves a handled request to RecoverySubcode, so after
+	// a recovery boot it holds the reason of that boot.
+	RecoveryRequest RecoveryReason
+	RecoverySubcode RecoveryReason
+	// FirmwareReset and KernelReset are set if vboot reset the firmware
+	// or kernel settings to defaults.
+	FirmwareReset bool
+	KernelReset   bool
+	// TryNext is the firmware slot to try at the next boot, 0 for A and
+	// 1 for B, Tried is set if the slot was tried and Result holds the
+	// result of the try, 0 unknown, 1 trying, 2 success and 3 failure.
+	TryNext uint8
+	Tried   bool
+	Result  uint8
+}
+
+// vbnvCRC8 is the CRC-8 with polynomial x^8 + x^2 + x + 1 used by vboot.
+func vbnvCRC8(b []byte) (crc byte) {
+	for _, v := range b {
+		crc ^= v
+		for i := 0; i < 8; i++ {
+			if crc&0x80 != 0 {
+				crc = crc<<1 ^ 0x07
+			} else {
+				crc <<= 1
+			}
+		}
+	}
+	return
+}
+
+// DecodeVbootNV decodes vboot nvdata, checking its signature and CRC.
+func DecodeVbootNV(b []byte) (nv VbootNV, err error) {
+	if len(b) < vbnvSize {
+		return nv, fmt.Errorf("nvram: vboot nvdata of %d bytes is too short.", len(b))
+	}
+	if b[vbnvOffHeader]&vbnvSignatureMask != vbnvSignature {
+		return nv, fmt.Errorf("nvram: vboot nvdata has bad signature 0x%02X.", b[vbnvOffHeader])
+	}
+	if crc := vbnvCRC8(b[:vbnvOffCRC]); crc != b[vbnvOffCRC] {
+		return nv, fmt.Errorf("nvram: vboot nvdata has bad CRC 0x%02X, expected 0x%02X.",
+			b[vbnvOffCRC], crc)
+	}
+
+	nv.RecoveryRequest = RecoveryReason(b[vbnvOffRecovery])
+	nv.RecoverySubcode = RecoveryReason(b[vbnvOffRecoverySub])
+	nv.FirmwareReset = b[vbnvOffHeader]&vbnvFirmwareReset != 0
+	nv.KernelReset = b[vbnvOffHeader]&vbnvKernelReset != 0
+	boot2 := b[vbnvOffBoot2]
+	if boot2&vbnvBoot2TryNext != 0 {
+		nv.TryNext = 1
+	}
+	nv.Tried = boot2&vbnvBoot2Tried != 0
+	nv.Result = boot2 & vbnvBoot2ResultMask
+	return
+}
+
+// VbootNVRange returns the CMOS offset and size of the vboot nvdata, from
+// the LB_TAG_VBNV record.
+func (t *CoreBootTable) VbootNVRange() (start, size uint32, err error) {
+	rec, ok := t.findRecord(lbTagVBNV)
+	if !ok {
+		err = fmt.Errorf("Coreboot VBNV record not found.")
+		return
+	}
+	b, err := lbRecordPayload(rec, "VBNV", 12)
+	if err != nil {
+		return
+	}
+	return binary.LittleEndian.Uint32(b[0:]), binary.LittleEndian.Uint32(b[8:]), nil
+}
+
+// VbootWorkbuf describes the vboot work buffer, from the
+// LB_TAG_VBOOT_WORKBUF record. SharedDataVersion is the version of the
+// shared data at its start, as major << 16 | minor, 0 if the buffer could
+// not be read or holds no shared data.
+type VbootWorkbuf struct {
+	Addr              uint64
+	Size              uint32
+	SharedDataVersion uint32
+}
+
+// VbootWorkbuf decodes the vboot work buffer record of the coreboot table
+// and reads the version of the shared data it holds.
+func (t *CoreBootTable) VbootWorkbuf() (w VbootWorkbuf, err error) {
+	rec, ok := t.findRecord(lbTagVbootWorkbuf)
+	if !ok {
+		err = fmt.Errorf("Coreboot vboot workbuf record not found.")
+		return
+	}
+	b, err := lbRecordPayload(rec, "vboot workbuf", 12)
+	if err != nil {
+		return
+	}
+	w.Addr = uint64(binary.LittleEndian.Uint32(b)) | uint64(binary.LittleEndian.Uint32(b[4:]))<<32
+	w.Size = binary.LittleEndian.Uint32(b[8:])
+
+	// The header layout is stable across shared data versions.
+	if t.mem_file != nil && w.Size >= 8 {
+		h := make([]byte, 8)
+		if _, rerr := t.mem_file.ReadAt(h, int64(w.Addr)); rerr == nil &&
+			binary.LittleEndian.Uint32(h) == vbootSharedDataMagic {
+			w.SharedDataVersion = uint32(binary.LittleEndian.Uint16(h[4:]))<<16 |
+				uint32(binary.LittleEndian.Uint16(h[6:]))
+		}
+	}
+	return
+}
+
+// VbootRecovery is the vboot recovery state of a ChromeOS derived
+// platform.
+type VbootRecovery struct {
+	// NVOffset is the CMOS offset of the nvdata.
+	NVOffset uint
+	NV       VbootNV
+	// Workbuf is the vboot work buffer, nil if the table has none.
+	Workbuf *VbootWorkbuf
+}
+
+// Reason returns the recovery reason of the last recovery boot, or the
+// pending request if one has not been handled yet.
+func (r VbootRecovery) Reason() RecoveryReason {
+	if r.NV.RecoveryRequest != 0 {
+		return r.NV.RecoveryRequest
+	}
+	return r.NV.RecoverySubcode
+}
+
+// ReadVbootNV reads and decodes the vboot nvdata at CMOS offset off.
+func (nv *NVRAM) ReadVbootNV(off uint) (v VbootNV, err error) {
+	b := make([]byte, vbnvSize)
+	for i := range b {
+		b[i], err = nv.CMOS.ReadByte(off + uint(i))
+		if err != nil {
+			return
+		}
+	}
+	return DecodeVbootNV(b)
+}
+
+// VbootRecovery reads the vboot recovery state, locating the nvdata with
+// the machine's coreboot table. The recovery reason codes are taken from
+// the nvdata, as the layout of the shared data in the work buffer varies
+// between vboot versions.
+func (nv *NVRAM) VbootRecovery() (r VbootRecovery, err error) {
+	var t CoreBootTable
+	defer t.Close()
+
+	err = t.Open()
+	if err != nil {
+		return
+	}
+	start, size, err := t.VbootNVRange()
+	if err != nil {
+		return
+	}
+	if size < vbnvSize {
+		return r, fmt.Errorf("nvram: vboot nvdata of %d bytes is too short.", size)
+	}
+	r.NVOffset = uint(start)
+	r.NV, err = nv.ReadVbootNV(r.NVOffset)
+	if err != nil {
+		return
+	}
+
+	if _, ok := t.findRecord(lbTagVbootWorkbuf); ok {
+		var w VbootWorkbuf
+		w, err = t.VbootWorkbuf()
+		if err != nil {
+			return
+		}
+		r.Workbuf = &w
+	}
+	return
+}
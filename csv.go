@@ -0,0 +1,131 @@
+// Copyright © 2019 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+package nvram
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"reflect"
+)
+
+// CSV type column values.
+const (
+	CSVTypeEnum     = "enum"
+	CSVTypeHex      = "hex"
+	CSVTypeString   = "string"
+	CSVTypeVirtual  = "virtual"
+	CSVTypeReserved = "reserved"
+)
+
+var csvHeader = []string{"name", "type", "value"}
+
+func (nv *NVRAM) csvType(name string) (typ string, err error) {
+	info, err := nv.ParameterInfo(name)
+	if err != nil {
+		return
+	}
+	switch {
+	case info.Virtual:
+		typ = CSVTypeVirtual
+	case info.Config == CMOSEntryEnum:
+		typ = CSVTypeEnum
+	case info.Config == CMOSEntryHex:
+		typ = CSVTypeHex
+	case info.Config == CMOSEntryString:
+		typ = CSVTypeString
+	case info.Config == CMOSEntryReserved:
+		typ = CSVTypeReserved
+	default:
+		err = fmt.Errorf("CMOS parameter %s has invalid config type.", name)
+	}
+	return
+}
+
+// ExportCSV writes all parameters as CSV with name, type and value columns
+// after a header row.
+func (nv *NVRAM) ExportCSV(w io.Writer) (err error) {
+	params, err := nv.ReadAllParameters()
+	if err != nil {
+		return
+	}
+
+	cw := csv.NewWriter(w)
+	cw.Write(csvHeader)
+	for _, p := range params {
+		var typ string
+		typ, err = nv.csvType(p.Name)
+		if err != nil {
+			return
+		}
+		cw.Write([]string{p.Name, typ, formatParameterValue(p.Value)})
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// ImportCSV reads parameters in the format written by ExportCSV and writes
+// the values that differ with WriteCMOSParameters, so either all or none
+// are changed. The type column must match the parameter type. Reserved
+// entries are read only and skipped. The names of
+// the parameters changed are returned. Unique parameters are skipped unless
+// the NVRAM was configured WithUniqueRestore.
+func (nv *NVRAM) ImportCSV(r io.Reader) (changed []string, err error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = len(csvHeader)
+	records, err := cr.ReadAll()
+	if err != nil {
+		return
+	}
+
+	// Skip header row
+	first := 1
+	if len(records) > 0 && records[0][0] == csvHeader[0] {
+		records = records[1:]
+		first++
+	}
+
+	// Check all records before writing anything.
+	var params []Parameter
+	for i, rec := range records {
+		name, typ, s := rec[0], rec[1], rec[2]
+
+		var want string
+		want, err = nv.csvType(name)
+		if err != nil {
+			return
+		}
+		if typ != want {
+			err = fmt.Errorf("CMOS parameter %s is %s not %s on CSV row %d", name, want, typ, first+i)
+			return
+		}
+		if typ == CSVTypeReserved {
+			continue
+		}
+
+		var value, current interface{}
+		value, err = nv.ParseParameterValue(name, s)
+		if err != nil {
+			return
+		}
+		current, err = nv.ReadCMOSParameter(name)
+		if err != nil {
+			return
+		}
+		if reflect.DeepEqual(current, value) || !nv.restorable(name) {
+			continue
+		}
+		params = append(params, Parameter{Name: name, Value: value})
+	}
+
+	err = nv.WriteCMOSParameters(params)
+	if err != nil {
+		return
+	}
+	for _, p := range params {
+		changed = append(changed, p.Name)
+	}
+	return
+}
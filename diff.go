@@ -0,0 +1,42 @@
+// Copyright © 2019 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+package nvram
+
+// ParamDiff is a parameter whose value differs between two CMOS images.
+type ParamDiff struct {
+	Name   string
+	Before interface{}
+	After  interface{}
+}
+
+// DiffImages decodes two CMOS images with layout and returns the parameters
+// whose values differ. Parameters are ordered by bit offset followed by the
+// subfields sorted by name, so the result is the same for every call.
+// Parameters that can not be decoded from an image are reported with a nil
+// value.
+func DiffImages(layout *Layout, a, b []byte) (diffs []ParamDiff) {
+	nva, erra := openImage(layout, a)
+	nvb, errb := openImage(layout, b)
+
+	read := func(nv *NVRAM, err error, name string) interface{} {
+		if err != nil {
+			return nil
+		}
+		v, err := nv.ReadCMOSParameter(name)
+		if err != nil {
+			return nil
+		}
+		return v
+	}
+
+	for _, name := range layout.parameterNames() {
+		before := read(nva, erra, name)
+		after := read(nvb, errb, name)
+		if before != after {
+			diffs = append(diffs, ParamDiff{Name: name, Before: before, After: after})
+		}
+	}
+	return
+}
@@ -0,0 +1,114 @@
+// Copyright © 2019 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+package nvram
+
+import (
+	"reflect"
+	"time"
+)
+
+type EventKind int
+
+const (
+	// EventCorruption reports CMOS bytes changed by someone else or a
+	// stored checksum that does not match the CMOS data.
+	EventCorruption EventKind = iota
+	// EventRepaired reports corrupted CMOS bytes that were restored.
+	EventRepaired
+	// EventGuardError reports a failure to check the CMOS.
+	EventGuardError
+)
+
+func (k EventKind) String() string {
+	switch k {
+	case EventCorruption:
+		return "corruption"
+	case EventRepaired:
+		return "repaired"
+	case EventGuardError:
+		return "guard-error"
+	}
+	return "unknown"
+}
+
+// Event is passed to the handler set with WithEventHandler.
+type Event struct {
+	Kind EventKind
+	Time time.Time
+	// Ranges are the CMOS bytes found changed.
+	Ranges []ByteRange
+	// Computed and Stored are the checksums at the time of the event.
+	Computed uint16
+	Stored   uint16
+	// Suspects are the parameters in Ranges, or in the checksummed area
+	// if no ranges are known, whose value differs from their layout
+	// default. Parameters without a default are always suspects.
+	Suspects []string
+	Err      error
+}
+
+// WithEventHandler calls h for every NVRAM event. The handler may be
+// called from a background goroutine.
+func WithEventHandler(h func(Event)) Option {
+	return func(nv *NVRAM) {
+		nv.eventHandler = h
+	}
+}
+
+func (nv *NVRAM) emit(ev Event) {
+	ev.Time = time.Now()
+	switch ev.Kind {
+	case EventCorruption, EventRepaired:
+		nv.logf("nvram: CMOS %s in %v, checksum computed 0x%X stored 0x%X, suspects %v",
+			ev.Kind, ev.Ranges, ev.Computed, ev.Stored, ev.Suspects)
+	}
+	if ev.Kind == EventCorruption {
+		nv.countFailure(func(s *FailureStats, r *FailureRun) {
+			if ev.Computed != ev.Stored {
+				s.ChecksumFailures++
+				r.ChecksumFailures++
+			} else {
+				s.Corruptions++
+				r.Corruptions++
+			}
+		})
+	}
+	if nv.eventHandler != nil {
+		nv.eventHandler(ev)
+	}
+}
+
+// suspects returns the parameters with bits in ranges whose value differs
+// from their default. Without ranges the checksummed area is used.
+func (nv *NVRAM) suspects(ranges []ByteRange) (names []string) {
+	if len(ranges) == 0 {
+		ranges = []ByteRange{{Start: nv.CMOS.checksum.start, End: nv.CMOS.checksum.end}}
+	}
+
+	for _, e := range nv.entryList() {
+		if e.config == CMOSEntryReserved || e.name == "check_sum" {
+			continue
+		}
+		affected := false
+		for _, r := range ranges {
+			if checkAreaOverLap(e.bit, e.length, r.Start*8, (r.End-r.Start+1)*8) {
+				affected = true
+				break
+			}
+		}
+		if !affected {
+			continue
+		}
+
+		def, ok := e.Default()
+		if ok {
+			if v, err := nv.readRawParameter(e.name); err == nil && reflect.DeepEqual(v, def) {
+				continue
+			}
+		}
+		names = append(names, e.name)
+	}
+	return
+}
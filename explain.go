@@ -0,0 +1,190 @@
+// Copyright © 2019 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+package nvram
+
+import (
+	"encoding/binary"
+	"fmt"
+	"strings"
+)
+
+// ExplainedByte is a CMOS byte holding bits of a parameter. Mask selects
+// the bits of the parameter and Value is the current byte, zero for bytes
+// in the RTC area.
+type ExplainedByte struct {
+	Offset uint
+	Mask   byte
+	Value  byte
+}
+
+// Explanation describes how a parameter is stored, as returned by Explain.
+type Explanation struct {
+	Name   string
+	Config CMOSEntryConfig
+	// Bit and Length are the CMOS bits of the parameter. Parent is the
+	// hex parameter holding a subfield, whose bits are then those of the
+	// subfield.
+	Bit    uint
+	Length uint
+	Parent string
+	// Bytes are the CMOS bytes the parameter touches.
+	Bytes []ExplainedByte
+	// Raw is the decoded entry value, least significant byte first, and
+	// Value the parameter value. Both are unset for entries that can not
+	// be read.
+	Raw   []byte
+	Value interface{}
+	// Enum is the enum table consulted for an enum parameter.
+	Enum []CMOSEnumItem
+	// Checksummed is set if writing the parameter changes the checksum
+	// stored at ChecksumIndex.
+	Checksummed   bool
+	ChecksumIndex uint
+	// Steps describe the decoding in words.
+	Steps []string
+}
+
+// String formats the explanation for display.
+func (x Explanation) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s:\n", x.Name)
+	for _, step := range x.Steps {
+		fmt.Fprintf(&b, "  %s\n", step)
+	}
+	for _, eb := range x.Bytes {
+		fmt.Fprintf(&b, "  byte 0x%02X = 0x%02X mask 0x%02X\n", eb.Offset, eb.Value, eb.Mask)
+	}
+	return b.String()
+}
+
+// Explain traces how the named parameter maps to CMOS bits: the bits and
+// bytes it occupies, their current contents, how they decode to the value,
+// the enum table consulted and whether the parameter is covered by the
+// checksum. It is a debugging aid, e.g. to find out why writing one
+// parameter changed a byte.
+func (nv *NVRAM) Explain(name string) (x Explanation, err error) {
+	name = nv.resolveName(name)
+	x.Name = name
+
+	if _, ok := nv.virtuals[name]; ok {
+		err = fmt.Errorf("Virtual parameter %s is computed by Go callbacks, not stored in CMOS bits.", name)
+		return
+	}
+
+	// A subfield is explained through its parent entry.
+	s, isSubfield := nv.FindCMOSSubfield(name)
+	var e *CMOSEntry
+	if isSubfield {
+		e = s.parent
+		x.Parent = e.name
+		x.Config = CMOSEntryHex
+		x.Bit, x.Length = e.bit+s.bit, s.length
+	} else {
+		// The checksum entry is explained too, it changes with every
+		// write to the checksum range.
+		var ok bool
+		e, ok = nv.entry(name)
+		if !ok {
+			err = &ParameterError{Name: name, Err: ErrParameterNotFound}
+			return
+		}
+		x.Config = e.config
+		x.Bit, x.Length = e.bit, e.length
+	}
+	first, last := x.Bit/8, (x.Bit+x.Length-1)/8
+	x.Steps = append(x.Steps, fmt.Sprintf("Occupies bits %d-%d (%d bits) in bytes 0x%02X-0x%02X.",
+		x.Bit, x.Bit+x.Length-1, x.Length, first, last))
+
+	// Collect the bytes touched.
+	for off := first; off <= last; off++ {
+		lo, hi := uint(0), uint(8)
+		if x.Bit > off*8 {
+			lo = x.Bit - off*8
+		}
+		if x.Bit+x.Length < off*8+8 {
+			hi = x.Bit + x.Length - off*8
+		}
+		eb := ExplainedByte{Offset: off, Mask: byte((1<<hi)-1) &^ byte((1<<lo)-1)}
+		if verifyCMOSByteIndex(off) {
+			eb.Value, err = nv.CMOS.ReadByte(off)
+			if err != nil {
+				return
+			}
+		}
+		x.Bytes = append(x.Bytes, eb)
+	}
+
+	// Decode the entry.
+	if verr := verifyCMOSOp(e); verr != nil {
+		x.Steps = append(x.Steps, fmt.Sprintf("Not decoded: %v", verr))
+	} else {
+		err = nv.explainValue(&x, e, s)
+		if err != nil {
+			return
+		}
+	}
+
+	// Checksum membership
+	c := nv.CMOS.checksum
+	switch {
+	case c.kind == ChecksumNone:
+		x.Steps = append(x.Steps, "The layout has no checksum.")
+	case first <= c.index+1 && last >= c.index:
+		x.Steps = append(x.Steps, fmt.Sprintf("Overlaps the checksum stored at 0x%02X-0x%02X.",
+			c.index, c.index+1))
+	case first <= c.end && last >= c.start:
+		x.Checksummed, x.ChecksumIndex = true, c.index
+		x.Steps = append(x.Steps, fmt.Sprintf("In the checksum range 0x%02X-0x%02X, writing it changes the checksum at 0x%02X-0x%02X.",
+			c.start, c.end, c.index, c.index+1))
+	default:
+		x.Steps = append(x.Steps, fmt.Sprintf("Outside the checksum range 0x%02X-0x%02X.",
+			c.start, c.end))
+	}
+	return
+}
+
+// explainValue reads and decodes entry e, or subfield s of it, into x.
+func (nv *NVRAM) explainValue(x *Explanation, e *CMOSEntry, s *CMOSSubfield) (err error) {
+	x.Raw, err = nv.CMOS.ReadEntry(e)
+	if err != nil {
+		return
+	}
+
+	switch e.config {
+	case CMOSEntryString:
+		x.Value = cString(x.Raw[:e.length/8])
+		x.Steps = append(x.Steps, fmt.Sprintf("Bytes up to the first NUL are the string %q.", x.Value))
+	case CMOSEntryEnum:
+		n := binary.LittleEndian.Uint64(x.Raw)
+		x.Enum, _ = nv.GetCMOSEnumItemsById(e.config_id)
+		text, ok := nv.FindCMOSEnumText(e.config_id, uint(n))
+		if ok {
+			x.Value = text
+			x.Steps = append(x.Steps, fmt.Sprintf("Raw value 0x%X is %s in enum %d.", n, text, e.config_id))
+		} else {
+			x.Value = fmt.Sprintf("0x%X # Bad Value", n)
+			x.Steps = append(x.Steps, fmt.Sprintf("Raw value 0x%X is not in enum %d.", n, e.config_id))
+		}
+	case CMOSEntryHex:
+		n := binary.LittleEndian.Uint64(x.Raw)
+		x.Value = n
+		x.Steps = append(x.Steps, fmt.Sprintf("Bits read least significant byte first and shifted to bit 0 are 0x%X.", n))
+		if s != nil {
+			x.Value = (n >> s.bit) & subfieldMask(s)
+			x.Steps = append(x.Steps, fmt.Sprintf("Subfield of %s: (0x%X >> %d) & 0x%X = 0x%X.",
+				e.name, n, s.bit, subfieldMask(s), x.Value))
+		}
+	}
+
+	// Transformers change the value presented.
+	if t, ok := nv.transformers[x.Name]; ok && t.read != nil {
+		x.Value, err = t.readValue(nv, x.Value)
+		if err != nil {
+			return
+		}
+		x.Steps = append(x.Steps, fmt.Sprintf("A transformer presents it as %v.", x.Value))
+	}
+	return
+}
@@ -0,0 +1,187 @@
+// Copyright © 2019 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+// Package fleet applies CMOS settings to many targets concurrently.
+package fleet
+
+import (
+	"context"
+	"fmt"
+	"github.com/platinasystems/nvram"
+	"reflect"
+	"sort"
+	"sync"
+)
+
+// Session is an open NVRAM on one target. *nvram.NVRAM implements
+// Session, as does any remote accessor exposing the same methods. The
+// nvram module has no remote accessor of its own, StoreSession adapts a
+// string based nvram.Store, such as a client of a remote nvram.Service.
+type Session interface {
+	ReadCMOSParameter(name string) (value interface{}, err error)
+	WriteCMOSParameter(name string, value interface{}) error
+	Close() error
+}
+
+// StoreSession returns a Session on store, closed by calling close if it
+// is not nil. Values are read as strings and written formatted with
+// fmt.Sprint, and settings are compared with the values read in that form.
+func StoreSession(store nvram.Store, close func() error) Session {
+	return &storeSession{store: store, close: close}
+}
+
+type storeSession struct {
+	store nvram.Store
+	close func() error
+}
+
+func (s *storeSession) ReadCMOSParameter(name string) (interface{}, error) {
+	return s.store.Get(name)
+}
+
+func (s *storeSession) WriteCMOSParameter(name string, value interface{}) error {
+	return s.store.Set(name, fmt.Sprint(value))
+}
+
+func (s *storeSession) Close() error {
+	if s.close == nil {
+		return nil
+	}
+	return s.close()
+}
+
+// ConnectFunc opens a session to a target.
+type ConnectFunc func(ctx context.Context, host string) (Session, error)
+
+// Result reports the outcome of applying settings to one target.
+type Result struct {
+	Host string
+	// Changed lists the parameters written, in name order.
+	Changed []string
+	// RolledBack is set if a failure caused the changed parameters to be
+	// restored to their previous values.
+	RolledBack bool
+	Err        error
+}
+
+// Apply writes settings to every host with at most parallel targets in
+// progress at once. Each written parameter is read back, and if any write
+// or verification fails, all parameters changed on that host are restored.
+// Results are returned in the order of hosts.
+func Apply(ctx context.Context, hosts []string, connect ConnectFunc,
+	settings map[string]interface{}, parallel int) []Result {
+
+	if parallel < 1 {
+		parallel = 1
+	}
+
+	results := make([]Result, len(hosts))
+	sem := make(chan struct{}, parallel)
+	var wg sync.WaitGroup
+
+	for i, host := range hosts {
+		results[i].Host = host
+
+		// Wait for a free slot unless cancelled
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+			results[i].Err = ctx.Err()
+			continue
+		}
+
+		wg.Add(1)
+		go func(r *Result) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			applyHost(ctx, r, connect, settings)
+		}(&results[i])
+	}
+
+	wg.Wait()
+	return results
+}
+
+func applyHost(ctx context.Context, r *Result, connect ConnectFunc, settings map[string]interface{}) {
+	s, err := connect(ctx, r.Host)
+	if err != nil {
+		r.Err = err
+		return
+	}
+
+	err = applySession(ctx, r, s, settings)
+
+	// Close writes the new checksum
+	if cerr := s.Close(); err == nil {
+		err = cerr
+	}
+	r.Err = err
+}
+
+func applySession(ctx context.Context, r *Result, s Session, settings map[string]interface{}) (err error) {
+	var names []string
+	for name := range settings {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	// Restore changed parameters on failure
+	previous := make(map[string]interface{})
+	defer func() {
+		if err == nil || len(r.Changed) == 0 {
+			return
+		}
+		for _, name := range r.Changed {
+			if rerr := s.WriteCMOSParameter(name, previous[name]); rerr != nil {
+				err = fmt.Errorf("%v; rollback of %s failed: %v", err, name, rerr)
+				return
+			}
+		}
+		r.RolledBack = true
+	}()
+
+	for _, name := range names {
+		if err = ctx.Err(); err != nil {
+			return
+		}
+
+		value := settings[name]
+		var old interface{}
+		old, err = s.ReadCMOSParameter(name)
+		if err != nil {
+			return
+		}
+		if sameValue(old, value) {
+			continue
+		}
+
+		previous[name] = old
+		r.Changed = append(r.Changed, name)
+		err = s.WriteCMOSParameter(name, value)
+		if err != nil {
+			return
+		}
+
+		// Verify the value written
+		var v interface{}
+		v, err = s.ReadCMOSParameter(name)
+		if err != nil {
+			return
+		}
+		if !sameValue(v, value) {
+			err = fmt.Errorf("fleet: %s: %s reads back %v after writing %v", r.Host, name, v, value)
+			return
+		}
+	}
+	return
+}
+
+// sameValue reports if a value read equals a setting. A Store reads
+// strings, which are compared with the setting as StoreSession writes it.
+func sameValue(read, setting interface{}) bool {
+	if s, ok := read.(string); ok {
+		return s == fmt.Sprint(setting)
+	}
+	return reflect.DeepEqual(read, setting)
+}
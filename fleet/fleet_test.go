@@ -0,0 +1,88 @@
+// Copyright © 2019 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+package fleet
+
+import (
+	"context"
+	"github.com/platinasystems/nvram"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+)
+
+// testLayout has a decimal entry with a unit.
+const testLayout = `entries
+
+#start-bit length  config config-ID    name
+0          384     r      0        reserved_memory
+384        16      h      0        cpu_freq
+400        8       h      0        fan_level
+1008       16      h      0        check_sum
+
+enumerations
+
+checksums
+
+checksum 384 1007 1008
+
+metadata
+
+cpu_freq unit=MHz base=10
+fan_level base=10
+`
+
+// writeTest writes testLayout and a zeroed CMOS file and returns their
+// paths.
+func writeTest(t *testing.T) (dir, layout, cmos string) {
+	dir = t.TempDir()
+	layout = filepath.Join(dir, "cmos.layout")
+	cmos = filepath.Join(dir, "cmos.bin")
+	if err := ioutil.WriteFile(layout, []byte(testLayout), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(cmos, make([]byte, 256), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return
+}
+
+func TestApplyStoreSession(t *testing.T) {
+	dir, layout, cmos := writeTest(t)
+	connect := func(ctx context.Context, host string) (Session, error) {
+		nv := nvram.NewNVRAM(nvram.WithLockDir(dir))
+		if err := nv.Open(layout, cmos); err != nil {
+			return nil, err
+		}
+		return StoreSession(nv.Store(), nv.Close), nil
+	}
+
+	settings := map[string]interface{}{"cpu_freq": "1600", "fan_level": uint64(3)}
+	results := Apply(context.Background(), []string{"host"}, connect, settings, 1)
+	r := results[0]
+	if r.Err != nil || r.RolledBack {
+		t.Fatalf("Apply returned %v, rolled back %v", r.Err, r.RolledBack)
+	}
+	if len(r.Changed) != 2 || r.Changed[0] != "cpu_freq" || r.Changed[1] != "fan_level" {
+		t.Errorf("Changed is %v, want [cpu_freq fan_level]", r.Changed)
+	}
+
+	// The values were written and applying them again changes nothing.
+	nv := nvram.NewNVRAM(nvram.WithLockDir(dir))
+	if err := nv.Open(layout, cmos); err != nil {
+		t.Fatal(err)
+	}
+	for name, want := range map[string]uint64{"cpu_freq": 1600, "fan_level": 3} {
+		if v, err := nv.ReadCMOSParameter(name); err != nil || v != want {
+			t.Errorf("%s is %v, %v, want %v", name, v, err, want)
+		}
+	}
+	if err := nv.Close(); err != nil {
+		t.Fatal(err)
+	}
+	results = Apply(context.Background(), []string{"host"}, connect, settings, 1)
+	if r = results[0]; r.Err != nil || len(r.Changed) != 0 {
+		t.Errorf("second Apply returned %v, changed %v", r.Err, r.Changed)
+	}
+}
@@ -0,0 +1,230 @@
+// Copyright © 2019 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+package nvram
+
+import (
+	"crypto/sha1"
+	"encoding/binary"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+)
+
+// DMIDir is the sysfs directory ReadDMI reads by default.
+const DMIDir = "/sys/class/dmi/id"
+
+// DMI holds the SMBIOS fields used for hardware ids, keyed by the fwupd
+// field names, e.g. "Manufacturer" or "BiosVersion". Missing fields are
+// absent.
+type DMI map[string]string
+
+// dmiFiles maps DMI fields to their sysfs file names.
+var dmiFiles = map[string]string{
+	"Manufacturer":          "sys_vendor",
+	"Family":                "product_family",
+	"ProductName":           "product_name",
+	"ProductSku":            "product_sku",
+	"BiosVendor":            "bios_vendor",
+	"BiosVersion":           "bios_version",
+	"BaseboardManufacturer": "board_vendor",
+	"BaseboardProduct":      "board_name",
+	"EnclosureKind":         "chassis_type",
+}
+
+// ReadDMI reads the DMI fields from a sysfs DMI directory, DMIDir if dir is
+// empty.
+func ReadDMI(dir string) (dmi DMI, err error) {
+	if dir == "" {
+		dir = DMIDir
+	}
+	if _, err = os.Stat(dir); err != nil {
+		return
+	}
+
+	dmi = make(DMI)
+	for field, file := range dmiFiles {
+		b, rerr := ioutil.ReadFile(filepath.Join(dir, file))
+		if rerr != nil {
+			continue
+		}
+		if s := strings.TrimSpace(string(b)); s != "" {
+			dmi[field] = s
+		}
+	}
+
+	// Chassis type is written in hex, BIOS release is split in two
+	// fields written as hex bytes.
+	if s, ok := dmi["EnclosureKind"]; ok {
+		if n, err := strconv.ParseUint(s, 10, 8); err == nil {
+			dmi["EnclosureKind"] = fmt.Sprintf("%x", n)
+		}
+	}
+	if b, rerr := ioutil.ReadFile(filepath.Join(dir, "bios_release")); rerr == nil {
+		parts := strings.SplitN(strings.TrimSpace(string(b)), ".", 2)
+		if len(parts) == 2 {
+			major, err1 := strconv.ParseUint(parts[0], 10, 8)
+			minor, err2 := strconv.ParseUint(parts[1], 10, 8)
+			if err1 == nil && err2 == nil {
+				dmi["BiosMajorRelease"] = fmt.Sprintf("%02x", major)
+				dmi["BiosMinorRelease"] = fmt.Sprintf("%02x", minor)
+			}
+		}
+	}
+	return
+}
+
+// hardwareIDFields are the fields of the Microsoft computer hardware ids
+// HardwareID-0 to HardwareID-14 used by fwupd.
+var hardwareIDFields = [][]string{
+	{"Manufacturer", "Family", "ProductName", "ProductSku", "BiosVendor", "BiosVersion", "BiosMajorRelease", "BiosMinorRelease"},
+	{"Manufacturer", "Family", "ProductName", "BiosVendor", "BiosVersion", "BiosMajorRelease", "BiosMinorRelease"},
+	{"Manufacturer", "ProductName", "BiosVendor", "BiosVersion", "BiosMajorRelease", "BiosMinorRelease"},
+	{"Manufacturer", "Family", "ProductName", "ProductSku", "BaseboardManufacturer", "BaseboardProduct"},
+	{"Manufacturer", "Family", "ProductName", "ProductSku"},
+	{"Manufacturer", "Family", "ProductName"},
+	{"Manufacturer", "ProductSku", "BaseboardManufacturer", "BaseboardProduct"},
+	{"Manufacturer", "ProductSku"},
+	{"Manufacturer", "ProductName", "BaseboardManufacturer", "BaseboardProduct"},
+	{"Manufacturer", "ProductName"},
+	{"Manufacturer", "Family", "BaseboardManufacturer", "BaseboardProduct"},
+	{"Manufacturer", "Family"},
+	{"Manufacturer", "EnclosureKind"},
+	{"Manufacturer", "BaseboardManufacturer", "BaseboardProduct"},
+	{"Manufacturer"},
+}
+
+// hardwareIDNamespace is the Microsoft CHID namespace
+// 70ffd812-4c7f-4c7d-0000-000000000000.
+var hardwareIDNamespace = []byte{0x70, 0xff, 0xd8, 0x12, 0x4c, 0x7f, 0x4c, 0x7d,
+	0, 0, 0, 0, 0, 0, 0, 0}
+
+// HardwareIDs returns the hardware id GUIDs of the DMI fields in
+// HardwareID-0 to HardwareID-14 order, as fwupd computes them. Ids using a
+// missing field are skipped.
+func (dmi DMI) HardwareIDs() (guids []string) {
+next:
+	for _, fields := range hardwareIDFields {
+		values := make([]string, len(fields))
+		for i, field := range fields {
+			v, ok := dmi[field]
+			if !ok {
+				continue next
+			}
+			values[i] = v
+		}
+		guids = append(guids, hardwareIDGUID(strings.Join(values, "&")))
+	}
+	return
+}
+
+// hardwareIDGUID returns the name based version 5 GUID of s encoded in
+// UTF-16LE.
+func hardwareIDGUID(s string) string {
+	h := sha1.New()
+	h.Write(hardwareIDNamespace)
+	for _, c := range utf16.Encode([]rune(s)) {
+		var b [2]byte
+		binary.LittleEndian.PutUint16(b[:], c)
+		h.Write(b[:])
+	}
+	u := h.Sum(nil)[:16]
+	u[6] = (u[6] & 0x0f) | 0x50
+	u[8] = (u[8] & 0x3f) | 0x80
+
+	return fmt.Sprintf("%x-%x-%x-%x-%x", u[0:4], u[4:6], u[6:8], u[8:10], u[10:16])
+}
+
+// BiosSettingKind is the fwupd BIOS setting type.
+type BiosSettingKind int
+
+const (
+	BiosSettingEnumeration BiosSettingKind = 1
+	BiosSettingInteger     BiosSettingKind = 2
+	BiosSettingString      BiosSettingKind = 3
+)
+
+// BiosSetting describes a parameter as a fwupd BIOS setting attribute.
+// Integer settings bound the value, string settings the length.
+type BiosSetting struct {
+	Name            string          `json:"Name"`
+	Description     string          `json:"Description,omitempty"`
+	Filename        string          `json:"Filename"`
+	ID              string          `json:"BiosSettingId"`
+	Kind            BiosSettingKind `json:"BiosSettingType"`
+	CurrentValue    string          `json:"BiosSettingCurrentValue"`
+	ReadOnly        bool            `json:"BiosSettingReadOnly"`
+	PossibleValues  []string        `json:"BiosSettingPossibleValues,omitempty"`
+	LowerBound      uint64          `json:"BiosSettingLowerBound,omitempty"`
+	UpperBound      uint64          `json:"BiosSettingUpperBound,omitempty"`
+	ScalarIncrement uint64          `json:"BiosSettingScalarIncrement,omitempty"`
+}
+
+// BiosSettings returns every parameter as a BIOS setting. Parameters of
+// groups with the GroupReadOnly policy are read only.
+func (nv *NVRAM) BiosSettings() (settings []BiosSetting, err error) {
+	for _, name := range nv.ParameterNames() {
+		var info ParameterInfo
+		info, err = nv.ParameterInfo(name)
+		if err != nil {
+			return
+		}
+		var value interface{}
+		value, err = nv.readRawParameter(name)
+		if err != nil {
+			return
+		}
+
+		s := BiosSetting{
+			Name:         name,
+			Description:  info.Description,
+			Filename:     name,
+			ID:           "coreboot." + name,
+			CurrentValue: formatParameterValue(value),
+			ReadOnly: nv.groupPolicies[nv.parameterGroup(name)] == GroupReadOnly ||
+				info.Config == CMOSEntryReserved,
+		}
+		// Settings without a number value are presented as strings.
+		n, isNumber := value.(uint64)
+		switch {
+		case info.Config == CMOSEntryEnum:
+			s.Kind = BiosSettingEnumeration
+			s.PossibleValues = info.Values
+		case info.Config == CMOSEntryHex && !info.Virtual && isNumber:
+			s.Kind = BiosSettingInteger
+			s.CurrentValue = strconv.FormatUint(n, 10)
+			s.LowerBound, s.UpperBound = info.Min, info.Max
+			s.ScalarIncrement = 1
+		default:
+			s.Kind = BiosSettingString
+			if max, merr := nv.MaxLength(name); merr == nil {
+				s.UpperBound = uint64(max)
+			}
+		}
+		settings = append(settings, s)
+	}
+	return
+}
+
+// FwupdMetadata is the device metadata a fwupd plugin needs.
+type FwupdMetadata struct {
+	HardwareIDs  []string      `json:"HardwareIds"`
+	BiosSettings []BiosSetting `json:"BiosSettings"`
+}
+
+// FwupdMetadata returns the hardware ids of the DMI fields in dmiDir, DMIDir
+// if empty, and the parameters as BIOS settings.
+func (nv *NVRAM) FwupdMetadata(dmiDir string) (m FwupdMetadata, err error) {
+	dmi, err := ReadDMI(dmiDir)
+	if err != nil {
+		return
+	}
+	m.HardwareIDs = dmi.HardwareIDs()
+	m.BiosSettings, err = nv.BiosSettings()
+	return
+}
@@ -0,0 +1,143 @@
+// Copyright © 2019 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+package nvram
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// HealthReport is the result of HealthCheck.
+type HealthReport struct {
+	Time    time.Time `json:"time"`
+	Backend string    `json:"backend"`
+	// Healthy is set if no problem was found.
+	Healthy bool `json:"healthy"`
+
+	ChecksumOK       bool   `json:"checksum_ok"`
+	ComputedChecksum uint16 `json:"computed_checksum"`
+	StoredChecksum   uint16 `json:"stored_checksum"`
+
+	// LayoutFindings are the problems reported by Layout.Validate.
+	LayoutFindings []string `json:"layout_findings,omitempty"`
+	// DecodeFailures are parameters that can not be read or hold a
+	// value not in their enum.
+	DecodeFailures []string `json:"decode_failures,omitempty"`
+	// ConstraintViolations are layout constraints not met.
+	ConstraintViolations []string `json:"constraint_violations,omitempty"`
+
+	// BatteryKnown is cleared for backends without RTC registers.
+	BatteryKnown bool `json:"battery_known"`
+	BatteryGood  bool `json:"battery_good"`
+
+	// LastBackup is the time of the newest snapshot in the store set
+	// WithSnapshotStore, zero if there is none.
+	LastBackup time.Time     `json:"last_backup,omitempty"`
+	BackupAge  time.Duration `json:"backup_age,omitempty"`
+
+	// Conflicts are other users of the CMOS hardware found by
+	// Conflicts. They are warnings and do not affect Healthy.
+	Conflicts []string `json:"conflicts,omitempty"`
+
+	// Drift are the parameters differing from their default, as found
+	// by DriftFromDefaults. They are informational and do not affect
+	// Healthy.
+	Drift []string `json:"drift,omitempty"`
+
+	// Errors are failures to run a check.
+	Errors []string `json:"errors,omitempty"`
+}
+
+// WithSnapshotStore names the snapshot store directory used for backups, so
+// HealthCheck can report the age of the last backup.
+func WithSnapshotStore(dir string) Option {
+	return func(nv *NVRAM) {
+		nv.snapshotDir = dir
+	}
+}
+
+// HealthCheck runs all validations of the open NVRAM and returns one
+// report.
+func (nv *NVRAM) HealthCheck() (r HealthReport) {
+	r.Time = time.Now()
+	r.Backend = nv.CMOS.backend
+
+	// Checksum
+	var err error
+	r.ComputedChecksum, err = nv.CMOS.ComputeChecksum()
+	if err == nil {
+		r.StoredChecksum, err = nv.CMOS.ReadChecksum()
+	}
+	if err != nil {
+		r.Errors = append(r.Errors, fmt.Sprintf("checksum: %v", err))
+	}
+	r.ChecksumOK = err == nil && (nv.CMOS.checksum.kind == ChecksumNone ||
+		r.ComputedChecksum == r.StoredChecksum)
+
+	for _, f := range nv.Layout.Validate() {
+		r.LayoutFindings = append(r.LayoutFindings, f.Error())
+	}
+
+	// Decode every parameter
+	for _, name := range nv.ParameterNames() {
+		value, err := nv.ReadCMOSParameter(name)
+		if err != nil {
+			r.DecodeFailures = append(r.DecodeFailures, fmt.Sprintf("%s: %v", name, err))
+		} else if s, ok := value.(string); ok && strings.HasSuffix(s, "# Bad Value") {
+			r.DecodeFailures = append(r.DecodeFailures, fmt.Sprintf("%s: %s", name, s))
+		}
+	}
+
+	for _, err := range nv.constraintViolations() {
+		r.ConstraintViolations = append(r.ConstraintViolations, err.Error())
+	}
+
+	// RTC battery
+	r.BatteryGood, err = nv.BatteryGood()
+	r.BatteryKnown = err == nil
+	if err != nil && err != ErrRTCNotSupported {
+		r.Errors = append(r.Errors, fmt.Sprintf("battery: %v", err))
+	}
+
+	// Other CMOS users
+	conflicts, err := nv.conflicts()
+	if err != nil {
+		r.Errors = append(r.Errors, fmt.Sprintf("conflicts: %v", err))
+	}
+	for _, c := range conflicts {
+		r.Conflicts = append(r.Conflicts, c.String())
+	}
+
+	// Changes from defaults
+	drift, err := nv.DriftFromDefaults()
+	if err != nil {
+		r.Errors = append(r.Errors, fmt.Sprintf("drift: %v", err))
+	}
+	for _, d := range drift {
+		e, _ := nv.entry(d.Name)
+		r.Drift = append(r.Drift, fmt.Sprintf("%s: %s, default %s", d.Name,
+			e.formatValue(d.After), e.formatValue(d.Before)))
+	}
+
+	// Last backup
+	if nv.snapshotDir != "" {
+		s, err := nv.OpenSnapshotStore(nv.snapshotDir)
+		var history []SnapshotInfo
+		if err == nil {
+			history, err = s.History()
+		}
+		if err != nil {
+			r.Errors = append(r.Errors, fmt.Sprintf("backup: %v", err))
+		} else if len(history) > 0 {
+			r.LastBackup = history[0].Time
+			r.BackupAge = r.Time.Sub(r.LastBackup)
+		}
+	}
+
+	r.Healthy = r.ChecksumOK && len(r.LayoutFindings) == 0 && len(r.DecodeFailures) == 0 &&
+		len(r.ConstraintViolations) == 0 && (!r.BatteryKnown || r.BatteryGood) && len(r.Errors) == 0
+	return
+}
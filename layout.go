@@ -3,6 +3,7 @@ package nvram
 import (
 	"fmt"
 	"sort"
+	"sync"
 )
 
 type CMOSEnumItem struct {
@@ -32,11 +33,20 @@ type CMOSEnum struct {
 	stoi map[string]uint
 }
 
+// Layout is safe to share across goroutines. Getters return copies of
+// entries, so entries are only changed through the layout's methods.
 type Layout struct {
+	mu           sync.RWMutex
 	enums        map[uint]*CMOSEnum
 	entries      map[string]*CMOSEntry
 	entrieslist  []*CMOSEntry
+	subfields    map[string]*CMOSSubfield
+	aliases      map[string]string
+	translations map[string]map[uint]map[uint]string
+	constraints  []*CMOSConstraint
 	cmosChecksum *CMOSChecksum
+	version      uint
+	defaults     []byte
 }
 
 func NewLayout() *Layout {
@@ -44,9 +54,14 @@ func NewLayout() *Layout {
 	return &Layout{
 		enums:        make(map[uint]*CMOSEnum),
 		entries:      make(map[string]*CMOSEntry),
+		subfields:    make(map[string]*CMOSSubfield),
+		aliases:      make(map[string]string),
+		translations: make(map[string]map[uint]map[uint]string),
 		cmosChecksum: c}
 }
 
+// AddCMOSEntry adds a copy of entry to the layout. The entry must not
+// overlap another entry or have the name of one.
 func (l *Layout) AddCMOSEntry(entry *CMOSEntry) (err error) {
 	// Verify CMOS Entry
 	err = verifyCMOSEntry(entry)
@@ -54,11 +69,27 @@ func (l *Layout) AddCMOSEntry(entry *CMOSEntry) (err error) {
 		return
 	}
 
+	// Keep a private copy of the entry.
+	entry = entry.copy()
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if _, ok := l.entries[entry.name]; ok {
+		err = fmt.Errorf("CMOS parameter %s already exists.", entry.name)
+		return
+	}
+	return l.insertEntry(entry)
+}
+
+// insertEntry adds an entry to the entry list and map. The lock must be
+// held.
+func (l *Layout) insertEntry(entry *CMOSEntry) (err error) {
 	// Add entries to entry list sorted by starting bit.
-	var pos int = 0
+	pos := len(l.entrieslist)
 	for i, e := range l.entrieslist {
-		pos = i
 		if entry.bit < e.bit {
+			pos = i
 			break
 		}
 	}
@@ -72,33 +103,147 @@ func (l *Layout) AddCMOSEntry(entry *CMOSEntry) (err error) {
 			}
 		}
 
-		if entry.IsOverlap(l.entrieslist[pos]) {
+		if pos < len(l.entrieslist) && entry.IsOverlap(l.entrieslist[pos]) {
 			err = fmt.Errorf("Entry %s overlaps %s", *entry, l.entrieslist[pos])
 			return
 		}
 	}
 
-	// Add new entry to list at correct position
-	l.entrieslist = append(l.entrieslist, nil)
-	copy(l.entrieslist[pos+1:], l.entrieslist[pos:])
-	l.entrieslist[pos] = entry
+	// Add new entry to a new list at correct position, so lists returned
+	// earlier are not changed.
+	list := make([]*CMOSEntry, 0, len(l.entrieslist)+1)
+	list = append(list, l.entrieslist[:pos]...)
+	list = append(list, entry)
+	l.entrieslist = append(list, l.entrieslist[pos:]...)
 
 	// Add entry to enteries map
 	l.entries[entry.name] = entry
 	return
 }
 
-func (l *Layout) GetCMOSEntriesList() []*CMOSEntry {
+// IsEmpty returns true if the layout has no entries.
+func (l *Layout) IsEmpty() bool {
+	return len(l.entryList()) == 0
+}
+
+// GetCMOSEntriesList returns copies of all entries sorted by bit offset.
+func (l *Layout) GetCMOSEntriesList() (list []*CMOSEntry) {
 	// Return a copy of the sorted CMOS entry list.
+	for _, e := range l.entryList() {
+		list = append(list, e.copy())
+	}
+	return
+}
+
+// entryList returns the current sorted entry list. The list and its entries
+// are never changed once published, so it can be used without the lock.
+func (l *Layout) entryList() []*CMOSEntry {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
 	return l.entrieslist
 }
 
+// entry returns the layout's own entry, which must not be changed.
+func (l *Layout) entry(name string) (e *CMOSEntry, ok bool) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	e, ok = l.entries[name]
+	return
+}
+
+func (l *Layout) parameterNames() (names []string) {
+	return l.listNames(false)
+}
+
+// listNames lists readable entries in layout order followed by subfields.
+// Reserved entries are included if reserved is set.
+func (l *Layout) listNames(reserved bool) (names []string) {
+	for _, e := range l.entryList() {
+		if (e.config == CMOSEntryReserved && !reserved) || e.name == "check_sum" {
+			continue
+		}
+		names = append(names, e.name)
+	}
+	for _, s := range l.GetCMOSSubfieldsList() {
+		names = append(names, s.name)
+	}
+	return
+}
+
+// FindCMOSEntry returns a copy of the named entry. Use UpdateCMOSEntry to
+// change an entry of the layout.
 func (l *Layout) FindCMOSEntry(name string) (entry *CMOSEntry, ok bool) {
-	entry, ok = l.entries[name]
+	entry, ok = l.entry(name)
+	if ok {
+		entry = entry.copy()
+	}
+	return
+}
+
+// UpdateCMOSEntry calls update with a copy of the named entry and replaces
+// the entry with the copy if update returns no error.
+//
+//	l.UpdateCMOSEntry("baud_rate", func(e *CMOSEntry) error {
+//		e.SetDescription("Serial console baud rate")
+//		return nil
+//	})
+func (l *Layout) UpdateCMOSEntry(name string, update func(e *CMOSEntry) error) (err error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	old, ok := l.entries[name]
+	if !ok {
+		err = &ParameterError{Name: name, Err: ErrParameterNotFound}
+		return
+	}
+
+	e := old.copy()
+	err = update(e)
+	if err != nil {
+		return
+	}
+
+	// Only metadata may change, position and type are fixed.
+	if e.bit != old.bit || e.length != old.length || e.config != old.config ||
+		e.config_id != old.config_id || e.name != old.name {
+		err = fmt.Errorf("CMOS entry %s position and type can not be updated.", name)
+		return
+	}
+
+	l.replaceEntry(old, e)
 	return
 }
 
+// replaceEntry replaces an entry in the map, a new entry list and subfields
+// of the entry. The lock must be held.
+func (l *Layout) replaceEntry(old, e *CMOSEntry) {
+	list := make([]*CMOSEntry, len(l.entrieslist))
+	for i, le := range l.entrieslist {
+		if le == old {
+			le = e
+		}
+		list[i] = le
+	}
+	l.entrieslist = list
+	l.entries[e.name] = e
+	l.reparentSubfields(old, e)
+}
+
+// reparentSubfields replaces subfields of old with subfields of e. The lock
+// must be held.
+func (l *Layout) reparentSubfields(old, e *CMOSEntry) {
+	for name, s := range l.subfields {
+		if s.parent == old {
+			ns := *s
+			ns.parent = e
+			l.subfields[name] = &ns
+		}
+	}
+}
+
 func (l *Layout) AddCMOSEnum(item *CMOSEnumItem) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
 
 	// Create new CMOS Enum for each item's id.
 	enum, ok := l.enums[item.id]
@@ -116,6 +261,12 @@ func (l *Layout) AddCMOSEnum(item *CMOSEnumItem) {
 }
 
 func (l *Layout) FindCMOSEnumText(id uint, value uint) (text string, ok bool) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return l.findCMOSEnumText(id, value)
+}
+
+func (l *Layout) findCMOSEnumText(id uint, value uint) (text string, ok bool) {
 	var enum *CMOSEnum
 
 	// Find CMOS Enum by id
@@ -130,6 +281,12 @@ func (l *Layout) FindCMOSEnumText(id uint, value uint) (text string, ok bool) {
 }
 
 func (l *Layout) FindCMOSEnumValue(id uint, text string) (value uint, ok bool) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return l.findCMOSEnumValue(id, text)
+}
+
+func (l *Layout) findCMOSEnumValue(id uint, text string) (value uint, ok bool) {
 	var enum *CMOSEnum
 
 	// Find CMOS Enum by id
@@ -143,7 +300,14 @@ func (l *Layout) FindCMOSEnumValue(id uint, text string) (value uint, ok bool) {
 	return
 }
 
+// GetCMOSEnumItemsById returns the items of enum id sorted by value.
 func (l *Layout) GetCMOSEnumItemsById(id uint) (items []CMOSEnumItem, ok bool) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return l.getCMOSEnumItemsById(id)
+}
+
+func (l *Layout) getCMOSEnumItemsById(id uint) (items []CMOSEnumItem, ok bool) {
 
 	// Find CMOS Enum by id
 	enum, ok := l.enums[id]
@@ -171,7 +335,11 @@ func (l *Layout) GetCMOSEnumItemsById(id uint) (items []CMOSEnumItem, ok bool) {
 	return
 }
 
+// GetCMOSEnumItems returns all enum items sorted by id and then by value.
 func (l *Layout) GetCMOSEnumItems() (items []CMOSEnumItem) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
 	// Create sorted list of enum ids.	
 	var ids []int
 	for id := range l.enums {
@@ -181,7 +349,7 @@ func (l *Layout) GetCMOSEnumItems() (items []CMOSEnumItem) {
 
 	// Add all enum items for all ids to list
 	for _, id := range ids {
-		items_for_id, _ := l.GetCMOSEnumItemsById(uint(id))
+		items_for_id, _ := l.getCMOSEnumItemsById(uint(id))
 		items = append(items, items_for_id...)
 	}
 
@@ -191,3 +359,17 @@ func (l *Layout) GetCMOSEnumItems() (items []CMOSEnumItem) {
 func (l *Layout) GetCheckChecksum() CMOSChecksum {
 	return *l.cmosChecksum
 }
+
+// Version returns the layout schema version. Layouts without a version
+// directive or record have version 0.
+func (l *Layout) Version() uint {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return l.version
+}
+
+func (l *Layout) SetVersion(version uint) {
+	l.mu.Lock()
+	l.version = version
+	l.mu.Unlock()
+}
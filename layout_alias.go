@@ -0,0 +1,123 @@
+// Copyright © 2019 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+package nvram
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// AddCMOSAlias makes oldName refer to the parameter newName, so callers
+// using a name from before a firmware rename keep working.
+func (l *Layout) AddCMOSAlias(oldName, newName string) (err error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if _, ok := l.entries[oldName]; ok {
+		err = fmt.Errorf("Alias %s is a CMOS parameter.", oldName)
+		return
+	}
+	if _, ok := l.subfields[oldName]; ok {
+		err = fmt.Errorf("Alias %s is a CMOS subfield.", oldName)
+		return
+	}
+	if _, ok := l.aliases[oldName]; ok {
+		err = fmt.Errorf("Alias %s already exists.", oldName)
+		return
+	}
+
+	// Alias must refer to a defined parameter
+	_, isEntry := l.entries[newName]
+	_, isSubfield := l.subfields[newName]
+	if !isEntry && !isSubfield {
+		err = &ParameterError{Name: newName, Err: ErrParameterNotFound}
+		return
+	}
+
+	l.aliases[oldName] = newName
+	return
+}
+
+// ResolveCMOSAlias returns the parameter name an alias refers to.
+func (l *Layout) ResolveCMOSAlias(name string) (newName string, ok bool) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	newName, ok = l.aliases[name]
+	return
+}
+
+// GetCMOSAliases returns the old names aliased to a parameter.
+func (l *Layout) GetCMOSAliases(name string) (aliases []string) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	for oldName, newName := range l.aliases {
+		if newName == name {
+			aliases = append(aliases, oldName)
+		}
+	}
+	sort.Strings(aliases)
+	return
+}
+
+// DeprecateCMOSEntry marks an entry as deprecated. The first access of a
+// deprecated parameter through an NVRAM logs the note.
+func (l *Layout) DeprecateCMOSEntry(name, note string) (err error) {
+	return l.UpdateCMOSEntry(name, func(e *CMOSEntry) error {
+		e.meta.deprecated = true
+		e.meta.deprecation = note
+		return nil
+	})
+}
+
+func (e CMOSEntry) Deprecated() (deprecated bool, note string) {
+	return e.meta.deprecated, e.meta.deprecation
+}
+
+// deprecationLog holds the names whose deprecation notice was logged.
+type deprecationLog struct {
+	mu     sync.Mutex
+	logged map[string]bool
+}
+
+// once reports true the first time it is called with name.
+func (d *deprecationLog) once(name string) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.logged[name] {
+		return false
+	}
+	if d.logged == nil {
+		d.logged = make(map[string]bool)
+	}
+	d.logged[name] = true
+	return true
+}
+
+// resolveName follows aliases and logs the deprecation notice of name the
+// first time it is used.
+func (nv *NVRAM) resolveName(name string) string {
+	if nv.Layout == nil {
+		return name
+	}
+
+	if newName, ok := nv.ResolveCMOSAlias(name); ok {
+		if nv.deprecations.once(name) {
+			nv.logf("nvram: CMOS parameter %s is deprecated, use %s.", name, newName)
+		}
+		name = newName
+	}
+
+	if e, ok := nv.entry(name); ok && e.meta.deprecated && nv.deprecations.once(name) {
+		if e.meta.deprecation != "" {
+			nv.logf("nvram: CMOS parameter %s is deprecated: %s", name, e.meta.deprecation)
+		} else {
+			nv.logf("nvram: CMOS parameter %s is deprecated.", name)
+		}
+	}
+	return name
+}
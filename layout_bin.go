@@ -1,78 +1,116 @@
 package nvram
 
 import (
+	"encoding/binary"
 	"fmt"
-	"os"
-	"syscall"
-	"unsafe"
+	"io/ioutil"
 )
 
-type cmosEntryTableRecord struct {
-	lbRecord
-	bit      uint32
-	length   uint32
-	config   uint32
-	configId uint32
-	name     [32]byte
-}
+// CMOS option table record tags.
+const (
+	cmosTagOptionTable = 200
+	cmosTagEntry       = 201
+	cmosTagEnum        = 202
+	cmosTagDefaults    = 203
+	cmosTagChecksum    = 204
+	// cmosTagVersion is a Platina extension to the coreboot CMOS option
+	// table holding the layout schema version.
+	cmosTagVersion = 205
+)
 
-type cmosEnumTableRecord struct {
-	lbRecord
-	configId uint32
-	value    uint32
-	text     [32]byte
-}
+// Minimum record sizes, including the 8 byte tag and size header. Entry
+// names and enum texts are 32 byte NUL padded strings. The defaults record
+// holds a 256 byte CMOS image after a 32 byte name.
+const (
+	cmosOptionTableSize    = 12
+	cmosEntryRecordSize    = 24 + 32
+	cmosEnumRecordSize     = 16 + 32
+	cmosDefaultsRecordSize = 12 + 32 + 256
+	cmosChecksumRecordSize = 24
+	cmosVersionRecordSize  = 12
+)
 
-type cmosChecksumTableRecord struct {
-	lbRecord
-	rangeStart   uint32
-	rangeEnd     uint32
-	location     uint32
-	checksumType uint32
+// cString returns the string of b up to its first NUL byte.
+func cString(b []byte) string {
+	for i, v := range b {
+		if v == 0 {
+			return string(b[:i])
+		}
+	}
+	return string(b)
 }
 
+// ReadLayoutFromCMOSTable reads the layout of a CMOS option table mapped in
+// memory, such as the one in the machine's coreboot table.
 func ReadLayoutFromCMOSTable(table *cmosOptionTable) (layout *Layout, err error) {
 	// Check that we have a valid CMOS Option table
-	if table == nil || table.tag != 200 {
+	if table == nil || table.tag != cmosTagOptionTable {
 		err = fmt.Errorf("Not a valid CMOS Option Table")
 		return
 	}
+	return decodeCMOSTable(recordBytes(&table.lbRecord))
+}
+
+// decodeCMOSTable reads the layout of a CMOS option table. All fields are
+// decoded as little endian, as coreboot writes them, whatever the host.
+func decodeCMOSTable(b []byte) (layout *Layout, err error) {
+	le := binary.LittleEndian
+
+	// Check that we have a valid CMOS Option table
+	if len(b) < cmosOptionTableSize || le.Uint32(b[0:]) != cmosTagOptionTable {
+		err = fmt.Errorf("Not a valid CMOS Option Table")
+		return
+	}
+	size := le.Uint32(b[4:])
+	headerLength := le.Uint32(b[8:])
+	if uint64(size) > uint64(len(b)) || headerLength < cmosOptionTableSize || headerLength > size {
+		err = fmt.Errorf("CMOS Option Table has bad size %d and header length %d",
+			size, headerLength)
+		return
+	}
 
 	// Create a new empty CMOS layout.
 	layout = NewLayout()
+	var defaults []byte
 
-	// Set address into option table after table header.
-	var address = uintptr(unsafe.Pointer(table)) + uintptr(table.headerLength)
-	var endAddress = address + uintptr(table.size-table.headerLength)
-
-	for {
-		// Continue looking for table records till end of table data.
-		if address >= endAddress {
-			break
+	// Look at table records after table header till end of table data.
+	for b = b[headerLength:size]; len(b) > 0; {
+		if len(b) < 8 {
+			err = fmt.Errorf("CMOS Option Table ends in a partial record")
+			return
+		}
+		tag := le.Uint32(b[0:])
+		recSize := le.Uint32(b[4:])
+		if recSize < 8 || uint64(recSize) > uint64(len(b)) {
+			err = fmt.Errorf("CMOS Option Table record %d has bad size %d", tag, recSize)
+			return
+		}
+		rec := b[:recSize]
+		b = b[recSize:]
+
+		short := func(min int) bool {
+			if len(rec) < min {
+				err = fmt.Errorf("CMOS Option Table record %d of %d bytes is too short",
+					tag, len(rec))
+				return true
+			}
+			return false
 		}
 
-		// Look at current table record
-		var lbrec = (*lbRecord)(unsafe.Pointer(address))
-
-		switch lbrec.tag {
+		switch tag {
 		// Decode CMOS entry Table Record
-		case 201:
-			var rec = (*cmosEntryTableRecord)(unsafe.Pointer(lbrec))
+		case cmosTagEntry:
+			if short(cmosEntryRecordSize) {
+				return
+			}
 			var entry CMOSEntry
 
 			// Read values for CMOS Entry
-			entry.bit = uint(rec.bit)
-			entry.length = uint(rec.length)
-			entry.config = CMOSEntryConfig(rec.config)
-			entry.config_id = uint(rec.configId)
-
-			// Copy string from table entry
-			for _, v := range rec.name {
-				if v == 0 {
-					break
-				}
-				entry.name = entry.name + string(v)
-			}
+			entry.bit = uint(le.Uint32(rec[8:]))
+			entry.length = uint(le.Uint32(rec[12:]))
+			entry.config = CMOSEntryConfig(le.Uint32(rec[16:]))
+			entry.config_id = uint(le.Uint32(rec[20:]))
+			entry.name = cString(rec[24:56])
 
 			// Add CMOS entry to layout
 			err = layout.AddCMOSEntry(&entry)
@@ -80,97 +118,81 @@ func ReadLayoutFromCMOSTable(table *cmosOptionTable) (layout *Layout, err error)
 				return
 			}
 
-		// Decode CMOS Enumeration Record		
-		case 202:
-			var rec = (*cmosEnumTableRecord)(unsafe.Pointer(lbrec))
+		// Decode CMOS Enumeration Record
+		case cmosTagEnum:
+			if short(cmosEnumRecordSize) {
+				return
+			}
 			var item CMOSEnumItem
 
 			// Read values for CMOS enumeration
-			item.id = uint(rec.configId)
-			item.value = uint(rec.value)
-
-			// Copy string from table entry
-			for _, v := range rec.text {
-				if v == 0 {
-					break
-				}
-				item.text = item.text + string(v)
-			}
+			item.id = uint(le.Uint32(rec[8:]))
+			item.value = uint(le.Uint32(rec[12:]))
+			item.text = cString(rec[16:48])
 
 			// Check if enumeration value already exists for an id.
 			_, ok := layout.FindCMOSEnumText(item.id, item.value)
 			if ok {
-				fmt.Errorf("Enum %d already exists for id %d",
-					item.id, item.value)
+				err = fmt.Errorf("Enum %d already exists for id %d",
+					item.value, item.id)
 				return
 			}
 
 			// Add CMOS enumeration to layout
 			layout.AddCMOSEnum(&item)
 
+		// Decode CMOS defaults Record
+		case cmosTagDefaults:
+			if short(cmosDefaultsRecordSize) {
+				return
+			}
+			defaults = append([]byte(nil), rec[44:300]...)
+
 		// Decode CMOS Checksum Record
-		case 204:
-			var rec = (*cmosChecksumTableRecord)(unsafe.Pointer(lbrec))
+		case cmosTagChecksum:
+			if short(cmosChecksumRecordSize) {
+				return
+			}
 
 			// Read and check CMOS checksum info.
-			layout.cmosChecksum, err = NewCMOSChecksum(uint(rec.rangeStart),
-				uint(rec.rangeEnd), uint(rec.location))
+			layout.cmosChecksum, err = NewCMOSChecksum(uint(le.Uint32(rec[8:])),
+				uint(le.Uint32(rec[12:])), uint(le.Uint32(rec[16:])))
+			if err != nil {
+				return
+			}
+			err = layout.cmosChecksum.setType(ChecksumType(le.Uint32(rec[20:])))
 			if err != nil {
 				return
 			}
-		}
 
-		// Move to next table record
-		address += uintptr(lbrec.size)
+		// Decode CMOS layout version Record
+		case cmosTagVersion:
+			if short(cmosVersionRecordSize) {
+				return
+			}
+			layout.version = uint(le.Uint32(rec[8:]))
+		}
 	}
 
+	// Take entry defaults from the defaults image once all entries and
+	// enums are known.
+	if defaults != nil {
+		err = layout.SetDefaultImage(defaults)
+	}
 	return
 }
 
+// ReadLayoutFromCMOSTableBinary reads the layout of a CMOS option table
+// file, such as coreboot's cmos_layout.bin.
 func ReadLayoutFromCMOSTableBinary(filename string) (layout *Layout, err error) {
-	var mem_file *os.File
-	var mem []byte
-
-	// Unmap and close CMOS Option table file.
-	defer func() {
-		if len(mem) > 0 {
-			syscall.Munmap(mem)
-			mem = nil
-		}
-
-		if mem_file != nil {
-			mem_file.Close()
-			mem_file = nil
-		}
-	}()
-
-	// Open CMOS option table file
-	mem_file, err = os.OpenFile(filename, os.O_RDONLY, 0)
-	if err != nil {
-		return
-	}
-
-	fi, err := mem_file.Stat()
-	if err != nil {
-		return
-	}
-	size := fi.Size()
-
-	if size < 0 {
-		err = fmt.Errorf("File %s has negative size.", filename)
-		return
-	}
-
-	// Map CMOS option table
-	mem, err = syscall.Mmap(int(mem_file.Fd()), 0, int(size),
-		syscall.PROT_READ, syscall.MAP_SHARED)
+	// Read CMOS option table file
+	b, err := ioutil.ReadFile(filename)
 	if err != nil {
 		return
 	}
 
-
-	// Read CMOS Option table and create layout	
-	return ReadLayoutFromCMOSTable((*cmosOptionTable)(unsafe.Pointer(&mem[0])))
+	// Read CMOS Option table and create layout
+	return decodeCMOSTable(b)
 }
 
 func ReadLayoutFromCoreBootTable() (layout *Layout, err error) {
@@ -0,0 +1,141 @@
+// Copyright © 2019 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+package nvram
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+)
+
+// layoutCache is the content of a layout cache file. Hash is the SHA-256 of
+// Layout and Defaults, Layout a Layout message and Defaults the defaults
+// image of the layout, if it has one.
+type layoutCache struct {
+	Fingerprint string `json:"fingerprint"`
+	Hash        string `json:"hash"`
+	Layout      []byte `json:"layout"`
+	Defaults    []byte `json:"defaults,omitempty"`
+}
+
+// WithLayoutCache makes Open keep the layout read from the machine's
+// coreboot table in the cache file path, so later opens skip mapping
+// /dev/mem and walking the table. The cache is only used while the DMI
+// fields, which include the BIOS version, are those it was written on, and
+// is rewritten when stale or damaged. Without readable DMI fields the table
+// is always read.
+func WithLayoutCache(path string) Option {
+	return func(nv *NVRAM) {
+		nv.layoutCache = path
+	}
+}
+
+// dmiFingerprint returns a hash of all DMI fields of the machine.
+func dmiFingerprint() (fp string, err error) {
+	dmi, err := ReadDMI("")
+	if err != nil {
+		return
+	}
+	if len(dmi) == 0 {
+		return "", fmt.Errorf("nvram: No DMI fields in %s.", DMIDir)
+	}
+	fields := make([]string, 0, len(dmi))
+	for field := range dmi {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+	h := sha256.New()
+	for _, field := range fields {
+		fmt.Fprintf(h, "%s=%s\n", field, dmi[field])
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+func (c *layoutCache) hash() string {
+	h := sha256.New()
+	h.Write(c.Layout)
+	h.Write(c.Defaults)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// readLayoutCache returns the layout of a cache file written on the machine
+// with fingerprint fp.
+func readLayoutCache(path, fp string) (layout *Layout, err error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return
+	}
+	var c layoutCache
+	err = json.Unmarshal(b, &c)
+	if err != nil {
+		return
+	}
+	if c.Fingerprint != fp {
+		return nil, fmt.Errorf("nvram: Layout cache %s is stale.", path)
+	}
+	if c.hash() != c.Hash {
+		return nil, fmt.Errorf("nvram: Layout cache %s is damaged.", path)
+	}
+
+	layout, err = UnmarshalLayoutProto(c.Layout)
+	if err == nil && c.Defaults != nil {
+		err = layout.SetDefaultImage(c.Defaults)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return
+}
+
+// writeLayoutCache writes layout to a cache file for the machine with
+// fingerprint fp.
+func writeLayoutCache(path, fp string, layout *Layout) (err error) {
+	c := layoutCache{Fingerprint: fp, Layout: MarshalLayoutProto(layout)}
+	c.Defaults, _ = layout.DefaultImage()
+	c.Hash = c.hash()
+	b, err := json.Marshal(&c)
+	if err != nil {
+		return
+	}
+
+	// Replace the cache atomically, a reader sees the old or new one.
+	tmp := path + ".tmp"
+	err = ioutil.WriteFile(tmp, b, 0644)
+	if err == nil {
+		err = os.Rename(tmp, path)
+	}
+	return
+}
+
+// readCachedLayout returns the layout of the machine's coreboot table from
+// the layout cache, reading the table and refreshing the cache if needed.
+func (nv *NVRAM) readCachedLayout() (layout *Layout, err error) {
+	fp, err := dmiFingerprint()
+	if err != nil {
+		nv.logf("nvram: Not using layout cache: %v", err)
+		return ReadLayoutFromCoreBootTable()
+	}
+
+	layout, err = readLayoutCache(nv.layoutCache, fp)
+	if err == nil {
+		return
+	}
+	if !os.IsNotExist(err) {
+		nv.logf("nvram: Refreshing layout cache: %v", err)
+	}
+
+	layout, err = ReadLayoutFromCoreBootTable()
+	if err != nil {
+		return
+	}
+	if werr := writeLayoutCache(nv.layoutCache, fp, layout); werr != nil {
+		nv.logf("nvram: Writing layout cache %s failed: %v", nv.layoutCache, werr)
+	}
+	return
+}
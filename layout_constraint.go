@@ -0,0 +1,110 @@
+// Copyright © 2019 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+package nvram
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// CMOSConstraint is a rule between two parameters: if parameter ifName
+// has value ifValue then parameter thenName must have value thenValue.
+// Values are enum texts, strings or hex numbers as accepted by strconv.
+type CMOSConstraint struct {
+	ifName    string
+	ifValue   string
+	thenName  string
+	thenValue string
+}
+
+func NewCMOSConstraint(ifName, ifValue, thenName, thenValue string) *CMOSConstraint {
+	return &CMOSConstraint{ifName: ifName, ifValue: ifValue,
+		thenName: thenName, thenValue: thenValue}
+}
+
+func parseCMOSConstraint(cond, req string) (c *CMOSConstraint, err error) {
+	// Both condition and requirement are in the form name=value
+	cs := strings.SplitN(cond, "=", 2)
+	rs := strings.SplitN(req, "=", 2)
+	if len(cs) != 2 || len(rs) != 2 || cs[0] == "" || rs[0] == "" {
+		err = fmt.Errorf("Constraint %s %s is not in the form name=value name=value", cond, req)
+		return
+	}
+	c = NewCMOSConstraint(cs[0], cs[1], rs[0], rs[1])
+	return
+}
+
+func (c CMOSConstraint) String() string {
+	return fmt.Sprintf("%s=%s %s=%s", c.ifName, c.ifValue, c.thenName, c.thenValue)
+}
+
+func (c CMOSConstraint) IfName() string {
+	return c.ifName
+}
+
+func (c CMOSConstraint) IfValue() string {
+	return c.ifValue
+}
+
+func (c CMOSConstraint) ThenName() string {
+	return c.thenName
+}
+
+func (c CMOSConstraint) ThenValue() string {
+	return c.thenValue
+}
+
+func (c *CMOSConstraint) involves(name string) bool {
+	return c.ifName == name || c.thenName == name
+}
+
+// ConstraintError is returned when a parameter value violates a layout
+// constraint.
+type ConstraintError struct {
+	Constraint CMOSConstraint
+	Value      interface{}
+}
+
+func (e *ConstraintError) Error() string {
+	return fmt.Sprintf("nvram: %s=%s requires %s=%s, found %v.",
+		e.Constraint.ifName, e.Constraint.ifValue,
+		e.Constraint.thenName, e.Constraint.thenValue, e.Value)
+}
+
+func constraintValueMatches(value interface{}, want string) bool {
+	switch v := value.(type) {
+	case string:
+		return v == want
+	case uint64:
+		n, err := strconv.ParseUint(want, 0, 64)
+		return err == nil && n == v
+	}
+	return false
+}
+
+func (l *Layout) AddCMOSConstraint(c *CMOSConstraint) (err error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	// Check that both parameters are defined.
+	for _, name := range []string{c.ifName, c.thenName} {
+		_, isEntry := l.entries[name]
+		_, isSubfield := l.subfields[name]
+		if !isEntry && !isSubfield {
+			err = fmt.Errorf("Constraint %s uses unknown parameter %s", *c, name)
+			return
+		}
+	}
+
+	l.constraints = append(l.constraints, c)
+	return
+}
+
+func (l *Layout) GetCMOSConstraints() []*CMOSConstraint {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return append([]*CMOSConstraint(nil), l.constraints...)
+}
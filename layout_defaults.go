@@ -0,0 +1,111 @@
+// Copyright © 2019 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+package nvram
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// DefaultImage returns a copy of the CMOS defaults image of the layout.
+func (l *Layout) DefaultImage() (image []byte, ok bool) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	if l.defaults == nil {
+		return nil, false
+	}
+	return append([]byte(nil), l.defaults...), true
+}
+
+// SetDefaultImage sets the CMOS defaults image of the layout and takes the
+// default of every entry without one from the image.
+func (l *Layout) SetDefaultImage(image []byte) (err error) {
+	if len(image) < int(cmosSize) {
+		return fmt.Errorf("nvram: CMOS defaults image smaller than %d bytes.", cmosSize)
+	}
+	image = append([]byte(nil), image[:cmosSize]...)
+
+	// Decode entry defaults from the image.
+	nv, err := openImage(l, image)
+	if err != nil {
+		return
+	}
+	defaults := make(map[string]interface{})
+	for _, e := range l.entryList() {
+		if e.config == CMOSEntryReserved || e.name == "check_sum" || e.meta.def != nil {
+			continue
+		}
+		value, err := nv.ReadCMOSParameter(e.name)
+		if err != nil {
+			continue
+		}
+
+		// Skip enum defaults that are not valid.
+		if s, ok := value.(string); ok && e.config == CMOSEntryEnum {
+			if _, ok := l.FindCMOSEnumValue(e.config_id, s); !ok {
+				continue
+			}
+		}
+		defaults[e.name] = value
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.defaults = image
+	for name, value := range defaults {
+		old := l.entries[name]
+		e := old.copy()
+		e.meta.def = value
+		l.replaceEntry(old, e)
+	}
+	return
+}
+
+// ResetToDefaults writes the default value of every parameter that has one
+// in one transaction. Defaults are stored values, so any transformer of the
+// parameter is bypassed. Unique parameters are kept unless the NVRAM was
+// configured WithUniqueRestore.
+func (nv *NVRAM) ResetToDefaults() (err error) {
+	var params []Parameter
+	for _, e := range nv.entryList() {
+		if value, ok := e.Default(); ok && nv.restorable(e.name) {
+			params = append(params, Parameter{Name: e.name, Value: value})
+		}
+	}
+	return nv.writeRawParameters(params)
+}
+
+// DriftFromDefaults returns the parameters whose current value differs from
+// their default, from the layout or its defaults image, in bit offset
+// order. Before is the default and After the current value. Parameters
+// without a default and unique parameters, which differ between machines by
+// design, are skipped.
+func (nv *NVRAM) DriftFromDefaults() (drift []ParamDiff, err error) {
+	for _, e := range nv.entryList() {
+		def, ok := e.Default()
+		if !ok || e.meta.unique {
+			continue
+		}
+		var value interface{}
+		value, err = nv.ReadCMOSParameter(e.name)
+		if err != nil {
+			return
+		}
+
+		// Compare in the form values are presented in.
+		if t, ok := nv.transformers[e.name]; ok {
+			def, err = t.readValue(nv, def)
+			if err != nil {
+				return
+			}
+		}
+		if !reflect.DeepEqual(value, def) {
+			drift = append(drift, ParamDiff{Name: e.name, Before: def, After: value})
+		}
+	}
+	return
+}
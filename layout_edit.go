@@ -0,0 +1,115 @@
+// Copyright © 2019 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+package nvram
+
+import (
+	"fmt"
+)
+
+// entryReferences returns an error if a subfield or constraint refers to the
+// named entry. The lock must be held.
+func (l *Layout) entryReferences(name string) error {
+	for _, s := range l.subfields {
+		if s.parent.name == name {
+			return fmt.Errorf("CMOS entry %s has subfield %s.", name, s.name)
+		}
+	}
+	for _, c := range l.constraints {
+		if c.involves(name) {
+			return fmt.Errorf("CMOS entry %s is used by constraint %s.", name, *c)
+		}
+	}
+	return nil
+}
+
+// RemoveCMOSEntry removes the named entry and any aliases of it. Entries
+// with subfields or used by constraints can not be removed.
+func (l *Layout) RemoveCMOSEntry(name string) (err error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	old, ok := l.entries[name]
+	if !ok {
+		err = &ParameterError{Name: name, Err: ErrParameterNotFound}
+		return
+	}
+	err = l.entryReferences(name)
+	if err != nil {
+		return
+	}
+
+	l.entrieslist = entryListWithout(l.entrieslist, old)
+	delete(l.entries, name)
+	for oldName, newName := range l.aliases {
+		if newName == name {
+			delete(l.aliases, oldName)
+		}
+	}
+	return
+}
+
+// ReplaceCMOSEntry replaces the entry with the same name as entry. The new
+// entry may move or change type. It must not overlap other entries and must
+// still hold any subfields of the old entry.
+func (l *Layout) ReplaceCMOSEntry(entry *CMOSEntry) (err error) {
+	// Verify CMOS Entry
+	err = verifyCMOSEntry(entry)
+	if err != nil {
+		return
+	}
+
+	// Keep a private copy of the entry.
+	entry = entry.copy()
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	old, ok := l.entries[entry.name]
+	if !ok {
+		err = &ParameterError{Name: entry.name, Err: ErrParameterNotFound}
+		return
+	}
+
+	// Check the new entry against all other entries.
+	for _, e := range l.entrieslist {
+		if e != old && entry.IsOverlap(e) {
+			err = fmt.Errorf("Entry %s overlaps %s", *entry, e)
+			return
+		}
+	}
+
+	// Subfields must still fit in a hex entry.
+	for _, s := range l.subfields {
+		if s.parent != old {
+			continue
+		}
+		if entry.config != CMOSEntryHex || s.bit+s.length > entry.length {
+			err = fmt.Errorf("CMOS subfield %s out of range of %s.", s.name, entry.name)
+			return
+		}
+	}
+
+	// Reinsert at the position of the new entry.
+	list := l.entrieslist
+	l.entrieslist = entryListWithout(list, old)
+	err = l.insertEntry(entry)
+	if err != nil {
+		l.entrieslist = list
+		return
+	}
+	l.reparentSubfields(old, entry)
+	return
+}
+
+// entryListWithout returns a new list without entry.
+func entryListWithout(list []*CMOSEntry, entry *CMOSEntry) []*CMOSEntry {
+	n := make([]*CMOSEntry, 0, len(list))
+	for _, e := range list {
+		if e != entry {
+			n = append(n, e)
+		}
+	}
+	return n
+}
@@ -0,0 +1,134 @@
+// Copyright © 2019 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+package nvram
+
+import (
+	"fmt"
+)
+
+// enumEntries returns the enum entries using enum id. The lock must be
+// held.
+func (l *Layout) enumEntries(id uint) (entries []*CMOSEntry) {
+	for _, e := range l.entrieslist {
+		if e.config == CMOSEntryEnum && e.config_id == id {
+			entries = append(entries, e)
+		}
+	}
+	return
+}
+
+// enumTextReferences returns an error if a constraint uses text as the value
+// of an entry using enum id. The lock must be held.
+func (l *Layout) enumTextReferences(id uint, text string) error {
+	for _, e := range l.enumEntries(id) {
+		for _, c := range l.constraints {
+			if (c.ifName == e.name && c.ifValue == text) ||
+				(c.thenName == e.name && c.thenValue == text) {
+				return fmt.Errorf("Enum %s of id %d is used by constraint %s.", text, id, *c)
+			}
+		}
+	}
+	return nil
+}
+
+// RemoveCMOSEnumItem removes an enum item and its translations. The last
+// item of an enum used by an entry and items used by constraints can not be
+// removed.
+func (l *Layout) RemoveCMOSEnumItem(id uint, value uint) (err error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	enum, ok := l.enums[id]
+	if !ok {
+		return fmt.Errorf("Enum %d not found for id %d", value, id)
+	}
+	text, ok := enum.itos[value]
+	if !ok {
+		return fmt.Errorf("Enum %d not found for id %d", value, id)
+	}
+	if entries := l.enumEntries(id); len(enum.itos) == 1 && len(entries) > 0 {
+		return fmt.Errorf("Enum id %d is used by CMOS entry %s.", id, entries[0].name)
+	}
+	err = l.enumTextReferences(id, text)
+	if err != nil {
+		return
+	}
+
+	delete(enum.itos, value)
+	delete(enum.stoi, text)
+	if len(enum.itos) == 0 {
+		delete(l.enums, id)
+	}
+	for _, ids := range l.translations {
+		delete(ids[id], value)
+	}
+	return
+}
+
+// RenameCMOSEnumItem changes the text of an enum item. The new text must be
+// unique within the enum and the old text must not be used by constraints.
+func (l *Layout) RenameCMOSEnumItem(id uint, value uint, text string) (err error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	enum, ok := l.enums[id]
+	if !ok {
+		return fmt.Errorf("Enum %d not found for id %d", value, id)
+	}
+	old, ok := enum.itos[value]
+	if !ok {
+		return fmt.Errorf("Enum %d not found for id %d", value, id)
+	}
+	if old == text {
+		return
+	}
+	if _, ok := enum.stoi[text]; ok {
+		return fmt.Errorf("Enum %s already exists for id %d", text, id)
+	}
+	err = l.enumTextReferences(id, old)
+	if err != nil {
+		return
+	}
+
+	delete(enum.stoi, old)
+	enum.stoi[text] = value
+	enum.itos[value] = text
+	return
+}
+
+// ReassignCMOSEnumId moves the items and translations of enum oldId to the
+// unused id newId and updates the entries using the enum.
+func (l *Layout) ReassignCMOSEnumId(oldId, newId uint) (err error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	enum, ok := l.enums[oldId]
+	if !ok {
+		return fmt.Errorf("Enum id %d not found", oldId)
+	}
+	if oldId == newId {
+		return
+	}
+	if _, ok := l.enums[newId]; ok {
+		return fmt.Errorf("Enum id %d already exists", newId)
+	}
+
+	// Update entries using the enum.
+	for _, old := range l.enumEntries(oldId) {
+		e := old.copy()
+		e.config_id = newId
+		l.replaceEntry(old, e)
+	}
+
+	delete(l.enums, oldId)
+	l.enums[newId] = enum
+	for _, ids := range l.translations {
+		if values, ok := ids[oldId]; ok {
+			delete(ids, oldId)
+			ids[newId] = values
+		}
+	}
+	return
+}
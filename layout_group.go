@@ -0,0 +1,117 @@
+// Copyright © 2019 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+package nvram
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// GroupPolicy restricts writes to the parameters of a group.
+type GroupPolicy int
+
+const (
+	// GroupWritable is the default policy.
+	GroupWritable GroupPolicy = iota
+	// GroupReadOnly rejects writes with ErrGroupReadOnly.
+	GroupReadOnly
+	// GroupRequiresReboot treats every entry of the group as requiring
+	// a reboot when its value changes.
+	GroupRequiresReboot
+)
+
+// Group returns the group of the entry, set by the group metadata or the
+// part of the name before the first dot, e.g. "power" for "power.on_ac".
+// Entries without a group return "".
+func (e CMOSEntry) Group() string {
+	if e.meta.group != "" {
+		return e.meta.group
+	}
+	return nameGroup(e.name)
+}
+
+// SetGroup sets the group of the entry, overriding its name prefix.
+func (e *CMOSEntry) SetGroup(group string) {
+	e.meta.group = group
+}
+
+func nameGroup(name string) string {
+	if i := strings.Index(name, "."); i > 0 {
+		return name[:i]
+	}
+	return ""
+}
+
+// parameterGroup returns the group of a parameter. Subfields are in the
+// group of their parent entry, virtual parameters are grouped by name.
+func (l *Layout) parameterGroup(name string) string {
+	if s, ok := l.FindCMOSSubfield(name); ok {
+		name = s.parent.name
+	}
+	if e, ok := l.entry(name); ok {
+		return e.Group()
+	}
+	return nameGroup(name)
+}
+
+// ListGroups returns the sorted names of all groups of the layout.
+func (l *Layout) ListGroups() (groups []string) {
+	seen := make(map[string]bool)
+	for _, e := range l.entryList() {
+		if g := e.Group(); g != "" && !seen[g] {
+			seen[g] = true
+			groups = append(groups, g)
+		}
+	}
+	sort.Strings(groups)
+	return
+}
+
+// GroupParameters returns the names of the parameters of a group in
+// ParameterNames order.
+func (l *Layout) GroupParameters(group string) (names []string) {
+	for _, name := range l.parameterNames() {
+		if l.parameterGroup(name) == group {
+			names = append(names, name)
+		}
+	}
+	return
+}
+
+// WithGroupPolicy sets the write policy of the parameters of a group.
+func WithGroupPolicy(group string, policy GroupPolicy) Option {
+	return func(nv *NVRAM) {
+		if nv.groupPolicies == nil {
+			nv.groupPolicies = make(map[string]GroupPolicy)
+		}
+		nv.groupPolicies[group] = policy
+	}
+}
+
+func (nv *NVRAM) groupPolicy(e *CMOSEntry) GroupPolicy {
+	if nv.groupPolicies == nil {
+		return GroupWritable
+	}
+	return nv.groupPolicies[e.Group()]
+}
+
+// ImportGroup applies a settings file, as written by ExportParameters with
+// ExportOptions.Group, to the parameters of one group. Nothing is written if
+// a setting is not in the group.
+func (nv *NVRAM) ImportGroup(r io.Reader, group string) (changed []string, err error) {
+	settings, err := ReadSettings(r)
+	if err != nil {
+		return
+	}
+	for _, s := range settings {
+		if g := nv.parameterGroup(nv.resolveName(s.Name)); g != group {
+			err = fmt.Errorf("CMOS parameter %s is not in group %s", s.Name, group)
+			return
+		}
+	}
+	return nv.ApplySettings(settings)
+}
@@ -0,0 +1,206 @@
+// Copyright © 2019 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+package nvram
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// inferMaxValues is the number of distinct values listed in the
+// description of an inferred entry.
+const inferMaxValues = 4
+
+// inferMinText is the minimum number of bytes of an inferred string entry.
+const inferMinText = 3
+
+// inferredField is a run of bits that changed between dumps.
+type inferredField struct {
+	bit, length uint
+	config      CMOSEntryConfig
+}
+
+// InferLayout compares CMOS dumps taken across reboots and configuration
+// changes and returns a draft text layout for them. This is experimental.
+//
+// Bits that differ between dumps are grouped into entries: runs of
+// changing bits within a byte, merged over byte boundaries when whole
+// bytes change, become hex entries and byte runs holding printable text in
+// every dump become string entries. A 16 bit word holding the sum of a byte
+// range in every dump is proposed as the checksum. Bits that never change
+// are left out, so the draft only shows where settings probably are. Each
+// entry's description lists the values seen.
+func InferLayout(dumps [][]byte) (draft []byte, err error) {
+	if len(dumps) < 2 {
+		return nil, fmt.Errorf("nvram: At least two CMOS dumps are needed to infer a layout.")
+	}
+	for i, d := range dumps {
+		if len(d) < int(cmosSize) {
+			return nil, fmt.Errorf("nvram: CMOS dump %d is smaller than %d bytes.", i, cmosSize)
+		}
+	}
+
+	// Find the checksum first, its bytes change with every setting.
+	sum, hasSum := inferChecksum(dumps)
+
+	// Mark the bits that change between dumps.
+	var changed [cmosSize * 8]bool
+	for off := cmosRTCAreaSize; off < cmosSize; off++ {
+		if hasSum && (off == sum.index || off == sum.index+1) {
+			continue
+		}
+		var diff byte
+		for _, d := range dumps[1:] {
+			diff |= d[off] ^ dumps[0][off]
+		}
+		for bit := uint(0); bit < 8; bit++ {
+			changed[off*8+bit] = diff&(1<<bit) != 0
+		}
+	}
+
+	// Take out text first, its bytes change in only some bits.
+	fields := inferText(dumps, changed[:])
+	for _, f := range fields {
+		for bit := f.bit; bit < f.bit+f.length; bit++ {
+			changed[bit] = false
+		}
+	}
+
+	// Split the remaining runs too wide for a hex entry.
+	for _, f := range inferFields(changed[:]) {
+		f.config = CMOSEntryHex
+		for f.length > 64 {
+			fields = append(fields, inferredField{bit: f.bit, length: 64, config: f.config})
+			f.bit += 64
+			f.length -= 64
+		}
+		fields = append(fields, f)
+	}
+
+	sort.Slice(fields, func(i, j int) bool {
+		return fields[i].bit < fields[j].bit
+	})
+
+	// Write the draft in the layout text format.
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "# Draft layout inferred from %d CMOS dumps.\n\n", len(dumps))
+	fmt.Fprintf(&b, "entries\n\n")
+	fmt.Fprintf(&b, "#start-bit length  config config-ID    name\n")
+	fmt.Fprintf(&b, "%-10d %-7d %-6c %-8d %s\n", 0, 8*cmosRTCAreaSize, CMOSEntryReserved, 0,
+		"rtc")
+	for _, f := range fields {
+		fmt.Fprintf(&b, "%-10d %-7d %-6c %-8d unknown_%d_%d # %s\n", f.bit, f.length, f.config,
+			0, f.bit/8, f.bit%8, inferValues(dumps, f))
+	}
+	if hasSum {
+		fmt.Fprintf(&b, "%-10d %-7d %-6c %-8d %s\n", sum.index*8, 16, CMOSEntryHex, 0,
+			"check_sum")
+		fmt.Fprintf(&b, "\nchecksums\n\n")
+		fmt.Fprintf(&b, "checksum %d %d %d\n", sum.start*8, sum.end*8+7, sum.index*8)
+	}
+	return b.Bytes(), nil
+}
+
+// inferFields groups changed bits into fields. A field ends at a byte
+// boundary unless it started on one and the whole next byte changed too.
+func inferFields(changed []bool) (fields []inferredField) {
+	wholeByte := func(bit uint) bool {
+		for i := bit; i < bit+8; i++ {
+			if !changed[i] {
+				return false
+			}
+		}
+		return true
+	}
+
+	for bit := uint(0); bit < uint(len(changed)); bit++ {
+		if !changed[bit] {
+			continue
+		}
+		f := inferredField{bit: bit, length: 1}
+		for next := bit + 1; next < uint(len(changed)) && changed[next]; next++ {
+			if next%8 == 0 && (f.bit%8 != 0 || !wholeByte(next)) {
+				break
+			}
+			f.length++
+		}
+		fields = append(fields, f)
+		bit += f.length - 1
+	}
+	return
+}
+
+// inferText finds runs of at least inferMinText changed bytes holding
+// printable text or zero padding in every dump.
+func inferText(dumps [][]byte, changed []bool) (fields []inferredField) {
+	text := func(off uint) bool {
+		diff := false
+		for _, bit := range changed[off*8 : off*8+8] {
+			diff = diff || bit
+		}
+		for _, d := range dumps {
+			if c := d[off]; c != 0 && (c < ' ' || c > '~') {
+				return false
+			}
+		}
+		return diff
+	}
+
+	for off := cmosRTCAreaSize; off < cmosSize; off++ {
+		n := uint(0)
+		for off+n < cmosSize && text(off+n) {
+			n++
+		}
+		if n >= inferMinText {
+			fields = append(fields, inferredField{bit: off * 8, length: n * 8,
+				config: CMOSEntryString})
+		}
+		off += n
+	}
+	return
+}
+
+// inferValues describes the values of a field seen in the dumps.
+func inferValues(dumps [][]byte, f inferredField) string {
+	var values []string
+	seen := make(map[string]bool)
+	for _, d := range dumps {
+		var s string
+		if f.config == CMOSEntryString {
+			s = fmt.Sprintf("%q", strings.TrimRight(string(d[f.bit/8:(f.bit+f.length)/8]), "\x00"))
+		} else {
+			var v uint64
+			for i := uint(0); i < f.length; i++ {
+				bit := f.bit + i
+				if d[bit/8]&(1<<(bit%8)) != 0 {
+					v |= 1 << i
+				}
+			}
+			s = fmt.Sprintf("0x%X", v)
+		}
+		if !seen[s] {
+			seen[s] = true
+			values = append(values, s)
+		}
+	}
+
+	desc := fmt.Sprintf("%d values seen: ", len(values))
+	if len(values) > inferMaxValues {
+		return desc + strings.Join(values[:inferMaxValues], " ") + " ..."
+	}
+	return desc + strings.Join(values, " ")
+}
+
+// inferChecksum returns the narrowest checksum candidate of the dumps.
+func inferChecksum(dumps [][]byte) (sum CMOSChecksum, ok bool) {
+	for _, c := range checksumCandidates(dumps) {
+		if !ok || c.end-c.start < sum.end-sum.start {
+			sum, ok = c, true
+		}
+	}
+	return
+}
@@ -0,0 +1,93 @@
+// Copyright © 2019 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+package nvram
+
+import (
+	"sort"
+)
+
+// CMOSEntryFilter selects entries returned by Layout.Entries. It is called
+// with a copy of each entry.
+type CMOSEntryFilter func(e *CMOSEntry) bool
+
+// EntryConfigFilter selects entries with one of the given config types.
+func EntryConfigFilter(configs ...CMOSEntryConfig) CMOSEntryFilter {
+	return func(e *CMOSEntry) bool {
+		for _, config := range configs {
+			if e.config == config {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// EntryBitRangeFilter selects entries that lie within the bit range start
+// to end inclusive.
+func EntryBitRangeFilter(start, end uint) CMOSEntryFilter {
+	return func(e *CMOSEntry) bool {
+		return e.bit >= start && e.bit+e.length-1 <= end
+	}
+}
+
+// Entries returns a traversal of copies of the entries in layout order that
+// match all filters. The traversal calls yield for each entry until yield
+// returns false. It has the form of an iter.Seq, so with Go 1.23 the
+// entries can be ranged over directly.
+//
+//	for e := range l.Entries(EntryConfigFilter(CMOSEntryEnum)) {
+//		fmt.Println(e.Name())
+//	}
+//
+// Older Go versions pass a callback instead.
+//
+//	l.Entries()(func(e *CMOSEntry) bool {
+//		fmt.Println(e.Name())
+//		return true
+//	})
+func (l *Layout) Entries(filters ...CMOSEntryFilter) func(yield func(*CMOSEntry) bool) {
+	return func(yield func(*CMOSEntry) bool) {
+	next:
+		for _, e := range l.entryList() {
+			// Filters get the copy yielded, so they cannot change the layout.
+			c := e.copy()
+			for _, filter := range filters {
+				if !filter(c) {
+					continue next
+				}
+			}
+			if !yield(c) {
+				return
+			}
+		}
+	}
+}
+
+// Enums returns a traversal of the enum items sorted by id and value in the
+// same form as Entries. If ids are given only items of those enums are
+// returned.
+func (l *Layout) Enums(ids ...uint) func(yield func(CMOSEnumItem) bool) {
+	return func(yield func(CMOSEnumItem) bool) {
+		sorted := append([]uint(nil), ids...)
+		if len(sorted) == 0 {
+			l.mu.RLock()
+			for id := range l.enums {
+				sorted = append(sorted, id)
+			}
+			l.mu.RUnlock()
+		}
+		sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+		// Items of each enum are collected so yield runs without the lock.
+		for _, id := range sorted {
+			items, _ := l.GetCMOSEnumItemsById(id)
+			for _, item := range items {
+				if !yield(item) {
+					return
+				}
+			}
+		}
+	}
+}
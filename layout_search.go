@@ -0,0 +1,54 @@
+// Copyright © 2019 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+package nvram
+
+// FindEntriesByConfigID returns copies of the enum entries referencing enum
+// id, in layout order.
+func (l *Layout) FindEntriesByConfigID(id uint) (entries []*CMOSEntry) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	for _, e := range l.enumEntries(id) {
+		entries = append(entries, e.copy())
+	}
+	return
+}
+
+// FindEntriesUsingConfigID returns copies of the enum entries using enum id.
+//
+// Deprecated: Use FindEntriesByConfigID.
+func (l *Layout) FindEntriesUsingConfigID(id uint) []*CMOSEntry {
+	return l.FindEntriesByConfigID(id)
+}
+
+// FindEntriesByEnumText returns copies of the enum entries whose enum has an
+// item with text, in layout order.
+func (l *Layout) FindEntriesByEnumText(text string) (entries []*CMOSEntry) {
+	return l.findEntriesByEnum(func(id uint) bool {
+		_, ok := l.findCMOSEnumValue(id, text)
+		return ok
+	})
+}
+
+// FindEntriesByEnumValue returns copies of the enum entries whose enum has
+// an item with value, in layout order.
+func (l *Layout) FindEntriesByEnumValue(value uint) (entries []*CMOSEntry) {
+	return l.findEntriesByEnum(func(id uint) bool {
+		_, ok := l.findCMOSEnumText(id, value)
+		return ok
+	})
+}
+
+func (l *Layout) findEntriesByEnum(match func(id uint) bool) (entries []*CMOSEntry) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	for _, e := range l.entrieslist {
+		if e.config == CMOSEntryEnum && match(e.config_id) {
+			entries = append(entries, e.copy())
+		}
+	}
+	return
+}
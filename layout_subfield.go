@@ -0,0 +1,108 @@
+// Copyright © 2019 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+package nvram
+
+import (
+	"fmt"
+	"sort"
+)
+
+type CMOSSubfield struct {
+	name   string
+	parent *CMOSEntry
+	bit    uint
+	length uint
+}
+
+func (s CMOSSubfield) String() string {
+	return fmt.Sprintf("%s %d %d %s", s.parent.name, s.bit, s.length, s.name)
+}
+
+func (s CMOSSubfield) Name() string {
+	return s.name
+}
+
+func (s CMOSSubfield) Parent() string {
+	return s.parent.name
+}
+
+func (s CMOSSubfield) Bit() uint {
+	return s.bit
+}
+
+func (s CMOSSubfield) Length() uint {
+	return s.length
+}
+
+// DefineSubfield defines a named view of width bits starting at bitOffset
+// within the hex CMOS entry parent. Subfields are read and written like
+// normal hex parameters.
+//
+//	nv.DefineSubfield("misc_flags.wol_enable", "misc_flags", 3, 1)
+func (l *Layout) DefineSubfield(name string, parent string, bitOffset, width uint) (err error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	// Subfield names must be unique.
+	if _, ok := l.entries[name]; ok {
+		err = fmt.Errorf("CMOS parameter %s already exists.", name)
+		return
+	}
+	if _, ok := l.subfields[name]; ok {
+		err = fmt.Errorf("CMOS subfield %s already exists.", name)
+		return
+	}
+
+	// Find parent entry
+	e, ok := l.entries[parent]
+	if !ok {
+		err = &ParameterError{Name: parent, Err: ErrParameterNotFound}
+		return
+	}
+
+	// Subfields can only be defined on hex entries.
+	if e.config != CMOSEntryHex {
+		err = fmt.Errorf("CMOS parameter %s is not a hex parameter.", parent)
+		return
+	}
+
+	// Check subfield lies within the parent.
+	if width == 0 || bitOffset+width > e.length {
+		err = fmt.Errorf("CMOS subfield %s out of range of %s.", name, parent)
+		return
+	}
+
+	l.subfields[name] = &CMOSSubfield{name: name, parent: e, bit: bitOffset, length: width}
+	return
+}
+
+// FindCMOSSubfield returns a copy of the named subfield.
+func (l *Layout) FindCMOSSubfield(name string) (s *CMOSSubfield, ok bool) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	s, ok = l.subfields[name]
+	if ok {
+		c := *s
+		s = &c
+	}
+	return
+}
+
+// GetCMOSSubfieldsList returns copies of all subfields sorted by name.
+func (l *Layout) GetCMOSSubfieldsList() (list []*CMOSSubfield) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	// Create list of subfields sorted by name.
+	for _, s := range l.subfields {
+		c := *s
+		list = append(list, &c)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].name < list[j].name
+	})
+	return
+}
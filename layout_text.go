@@ -3,29 +3,72 @@ package nvram
 import (
 	"bufio"
 	"fmt"
+	"io"
 	"os"
 	"strings"
 )
 
 func ReadLayoutFromTextFile(filename string) (layout *Layout, err error) {
-	var file *os.File
-
 	// Create new empty layout
 	layout = NewLayout()
+	err = readLayoutTextFile(layout, filename, false)
+	return
+}
 
+// ReadLayoutFromFiles reads a base layout followed by overlays merged on
+// top of it in order. The base layout is a text file or a binary CMOS
+// option table ending in .bin, overlays are text files. Overlay entries
+// and enum items replace those of the same name or value, their checksum
+// replaces the base checksum, and everything else is added, so an overlay
+// may also describe or add metadata to base entries.
+func ReadLayoutFromFiles(base string, overlays ...string) (layout *Layout, err error) {
+	if strings.HasSuffix(base, ".bin") {
+		layout, err = ReadLayoutFromCMOSTableBinary(base)
+	} else {
+		layout, err = ReadLayoutFromTextFile(base)
+	}
+	if err != nil {
+		return
+	}
+	for _, filename := range overlays {
+		err = readLayoutTextFile(layout, filename, true)
+		if err != nil {
+			return nil, fmt.Errorf("%v in layout overlay %s", err, filename)
+		}
+	}
+	return
+}
+
+func readLayoutTextFile(layout *Layout, filename string, overlay bool) (err error) {
 	// Open layout text file
-	file, err = os.Open(filename)
+	file, err := os.Open(filename)
 	if err != nil {
 		return
 	}
 	defer file.Close()
+	return readLayoutText(layout, file, overlay)
+}
+
+// splitLayoutComment splits a trailing comment off a layout line. A comment
+// starts at a # beginning a field, a # within a field is part of it.
+func splitLayoutComment(line string) (text, comment string) {
+	for i := 1; i < len(line); i++ {
+		if line[i] == '#' && (line[i-1] == ' ' || line[i-1] == '\t') {
+			return strings.TrimSpace(line[:i]), strings.TrimSpace(line[i+1:])
+		}
+	}
+	return line, ""
+}
 
+// readLayoutText parses layout text into layout. Entries and enum items of
+// an overlay replace existing ones of the same name or value.
+func readLayoutText(layout *Layout, r io.Reader, overlay bool) (err error) {
 	// Start parsing comment region
 	var mode int = 0
 
 	var linenum uint = 0
 
-	scanner := bufio.NewScanner(file)
+	scanner := bufio.NewScanner(r)
 	for scanner.Scan() {
 		// Get line and ignore blanks and comments
 		line := strings.TrimSpace(scanner.Text())
@@ -37,14 +80,37 @@ func ReadLayoutFromTextFile(filename string) (layout *Layout, err error) {
 			continue
 		}
 
+		// Split off any trailing comment as a description. The text of
+		// descriptions and translations runs to the end of the line.
+		var description string
+		if mode != 5 && mode != 7 {
+			line, description = splitLayoutComment(line)
+		}
+
 		// Break line into files
 		fields := strings.Fields(line)
 		if len(fields) == 0 {
 			continue
 		}
 
+		// A version directive can appear anywhere in the file.
+		if fields[0] == "version" {
+			if len(fields) != 2 {
+				err = fmt.Errorf("Unexpected data in version on line %d", linenum)
+				return
+			}
+			var n int
+			n, err = fmt.Sscanf(fields[1], "%d", &layout.version)
+			if err != nil || n != 1 {
+				err = fmt.Errorf("Unexpected data in version on line %d", linenum)
+				return
+			}
+			continue
+		}
+
 		// A single filed indicates a new region
-		// Change mode to parsing entries, enumerations or checksums
+		// Change mode to parsing entries, enumerations, checksums,
+		// constraints, descriptions, metadata or translations
 		if len(fields) == 1 {
 			switch fields[0] {
 			case "entries":
@@ -53,6 +119,14 @@ func ReadLayoutFromTextFile(filename string) (layout *Layout, err error) {
 				mode = 2
 			case "checksums":
 				mode = 3
+			case "constraints":
+				mode = 4
+			case "descriptions":
+				mode = 5
+			case "metadata":
+				mode = 6
+			case "translations":
+				mode = 7
 			default:
 				err = fmt.Errorf("Unexpected section header on line %d", linenum)
 				return
@@ -77,9 +151,14 @@ func ReadLayoutFromTextFile(filename string) (layout *Layout, err error) {
 				err = fmt.Errorf("Unexpected data in entries on line %d", linenum)
 				return
 			}
+			entry.description = description
 
-			// Add entry to layout
-			err = layout.AddCMOSEntry(&entry)
+			// Add entry to layout, an overlay replaces existing entries.
+			if _, ok := layout.entries[entry.name]; ok && overlay {
+				err = layout.ReplaceCMOSEntry(&entry)
+			} else {
+				err = layout.AddCMOSEntry(&entry)
+			}
 			if err != nil {
 				return
 			}
@@ -102,10 +181,19 @@ func ReadLayoutFromTextFile(filename string) (layout *Layout, err error) {
 			}
 
 			// Check if enumeration value already exists for an id.
+			// An overlay renames it.
 			_, ok := layout.FindCMOSEnumText(item.id, item.value)
+			if ok && overlay {
+				err = layout.RenameCMOSEnumItem(item.id, item.value, item.text)
+				if err != nil {
+					err = fmt.Errorf("%v on line %d", err, linenum)
+					return
+				}
+				continue
+			}
 			if ok {
-				fmt.Errorf("Enum %d already exists for id %d on line %d",
-					item.id, item.value, linenum)
+				err = fmt.Errorf("Enum %d already exists for id %d on line %d",
+					item.value, item.id, linenum)
 				return
 			}
 
@@ -142,6 +230,72 @@ func ReadLayoutFromTextFile(filename string) (layout *Layout, err error) {
 				return
 			}
 
+		case 4:
+			// Constraints have 2 fields
+			if len(fields) != 2 {
+				err = fmt.Errorf("Unexpected data in constraints on line %d", linenum)
+				return
+			}
+
+			// Parse name=value condition and requirement
+			var c *CMOSConstraint
+			c, err = parseCMOSConstraint(fields[0], fields[1])
+			if err != nil {
+				err = fmt.Errorf("%v on line %d", err, linenum)
+				return
+			}
+
+			// Add constraint to layout
+			err = layout.AddCMOSConstraint(c)
+			if err != nil {
+				return
+			}
+
+		case 5:
+			// Descriptions have a name followed by text
+			entry, ok := layout.entries[fields[0]]
+			if !ok {
+				err = fmt.Errorf("Unknown entry %s in descriptions on line %d", fields[0], linenum)
+				return
+			}
+			entry.description = strings.TrimSpace(strings.TrimPrefix(line, fields[0]))
+
+		case 6:
+			// Metadata has a name followed by keys and values
+			entry, ok := layout.entries[fields[0]]
+			if !ok {
+				err = fmt.Errorf("Unknown entry %s in metadata on line %d", fields[0], linenum)
+				return
+			}
+			err = layout.parseCMOSEntryMeta(entry, fields[1:])
+			if err != nil {
+				err = fmt.Errorf("%v for %s in metadata on line %d", err, fields[0], linenum)
+				return
+			}
+
+		case 7:
+			// Translations have locale, id, value and display text
+			if len(fields) < 4 {
+				err = fmt.Errorf("Unexpected data in translations on line %d", linenum)
+				return
+			}
+
+			var id, value uint
+			var n int
+			n, err = fmt.Sscanf(fields[1]+" "+fields[2], "%d %d", &id, &value)
+			if err != nil || n != 2 {
+				err = fmt.Errorf("Unexpected data in translations on line %d", linenum)
+				return
+			}
+
+			// Add translation to layout
+			err = layout.AddCMOSEnumTranslation(fields[0], id, value,
+				strings.Join(fields[3:], " "))
+			if err != nil {
+				err = fmt.Errorf("%v on line %d", err, linenum)
+				return
+			}
+
 		default:
 			err = fmt.Errorf("Unexpected data on line %d", linenum)
 			return
@@ -0,0 +1,69 @@
+// Copyright © 2019 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+package nvram
+
+import (
+	"strings"
+	"testing"
+)
+
+// commentTestLayout has # within fields and text as well as trailing
+// descriptions.
+const commentTestLayout = `entries
+
+#start-bit length  config config-ID    name
+0          384     r      0        reserved_memory
+384        8       e      1        console # Console	port
+392        8       e      1        debug_port
+400        8       h      0        boot#count	# Boots since reset
+1008       16      h      0        check_sum
+
+enumerations
+
+1          0       Port#0
+1          1       Port#1 # Second port
+
+checksums
+
+checksum 384 1007 1008
+
+descriptions
+
+debug_port Debug port, e.g. Port #1
+
+translations
+
+de 1 1 Anschluss #1
+`
+
+func TestLayoutTextComments(t *testing.T) {
+	l := NewLayout()
+	if err := readLayoutText(l, strings.NewReader(commentTestLayout), false); err != nil {
+		t.Fatal(err)
+	}
+
+	for name, want := range map[string]string{
+		"console":    "Console\tport",
+		"debug_port": "Debug port, e.g. Port #1",
+		"boot#count": "Boots since reset",
+	} {
+		e, ok := l.FindCMOSEntry(name)
+		if !ok {
+			t.Errorf("no entry %s", name)
+			continue
+		}
+		if e.Description() != want {
+			t.Errorf("%s: description is %q, want %q", name, e.Description(), want)
+		}
+	}
+	for value, want := range []string{"Port#0", "Port#1"} {
+		if text, ok := l.FindCMOSEnumText(1, uint(value)); !ok || text != want {
+			t.Errorf("enum %d is %q, %v, want %q", value, text, ok, want)
+		}
+	}
+	if text, ok := l.FindCMOSEnumDisplayText("de", 1, 1); !ok || text != "Anschluss #1" {
+		t.Errorf("translation is %q, %v, want %q", text, ok, "Anschluss #1")
+	}
+}
@@ -0,0 +1,87 @@
+// Copyright © 2019 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+package nvram
+
+import (
+	"fmt"
+	"sort"
+)
+
+// AddCMOSEnumTranslation adds a display text for an enum item in a locale.
+// Translations are only used for display. Parameters are always written
+// with the canonical enum text.
+func (l *Layout) AddCMOSEnumTranslation(locale string, id uint, value uint, text string) (err error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	// Translated item must exist.
+	if _, ok := l.findCMOSEnumText(id, value); !ok {
+		err = fmt.Errorf("Enum %d not found for id %d", value, id)
+		return
+	}
+
+	// Create maps for locale and id as needed.
+	ids, ok := l.translations[locale]
+	if !ok {
+		ids = make(map[uint]map[uint]string)
+		l.translations[locale] = ids
+	}
+	values, ok := ids[id]
+	if !ok {
+		values = make(map[uint]string)
+		ids[id] = values
+	}
+
+	values[value] = text
+	return
+}
+
+// FindCMOSEnumDisplayText returns the display text of an enum item in a
+// locale.
+func (l *Layout) FindCMOSEnumDisplayText(locale string, id uint, value uint) (text string, ok bool) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	text, ok = l.translations[locale][id][value]
+	return
+}
+
+// GetCMOSEnumLocales returns the sorted list of locales with translations.
+func (l *Layout) GetCMOSEnumLocales() (locales []string) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	for locale := range l.translations {
+		locales = append(locales, locale)
+	}
+	sort.Strings(locales)
+	return
+}
+
+// DisplayText returns the text shown for a parameter value in a locale.
+// Enum values with a translation use the translated text, all other values
+// are formatted with FormatParameter.
+func (nv *NVRAM) DisplayText(locale string, name string, value interface{}) string {
+	name = nv.resolveName(name)
+	if text, ok := nv.enumDisplayText(locale, name, value); ok {
+		return text
+	}
+	return nv.FormatParameter(name, value)
+}
+
+func (nv *NVRAM) enumDisplayText(locale string, name string, value interface{}) (text string, ok bool) {
+	e, ok := nv.FindCMOSEntry(name)
+	if !ok || e.config != CMOSEntryEnum {
+		return "", false
+	}
+	s, ok := value.(string)
+	if !ok {
+		return
+	}
+	n, ok := nv.FindCMOSEnumValue(e.config_id, s)
+	if !ok {
+		return
+	}
+	return nv.FindCMOSEnumDisplayText(locale, e.config_id, n)
+}
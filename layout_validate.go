@@ -0,0 +1,84 @@
+// Copyright © 2019 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+package nvram
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Validate cross-checks the layout and returns every problem found. It
+// checks that enum entries have an enum table, that entries do not overlap
+// each other, the RTC or the checksum, that the RTC area is reserved and
+// that the check_sum entry matches the checksum location.
+func (l *Layout) Validate() (findings []error) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	// Sort a copy of the entries by bit.
+	entries := append([]*CMOSEntry(nil), l.entrieslist...)
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].bit < entries[j].bit
+	})
+
+	rtcBits := 8 * cmosRTCAreaSize
+	sumStart := 8 * l.cmosChecksum.start
+	sumLength := 8 * (l.cmosChecksum.end - l.cmosChecksum.start + 1)
+	sumIndex := 8 * l.cmosChecksum.index
+
+	var covered uint
+	for i, e := range entries {
+		// Enum entries need an enum table.
+		if e.config == CMOSEntryEnum {
+			if _, ok := l.enums[e.config_id]; !ok {
+				findings = append(findings, fmt.Errorf("CMOS entry %s uses undefined enum id %d.", e.name, e.config_id))
+			}
+		}
+
+		// Entries must not overlap the following entries.
+		for _, next := range entries[i+1:] {
+			if next.bit >= e.bit+e.length {
+				break
+			}
+			findings = append(findings, fmt.Errorf("Entry %s overlaps %s", *e, *next))
+		}
+
+		if e.config == CMOSEntryReserved {
+			// Track reserved coverage of the RTC area from bit 0.
+			if e.bit <= covered && e.bit+e.length > covered {
+				covered = e.bit + e.length
+			}
+			continue
+		}
+
+		// Only reserved entries may be in the RTC area.
+		if e.bit < rtcBits {
+			findings = append(findings, fmt.Errorf("CMOS entry %s overlaps RTC.", e.name))
+		}
+
+		// The check_sum entry must be the checksum location.
+		if e.name == "check_sum" {
+			if e.bit != sumIndex || e.length != 16 {
+				findings = append(findings, fmt.Errorf("CMOS entry check_sum %s does not match checksum location %d.", *e, sumIndex))
+			}
+			continue
+		}
+
+		// Other entries must be inside or outside the summed area and not
+		// on the checksum.
+		if checkAreaOverLap(e.bit, e.length, sumStart, sumLength) &&
+			(e.bit < sumStart || e.bit+e.length > sumStart+sumLength) {
+			findings = append(findings, fmt.Errorf("CMOS entry %s is partly in the checksum area.", e.name))
+		}
+		if checkAreaOverLap(e.bit, e.length, sumIndex, 16) {
+			findings = append(findings, fmt.Errorf("CMOS entry %s overlaps the checksum.", e.name))
+		}
+	}
+
+	if covered < rtcBits {
+		findings = append(findings, fmt.Errorf("RTC area is not covered by reserved entries."))
+	}
+	return
+}
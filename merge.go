@@ -0,0 +1,71 @@
+// Copyright © 2019 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+package nvram
+
+import (
+	"reflect"
+	"sort"
+)
+
+// MergeConflict is a setting changed differently in ours and theirs. Values
+// of settings missing from a side are nil.
+type MergeConflict struct {
+	Name   string
+	Base   interface{}
+	Ours   interface{}
+	Theirs interface{}
+}
+
+type mergeValue struct {
+	value interface{}
+	ok    bool
+}
+
+func (v mergeValue) equal(w mergeValue) bool {
+	return v.ok == w.ok && reflect.DeepEqual(v.value, w.value)
+}
+
+// MergeSettings merges the changes from base to theirs, e.g. an updated
+// golden template, into ours, e.g. the settings of a machine with local
+// overrides. Settings changed only on one side take that side's value,
+// including removal. Settings changed differently on both sides keep our
+// value and are returned as conflicts sorted by name.
+func MergeSettings(base, ours, theirs map[string]interface{}) (merged map[string]interface{}, conflicts []MergeConflict) {
+	merged = make(map[string]interface{})
+
+	names := make(map[string]bool)
+	for _, m := range []map[string]interface{}{base, ours, theirs} {
+		for name := range m {
+			names[name] = true
+		}
+	}
+
+	for name := range names {
+		var b, o, t mergeValue
+		b.value, b.ok = base[name]
+		o.value, o.ok = ours[name]
+		t.value, t.ok = theirs[name]
+
+		// Pick the side that changed, ours if both changed.
+		result := o
+		switch {
+		case o.equal(t), t.equal(b):
+		case o.equal(b):
+			result = t
+		default:
+			conflicts = append(conflicts, MergeConflict{Name: name,
+				Base: b.value, Ours: o.value, Theirs: t.value})
+		}
+
+		if result.ok {
+			merged[name] = result.value
+		}
+	}
+
+	sort.Slice(conflicts, func(i, j int) bool {
+		return conflicts[i].Name < conflicts[j].Name
+	})
+	return
+}
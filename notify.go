@@ -0,0 +1,158 @@
+// Copyright © 2019 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+package nvram
+
+import (
+	"bytes"
+	"fmt"
+	"log/syslog"
+	"net"
+	"strings"
+	"sync"
+	"time"
+)
+
+// JournalSocket is the systemd journal's native protocol socket.
+const JournalSocket = "/run/systemd/journal/socket"
+
+// WriteFailure is a CMOS write that failed after all retries.
+type WriteFailure struct {
+	Time      time.Time
+	Backend   string
+	Parameter string
+	Err       error
+	// Suppressed is the number of failures of the parameter not
+	// notified since the last notification.
+	Suppressed int
+}
+
+// Fields returns the failure as structured log fields.
+func (f WriteFailure) Fields() map[string]string {
+	return map[string]string{
+		"NVRAM_BACKEND":    f.Backend,
+		"NVRAM_PARAMETER":  f.Parameter,
+		"NVRAM_ERROR":      f.Err.Error(),
+		"NVRAM_SUPPRESSED": fmt.Sprint(f.Suppressed),
+	}
+}
+
+func (f WriteFailure) String() string {
+	s := fmt.Sprintf("nvram: Writing CMOS parameter %s on %s failed: %v", f.Parameter, f.Backend, f.Err)
+	if f.Suppressed > 0 {
+		s += fmt.Sprintf(" (%d more failures suppressed)", f.Suppressed)
+	}
+	return s
+}
+
+// Notifier reports write failures, e.g. to syslog or the journal.
+type Notifier interface {
+	Notify(f WriteFailure) error
+}
+
+// WithWriteFailureNotifier reports CMOS writes that fail after all retries
+// to n, at most once per parameter in every interval. Failures in between
+// are counted in the next notification. Writes rejected by the write gate,
+// group policies or rate limits are not reported.
+func WithWriteFailureNotifier(n Notifier, interval time.Duration) Option {
+	return func(nv *NVRAM) {
+		nv.notifier = &failureNotifier{notifier: n, interval: interval,
+			last: make(map[string]time.Time), suppressed: make(map[string]int)}
+	}
+}
+
+type failureNotifier struct {
+	notifier   Notifier
+	interval   time.Duration
+	mu         sync.Mutex
+	last       map[string]time.Time
+	suppressed map[string]int
+}
+
+// writeFailed counts and reports a failed write of the named parameter.
+func (nv *NVRAM) writeFailed(name string, err error) {
+	nv.countFailure(func(s *FailureStats, r *FailureRun) {
+		s.WriteErrors++
+		r.WriteErrors++
+	})
+
+	n := nv.notifier
+	if n == nil {
+		return
+	}
+
+	now := time.Now()
+	n.mu.Lock()
+	if last, ok := n.last[name]; ok && now.Sub(last) < n.interval {
+		n.suppressed[name]++
+		n.mu.Unlock()
+		return
+	}
+	f := WriteFailure{Time: now, Backend: nv.CMOS.backend, Parameter: name, Err: err,
+		Suppressed: n.suppressed[name]}
+	n.last[name] = now
+	delete(n.suppressed, name)
+	n.mu.Unlock()
+
+	if nerr := n.notifier.Notify(f); nerr != nil {
+		nv.logf("nvram: Write failure notification failed: %v", nerr)
+	}
+}
+
+type syslogNotifier struct {
+	w *syslog.Writer
+}
+
+// NewSyslogNotifier returns a Notifier logging failures at error priority
+// to the local syslog with tag, appending the fields as key=value pairs.
+func NewSyslogNotifier(tag string) (n Notifier, err error) {
+	w, err := syslog.New(syslog.LOG_ERR|syslog.LOG_DAEMON, tag)
+	if err != nil {
+		return
+	}
+	n = &syslogNotifier{w: w}
+	return
+}
+
+func (n *syslogNotifier) Notify(f WriteFailure) error {
+	msg := f.String()
+	fields := f.Fields()
+	for _, key := range []string{"NVRAM_BACKEND", "NVRAM_PARAMETER", "NVRAM_SUPPRESSED"} {
+		msg += fmt.Sprintf(" %s=%q", key, fields[key])
+	}
+	return n.w.Err(msg)
+}
+
+type journalNotifier struct {
+	identifier string
+	socket     string
+}
+
+// NewJournalNotifier returns a Notifier sending failures with their
+// structured fields to the systemd journal at error priority.
+func NewJournalNotifier(identifier string) Notifier {
+	return &journalNotifier{identifier: identifier, socket: JournalSocket}
+}
+
+func (n *journalNotifier) Notify(f WriteFailure) (err error) {
+	var b bytes.Buffer
+	field := func(key, value string) {
+		// Keep values on one line for the simple field format.
+		fmt.Fprintf(&b, "%s=%s\n", key, strings.Replace(value, "\n", " ", -1))
+	}
+	field("MESSAGE", f.String())
+	field("PRIORITY", "3")
+	field("SYSLOG_IDENTIFIER", n.identifier)
+	for key, value := range f.Fields() {
+		field(key, value)
+	}
+
+	conn, err := net.Dial("unixgram", n.socket)
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+	_, err = conn.Write(b.Bytes())
+	return
+}
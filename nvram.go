@@ -4,29 +4,99 @@
 
 // Package nvram provides access to the coreboot CMOS option table and allows
 // reading, writing and listing CMOS parameters.
+//
+// New programs should use github.com/platinasystems/nvram/v2, which wraps
+// this package with context support and uniform errors.
 package nvram
 
 import (
+	"bytes"
 	"encoding/binary"
 	"errors"
 	"fmt"
 	"github.com/platinasystems/nvram/debug"
+	"log"
+	"os"
+	"sort"
 	"strings"
-	"sync/atomic"
 )
 
 var (
 	ErrNVRAMAccessInUse = errors.New("nvram: NVRAM is busy.")
 	ErrInvalidCMOSIndex = errors.New("nvram: Invalid CMOS index!")
 	ErrCMOSNotOpen = errors.New("nvram: CMOS Not Opened")
-)
+	ErrCMOSReadOnly = errors.New("nvram: CMOS is read only.")
+
+	ErrRTCNotSupported    = errors.New("nvram: RTC registers not supported by CMOS backend.")
+	ErrInvalidRTCRegister = errors.New("nvram: Invalid RTC register!")
+	ErrCMOSBatteryFailed  = errors.New("nvram: CMOS battery failed, CMOS settings were lost.")
 
-var lockstate uint32
+	ErrParameterNotFound = errors.New("nvram: CMOS parameter not found.")
+	ErrChecksumParameter = errors.New("nvram: CMOS checksum parameter requires the checksum API.")
+	ErrEmptyLayout       = errors.New("nvram: CMOS layout has no entries.")
+
+	ErrWriteQueueClosed = errors.New("nvram: Write queue closed.")
+	ErrGroupReadOnly    = errors.New("nvram: CMOS parameter group is read only.")
+	ErrWriteGated       = errors.New("nvram: CMOS writes blocked by write gate.")
+
+	ErrTableWritesDisabled = errors.New("nvram: Coreboot table writes are not enabled.")
+	ErrCoreBootTableClosed = errors.New("nvram: Coreboot table not open.")
+
+	ErrNotAuthorized = errors.New("nvram: Caller not authorized.")
+)
 
 type NVRAM struct {
 	CMOS
 	*Layout
 	modified bool
+	virtuals map[string]*virtualParameter
+	logger   *log.Logger
+
+	transformers map[string]*valueTransformer
+
+	eventHandler func(Event)
+	guard        checksumGuard
+	helperSocket string
+
+	rebootFlag     string
+	rebootRequired bool
+
+	noChecksumUpdate bool
+	rateLimits       map[string]*rateLimiter
+	snapshotDir      string
+	lockKey          string
+	lockDir          string
+	lockFile         *os.File
+	uniqueRestore    bool
+	restoreJournal   string
+	groupPolicies    map[string]GroupPolicy
+	writeGate        func() error
+	tracer           Tracer
+	notifier         *failureNotifier
+	failures         *failureStats
+	mappedAddr       uint64
+	mappedSize       uint
+	accessor         CMOSer
+	alphabetical     bool
+	showReserved     bool
+	layoutFiles      []string
+	scratch          string
+	maxRebootCount   uint64
+	bootHealth       *bootHealth
+	tableWrites      bool
+	provenance       Provenance
+	layoutCache      string
+	allowEmpty       bool
+	hooks            writeHooks
+	presets          map[string]Preset
+	presetDir        string
+	deprecations     deprecationLog
+}
+
+// Parameter is a named parameter value as returned by ReadAllParameters.
+type Parameter struct {
+	Name  string
+	Value interface{}
 }
 
 // Open opens NVRAM access.
@@ -40,18 +110,21 @@ type NVRAM struct {
 // If the layout file name ends in .bin the coreboot CMOS layout will be
 // read in binary form.
 //		nv.Open("cmos_layout.bin")
-// If the first argument is empty the machine's coreboot table will be used.
+// If the first argument is empty the machine's coreboot table will be used,
+// or the merged layout files of an NVRAM configured WithLayouts.
 //		nv.Open("")
 // Calling Open with a second CMOS memory file name will use the mem mapped
 // CMOS file instead of the NVRAM hardware.
 //		nv.Open("", "cmos.bin")
+// Without a CMOS memory file name, an NVRAM configured WithAccessor uses
+// that accessor, one configured WithMappedRTCRAM accesses memory mapped RTC
+// RAM and one configured WithHelperSocket accesses the hardware through the
+// privileged nvram-helper process.
+//
+// Each CMOS backend can be opened by one NVRAM at a time, a process may hold
+// handles on the hardware and on memory files at once.
 
 func (nv *NVRAM) Open(args ...string) (err error) {
-	// Only one NVRAM access is allowed at a time.
-	if !atomic.CompareAndSwapUint32(&lockstate, 0, 1) {
-		return ErrNVRAMAccessInUse
-	}
-
 	// Get file name arguments if they exist.
 	var layoutFileName, cmosMemFileName string
 	if len(args) > 0 {
@@ -60,10 +133,48 @@ func (nv *NVRAM) Open(args ...string) (err error) {
 	if len(args) > 1 {
 		cmosMemFileName = args[1]
 	}
+	layoutName := layoutFileName
+	if layoutName == "" && len(nv.layoutFiles) > 0 {
+		layoutName = strings.Join(nv.layoutFiles, ",")
+	}
+
+	span := nv.startSpan("nvram.Open", SpanAttribute{"nvram.layout", layoutName},
+		SpanAttribute{"nvram.cmos", cmosMemFileName})
+	defer nv.endSpan(span, &err)
+
+	// Only one NVRAM access is allowed at a time per CMOS backend.
+	key := backendLockKey(cmosMemFileName)
+	if cmosMemFileName == "" && nv.accessor != nil {
+		key = fmt.Sprintf("accessor:%p", nv.accessor)
+	}
+	if !lockBackend(key) {
+		return ErrNVRAMAccessInUse
+	}
+	nv.lockKey = key
+
+	// Release the backend again if open fails.
+	defer func() {
+		if err != nil {
+			nv.CMOS.Close()
+			nv.unlockBackendFile()
+			unlockBackend(key)
+			nv.lockKey = ""
+		}
+	}()
+
+	// Hold the backend against other processes too.
+	err = nv.lockBackendFile(key)
+	if err != nil {
+		return
+	}
 
 	// Load layout file from machine's Coreboot table, coreboot table binary,
-	// or CMOS layout text file.
-	if layoutFileName == "" {
+	// CMOS layout text file or merged layout files.
+	if layoutFileName == "" && len(nv.layoutFiles) > 0 {
+		nv.Layout, err = ReadLayoutFromFiles(nv.layoutFiles[0], nv.layoutFiles[1:]...)
+	} else if layoutFileName == "" && nv.layoutCache != "" {
+		nv.Layout, err = nv.readCachedLayout()
+	} else if layoutFileName == "" {
 		nv.Layout, err = ReadLayoutFromCoreBootTable()
 	} else {
 		if strings.HasSuffix(layoutFileName, ".bin") {
@@ -77,12 +188,23 @@ func (nv *NVRAM) Open(args ...string) (err error) {
 	if err != nil {
 		return
 	}
+	if nv.Layout.IsEmpty() && !nv.allowEmpty {
+		err = ErrEmptyLayout
+		return
+	}
 
-	// Open CMOS NVRAM access with hardware access or using a binary file.
-	if cmosMemFileName == "" {
-		err = nv.CMOS.Open()
-	} else {
+	// Open CMOS NVRAM access with hardware access, memory mapped RTC RAM,
+	// through the privileged helper or using a binary file.
+	if cmosMemFileName != "" {
 		err = nv.CMOS.OpenMem(cmosMemFileName)
+	} else if nv.accessor != nil {
+		nv.CMOS.OpenAccessor(nv.accessor)
+	} else if nv.mappedSize != 0 {
+		err = nv.CMOS.OpenMapped(nv.mappedAddr, nv.mappedSize)
+	} else if nv.helperSocket != "" {
+		err = nv.CMOS.OpenHelper(nv.helperSocket)
+	} else {
+		err = nv.CMOS.Open()
 	}
 
 	// If we don't have any CMOS access return error
@@ -93,30 +215,94 @@ func (nv *NVRAM) Open(args ...string) (err error) {
 	// Initialize CMOS with layout checksum
 	nv.CMOS.checksum = *nv.Layout.cmosChecksum
 
+	// Without entries nothing tells where the checksummed data is.
+	if nv.Layout.IsEmpty() {
+		nv.CMOS.checksum.kind = ChecksumNone
+	}
+
+	// Finish any interrupted restore.
+	err = nv.recoverRestore()
+	if err != nil {
+		return
+	}
+
+	// Start counting failures of this run
+	err = nv.startFailureStats()
+	if err != nil {
+		return
+	}
+
+	// Warn if the CMOS lost power or other software uses it.
+	if ok, err := nv.BatteryGood(); err == nil && !ok {
+		nv.logf("%v", ErrCMOSBatteryFailed)
+		nv.countFailure(func(s *FailureStats, r *FailureRun) {
+			s.BatteryFailures++
+			r.BatteryFailed = true
+		})
+	}
+	nv.warnConflicts()
+
+	// Record the outcome of this boot if enabled
+	err = nv.recordBootHealth()
+	if err != nil {
+		return
+	}
+
+	// Start watching for CMOS corruption if enabled
+	err = nv.startChecksumGuard()
 	return
 }
 
 // Close closes the currently opened CMOS layout and NVRAM access.
 // If the CMOS data has been modified a new checksum is calculed and written
-// before closing the CMOS access.
+// before closing the CMOS access, unless the NVRAM was configured
+// WithNoChecksumUpdate.
 func (nv *NVRAM) Close() (err error) {
+	span := nv.startSpan("nvram.Close")
+	defer nv.endSpan(span, &err)
+	nv.beginWrites()
+	defer nv.endWrites(&err)
+
+	if nv.lockKey != "" {
+		defer unlockBackend(nv.lockKey)
+		defer nv.unlockBackendFile()
+		nv.lockKey = ""
+	}
 
-	defer atomic.StoreUint32(&lockstate, 0)
+	nv.stopChecksumGuard()
 
-	if nv.modified {
-		debug.Trace(debug.LevelMSG1, "NVRAM Modified computing checksum.\n")
-		sum, err := nv.CMOS.ComputeChecksum()
-		if err == nil {
-			debug.Trace(debug.LevelMSG1, "NVRAM Modified writing checksum %02X.\n", sum)
-			err = nv.CMOS.WriteChecksum(sum)
+	if nv.modified && !nv.noChecksumUpdate && nv.CMOS.checksum.kind != ChecksumNone {
+		if gerr := nv.checkWriteGate(); gerr != nil {
+			// A blocked gate only skips the checksum, a failed
+			// pre-write hook fails Close.
+			if _, ok := gerr.(*WriteGatedError); !ok {
+				err = gerr
+			}
+			nv.logf("nvram: Checksum not updated: %v", gerr)
+		} else {
+			debug.Trace(debug.LevelMSG1, "NVRAM Modified computing checksum.\n")
+			sum, err := nv.CMOS.ComputeChecksum()
 			if err == nil {
-				debug.Trace(debug.LevelMSG1, "NVRAM cheksum updated.\n")
-				nv.modified = false
+				debug.Trace(debug.LevelMSG1, "NVRAM Modified writing checksum %02X.\n", sum)
+				err = nv.CMOS.WriteChecksum(sum)
+				if err == nil {
+					debug.Trace(debug.LevelMSG1, "NVRAM cheksum updated.\n")
+					nv.modified = false
+				} else {
+					nv.writeFailed("check_sum", err)
+				}
 			}
 		}
 	}
 
-	return nv.CMOS.Close()
+	// Save failure statistics last so the checksum write is counted.
+	if serr := nv.saveFailureStats(); serr != nil {
+		nv.logf("nvram: Saving failure statistics failed: %v", serr)
+	}
+	if cerr := nv.CMOS.Close(); err == nil {
+		err = cerr
+	}
+	return
 }
 
 // ValidateChechsum will calculate the CMOS checksum on the checksum area
@@ -124,6 +310,12 @@ func (nv *NVRAM) Close() (err error) {
 // If there is an error it will be a warning and contain the computed and
 // stored checksum value.
 func (nv *NVRAM) ValidateChecksum() (err error) {
+	if nv.CMOS.checksum.kind == ChecksumNone {
+		return
+	}
+	span := nv.startSpan("nvram.ValidateChecksum")
+	defer nv.endSpan(span, &err)
+
 	computed_sum, err := nv.CMOS.ComputeChecksum()
 	if err != nil {
 		return
@@ -136,16 +328,63 @@ func (nv *NVRAM) ValidateChecksum() (err error) {
 	if computed_sum != stored_sum {
 		err = fmt.Errorf("Warning: coreboot CMOS checksum is bad.\nComputed checksum: 0x%X. Stored checksum: 0x%X",
 			computed_sum, stored_sum)
+		nv.emit(Event{Kind: EventCorruption, Computed: computed_sum, Stored: stored_sum,
+			Suspects: nv.suspects(nil), Err: err})
+	}
+	return
+}
+
+// RepairChecksum writes the computed checksum if the stored checksum is bad
+// and reports an EventRepaired.
+func (nv *NVRAM) RepairChecksum() (err error) {
+	if nv.CMOS.checksum.kind == ChecksumNone {
+		return
+	}
+	span := nv.startSpan("nvram.RepairChecksum")
+	defer nv.endSpan(span, &err)
+	nv.beginWrites()
+	defer nv.endWrites(&err)
+
+	computed_sum, err := nv.CMOS.ComputeChecksum()
+	if err != nil {
+		return
+	}
+	stored_sum, err := nv.CMOS.ReadChecksum()
+	if err != nil || computed_sum == stored_sum {
+		return
+	}
+
+	err = nv.checkWriteGate()
+	if err != nil {
+		return
+	}
+	err = nv.CMOS.WriteChecksum(computed_sum)
+	if err != nil {
+		return
+	}
+	nv.emit(Event{Kind: EventRepaired, Computed: computed_sum, Stored: computed_sum,
+		Suspects: nv.suspects(nil)})
+	return
+}
+
+// ValidateAll validates the CMOS battery, the CMOS checksum and all layout
+// constraints and returns every problem found.
+func (nv *NVRAM) ValidateAll() (errs []error) {
+	if ok, err := nv.BatteryGood(); err == nil && !ok {
+		errs = append(errs, ErrCMOSBatteryFailed)
 	}
+	if err := nv.ValidateChecksum(); err != nil {
+		errs = append(errs, err)
+	}
+	errs = append(errs, nv.constraintViolations()...)
 	return
 }
 
 // NewParameterType will return an interface value for the CMOS parameter.
 // This will wither be a string or a uint64.
 func (nv *NVRAM) NewParameterType(name string) (value interface{}, err error) {
-	e, ok := nv.FindCMOSEntry(name)
-	if !ok {
-		err = fmt.Errorf("CMOS parameter %s not found.", name)
+	e, err := nv.findParameterEntry(name)
+	if err != nil {
 		return
 	}
 
@@ -167,78 +406,162 @@ func (nv *NVRAM) NewParameterType(name string) (value interface{}, err error) {
 
 // WriteCMOSParameter writes provided value to a named CMOS parameter.
 func (nv *NVRAM) WriteCMOSParameter(name string, value interface{}) (err error) {
-	e, ok := nv.FindCMOSEntry(name)
-	if !ok || name == "check_sum" {
-		err = fmt.Errorf("CMOS parameter %s not found.", name)
-		return
+	name = nv.resolveName(name)
+	span := nv.startSpan("nvram.Write", SpanAttribute{"nvram.parameter", name})
+	defer nv.endSpan(span, &err)
+	nv.beginWrites()
+	defer nv.endWrites(&err)
+
+	// Write virtual parameter if one is registered with this name.
+	if p, ok := nv.virtuals[name]; ok {
+		return p.writeValue(nv, value)
 	}
 
-	var v []byte
-
-	switch e.config {
-	case CMOSEntryString:
-		s, ok := value.(string)
-		if !ok {
-			err = fmt.Errorf("A string value is required.")
-		}
-		if e.length < uint(len(s)*8) {
-			err = fmt.Errorf("Can not write value %s to CMOS parameter %s that is only %d-bits wide.", s, name, e.length)
+	// Convert a presented value to the stored value.
+	if t, ok := nv.transformers[name]; ok {
+		value, err = t.writeValue(nv, value)
+		if err != nil {
 			return
 		}
-		// Copy string to byte array
-		v = make([]byte, (e.length+7)/8)
-		copy(v[:], []byte(s))
+	}
 
-	case CMOSEntryEnum:
-		s, ok := value.(string)
-		if !ok {
-			err = fmt.Errorf("A string value is required.")
-		}
-		n, ok := nv.FindCMOSEnumValue(e.config_id, s)
-		if !ok {
-			err = fmt.Errorf("Bad value for parameter %s", name)
-			return
-		}
-		// Check length
-		if e.length < 64 && (uint64(n) >= (uint64(1) << e.length)) {
-			err = fmt.Errorf("Enum value is too wide for parameter %s", name)
+	return nv.writeStoredParameter(name, value, span)
+}
+
+// writeRawParameter writes the stored value of a parameter, bypassing any
+// transformer. Internal read-modify-write code uses it together with
+// readRawParameter.
+func (nv *NVRAM) writeRawParameter(name string, value interface{}) (err error) {
+	name = nv.resolveName(name)
+	nv.beginWrites()
+	defer nv.endWrites(&err)
+
+	if p, ok := nv.virtuals[name]; ok {
+		return p.writeValue(nv, value)
+	}
+	return nv.writeStoredParameter(name, value, noSpan{})
+}
+
+func (nv *NVRAM) writeStoredParameter(name string, value interface{}, span Span) (err error) {
+	// Write subfield of a hex parameter if one is defined with this name.
+	if s, ok := nv.FindCMOSSubfield(name); ok {
+		err = nv.checkConstraints(name, value)
+		if err != nil {
 			return
 		}
-		// Copy uint64 to byte array
-		v = make([]byte, 8)
-		binary.LittleEndian.PutUint64(v, uint64(n))
+		return nv.writeSubfield(s, value)
+	}
 
-	case CMOSEntryHex:
-		n, ok := value.(uint64)
-		if !ok {
-			err = fmt.Errorf("A uint64 value is required.")
-		}
-		// Check length
-		if e.length < 64 && (n >= (uint64(1) << e.length)) {
-			err = fmt.Errorf("Can not write value 0x%X to CMOS parameter %s that is only %d-bits wide.", n, name, e.length)
+	e, err := nv.findParameterEntry(name)
+	if err != nil {
+		return
+	}
+
+	span.SetAttributes(entryBytes(e))
+	v, err := nv.encodeValue(e, value)
+	if err != nil {
+		return
+	}
+
+	// Check new value against layout constraints.
+	err = nv.checkConstraints(name, value)
+	if err != nil {
+		return
+	}
+
+	return nv.writeEntry(e, v)
+}
+
+// writeEntry writes an entry value subject to group policies and rate
+// limits and notes a required reboot if the value of an entry marked as
+// requiring one changed.
+func (nv *NVRAM) writeEntry(e *CMOSEntry, v []byte) (err error) {
+	err = nv.checkWriteGate()
+	if err != nil {
+		return
+	}
+
+	policy := nv.groupPolicy(e)
+	if policy == GroupReadOnly {
+		return &ParameterError{Name: e.name, Err: ErrGroupReadOnly}
+	}
+
+	// Count write against any rate limit of the entry.
+	err = nv.checkRateLimit(e.name)
+	if err != nil {
+		return
+	}
+
+	var old []byte
+	reboot := e.meta.reboot || policy == GroupRequiresReboot
+	if reboot && e.name != nv.rebootFlag {
+		old, err = nv.CMOS.ReadEntry(e)
+		if err != nil {
 			return
 		}
-
-		// Copy uint64 to byte array
-		v = make([]byte, 8)
-		binary.LittleEndian.PutUint64(v, n)
 	}
 
 	err = nv.CMOS.WriteEntry(e, v)
-	if err == nil {
-		nv.modified = true
+	if err != nil {
+		nv.writeFailed(e.name, err)
+		return
+	}
+	nv.modified = true
+
+	if old != nil && !bytes.Equal(old, v[:len(old)]) {
+		err = nv.SetRebootRequired()
 	}
 	return
 }
 
 // ReadCMOSParameter read the current value of a named CMOS parameter.
 func (nv *NVRAM) ReadCMOSParameter(name string) (value interface{}, err error) {
-	e, ok := nv.FindCMOSEntry(name)
-	if !ok || name == "check_sum" {
-		err = fmt.Errorf("CMOS parameter %s not found.", name)
+	name = nv.resolveName(name)
+	span := nv.startSpan("nvram.Read", SpanAttribute{"nvram.parameter", name})
+	defer nv.endSpan(span, &err)
+
+	// Read virtual parameter if one is registered with this name.
+	if p, ok := nv.virtuals[name]; ok {
+		return p.read(nv)
+	}
+
+	value, err = nv.readStoredParameter(name, span)
+	if err != nil {
+		return
+	}
+
+	// Present the value read through any transformer.
+	if t, ok := nv.transformers[name]; ok {
+		value, err = t.readValue(nv, value)
+	}
+	return
+}
+
+// readRawParameter reads the stored value of a parameter, bypassing any
+// transformer, so its type is the one given by the layout.
+func (nv *NVRAM) readRawParameter(name string) (value interface{}, err error) {
+	name = nv.resolveName(name)
+	if p, ok := nv.virtuals[name]; ok {
+		return p.read(nv)
+	}
+	return nv.readStoredParameter(name, noSpan{})
+}
+
+func (nv *NVRAM) readStoredParameter(name string, span Span) (value interface{}, err error) {
+	// Read subfield of a hex parameter if one is defined with this name.
+	if s, ok := nv.FindCMOSSubfield(name); ok {
+		return nv.readSubfield(s)
+	}
+
+	e, err := nv.findParameterEntry(name)
+	if err != nil {
 		return
 	}
 
+	span.SetAttributes(entryBytes(e))
+	if e.config == CMOSEntryReserved && nv.showReserved {
+		return nv.CMOS.readRawEntry(e)
+	}
 	v, err := nv.CMOS.ReadEntry(e)
 	if err != nil {
 		return
@@ -246,7 +569,8 @@ func (nv *NVRAM) ReadCMOSParameter(name string) (value interface{}, err error) {
 
 	switch e.config {
 	case CMOSEntryString:
-		value = string(v)
+		// Strings are NUL padded and only use whole bytes.
+		value = cString(v[:e.length/8])
 	case CMOSEntryEnum:
 		n := binary.LittleEndian.Uint64(v)
 		s, ok := nv.FindCMOSEnumText(e.config_id, uint(n))
@@ -262,3 +586,31 @@ func (nv *NVRAM) ReadCMOSParameter(name string) (value interface{}, err error) {
 
 	return
 }
+
+// ParameterNames returns the names of all readable parameters. CMOS
+// parameters are listed first sorted by bit offset, followed by subfields
+// and virtual parameters sorted by name. Reserved entries are only listed
+// WithReservedEntries. With WithAlphabeticalOrder all names are sorted by
+// name.
+func (nv *NVRAM) ParameterNames() (names []string) {
+	names = nv.listNames(nv.showReserved)
+	names = append(names, nv.virtualParameterNames()...)
+	if nv.alphabetical {
+		sort.Strings(names)
+	}
+	return
+}
+
+// ReadAllParameters reads the current value of every parameter returned by
+// ParameterNames, in the same order.
+func (nv *NVRAM) ReadAllParameters() (params []Parameter, err error) {
+	for _, name := range nv.ParameterNames() {
+		var value interface{}
+		value, err = nv.ReadCMOSParameter(name)
+		if err != nil {
+			return
+		}
+		params = append(params, Parameter{Name: name, Value: value})
+	}
+	return
+}
@@ -0,0 +1,63 @@
+// Copyright © 2019 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+package nvram
+
+import (
+	"fmt"
+	"strings"
+)
+
+// WriteCMOSParameters writes all parameters in order as one transaction. If
+// a write fails the parameters already written are restored to their
+// previous values and the error of the failed write is returned, together
+// with the errors of any parameters that could not be restored. With
+// WithSafeWriteOrder critical parameters are written last.
+func (nv *NVRAM) WriteCMOSParameters(params []Parameter) (err error) {
+	return nv.writeParameters(params, nv.WriteCMOSParameter)
+}
+
+// writeRawParameters writes the stored values of all parameters in order as
+// one transaction like WriteCMOSParameters, bypassing any transformer.
+func (nv *NVRAM) writeRawParameters(params []Parameter) (err error) {
+	return nv.writeParameters(params, nv.writeRawParameter)
+}
+
+func (nv *NVRAM) writeParameters(params []Parameter,
+	write func(name string, value interface{}) error) (err error) {
+	nv.beginWrites()
+	defer nv.endWrites(&err)
+	params = nv.orderParameters(params)
+
+	// Read previous stored values first so nothing is written unless all
+	// parameters exist.
+	old := make([]Parameter, len(params))
+	for i, p := range params {
+		old[i].Name = p.Name
+		old[i].Value, err = nv.readRawParameter(p.Name)
+		if err != nil {
+			return
+		}
+	}
+
+	for i, p := range params {
+		err = write(p.Name, p.Value)
+		if err == nil {
+			continue
+		}
+
+		// Roll back in reverse order
+		var failed []string
+		for j := i - 1; j >= 0; j-- {
+			if rerr := nv.writeRawParameter(old[j].Name, old[j].Value); rerr != nil {
+				failed = append(failed, fmt.Sprintf("%s: %v", old[j].Name, rerr))
+			}
+		}
+		if len(failed) > 0 {
+			err = fmt.Errorf("%w; rollback failed for %s", err, strings.Join(failed, "; "))
+		}
+		return
+	}
+	return
+}
@@ -0,0 +1,174 @@
+// Copyright © 2019 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+package nvram
+
+import (
+	"bytes"
+	"fmt"
+)
+
+// bitsEntry returns an entry for a region of CMOS bits, checked like the
+// layout's entries.
+func bitsEntry(bitOffset, length uint) (e *CMOSEntry, err error) {
+	if length == 0 {
+		return nil, fmt.Errorf("nvram: Can not access 0 CMOS bits.")
+	}
+	// String entries read and write any number of bytes.
+	e = &CMOSEntry{
+		bit:    bitOffset,
+		length: length,
+		config: CMOSEntryString,
+		name:   fmt.Sprintf("bits %d-%d", bitOffset, bitOffset+length-1),
+	}
+	err = verifyCMOSOp(e)
+	return
+}
+
+// ReadBits reads length CMOS bits starting at bitOffset, whether or not the
+// layout describes them, least significant byte first and with the first
+// bit in bit 0. The same rules apply as for entries: the bits must be
+// outside the RTC area and must not span bytes unless they start on a byte.
+func (nv *NVRAM) ReadBits(bitOffset, length uint) (v []byte, err error) {
+	span := nv.startSpan("nvram.ReadBits", SpanAttribute{"nvram.bit", bitOffset},
+		SpanAttribute{"nvram.length", length})
+	defer nv.endSpan(span, &err)
+
+	e, err := bitsEntry(bitOffset, length)
+	if err != nil {
+		return
+	}
+	return nv.CMOS.ReadEntry(e)
+}
+
+// WriteBits writes length CMOS bits starting at bitOffset from v, in the
+// form returned by ReadBits. Besides the rules of ReadBits, writes are
+// subject to the write gate, write hooks and group policies like parameter
+// writes, and must not touch reserved entries, the stored checksum or
+// parameters of read only groups. The parameters the bits overlap are
+// checked with their new values against constraints and rate limits, and
+// changing one that requires a reboot sets the reboot flag. Close updates
+// the checksum as after parameter writes.
+func (nv *NVRAM) WriteBits(bitOffset, length uint, v []byte) (err error) {
+	span := nv.startSpan("nvram.WriteBits", SpanAttribute{"nvram.bit", bitOffset},
+		SpanAttribute{"nvram.length", length})
+	defer nv.endSpan(span, &err)
+	nv.beginWrites()
+	defer nv.endWrites(&err)
+
+	e, err := bitsEntry(bitOffset, length)
+	if err != nil {
+		return
+	}
+
+	// The value must have exactly the bits written.
+	n := (length + 7) / 8
+	if uint(len(v)) != n {
+		return fmt.Errorf("nvram: Writing %d CMOS bits requires %d bytes, not %d.", length, n, len(v))
+	}
+	if r := length % 8; r != 0 && v[n-1]>>r != 0 {
+		return fmt.Errorf("nvram: Value 0x%02X of the last byte does not fit in %d bits.", v[n-1], r)
+	}
+
+	// Protect what the layout protects.
+	c := nv.CMOS.checksum
+	if c.kind != ChecksumNone && checkAreaOverLap(e.bit, e.length, c.index*8, 16) {
+		return fmt.Errorf("nvram: CMOS %s overlap the stored checksum: %w", e.name, ErrChecksumParameter)
+	}
+	var overlapped []*CMOSEntry
+	for _, le := range nv.entryList() {
+		if !e.IsOverlap(le) {
+			continue
+		}
+		if le.config == CMOSEntryReserved {
+			return fmt.Errorf("nvram: CMOS %s overlap reserved entry %s.", e.name, le.name)
+		}
+		if nv.groupPolicy(le) == GroupReadOnly {
+			return &ParameterError{Name: le.name, Err: ErrGroupReadOnly}
+		}
+		overlapped = append(overlapped, le)
+	}
+
+	reboot, err := nv.checkBitsEntries(e, v, overlapped)
+	if err != nil {
+		return
+	}
+
+	err = nv.writeEntry(e, v)
+	if err == nil && reboot {
+		err = nv.SetRebootRequired()
+	}
+	return
+}
+
+// checkBitsEntries checks the entries overlapped by a bits write like
+// writeEntry checks a parameter write, using their values after the write,
+// and reports if one requiring a reboot changes.
+func (nv *NVRAM) checkBitsEntries(e *CMOSEntry, v []byte, overlapped []*CMOSEntry) (reboot bool, err error) {
+	if len(overlapped) == 0 {
+		return
+	}
+
+	// Apply the write to a copy of the bytes of all entries involved.
+	image := make([]byte, cmosSize)
+	first, last := e.bit>>3, (e.bit+e.length-1)>>3
+	for _, le := range overlapped {
+		if le.bit>>3 < first {
+			first = le.bit >> 3
+		}
+		if end := (le.bit + le.length - 1) >> 3; end > last {
+			last = end
+		}
+	}
+	for i := first; i <= last; i++ {
+		image[i], err = nv.CMOS.ReadByte(i)
+		if err != nil {
+			return
+		}
+	}
+	after, err := openImage(nv.Layout, image)
+	if err != nil {
+		return
+	}
+	defer after.CMOS.Close()
+	err = after.CMOS.WriteEntry(e, v)
+	if err != nil {
+		return
+	}
+
+	for _, le := range overlapped {
+		var value interface{}
+		value, err = after.readRawParameter(le.name)
+		if err != nil {
+			return
+		}
+		err = nv.checkConstraints(le.name, value)
+		if err != nil {
+			return
+		}
+		err = nv.checkRateLimit(le.name)
+		if err != nil {
+			return
+		}
+
+		// Note a change of an entry requiring a reboot.
+		if le.name == nv.rebootFlag ||
+			!(le.meta.reboot || nv.groupPolicy(le) == GroupRequiresReboot) {
+			continue
+		}
+		var old, changed []byte
+		old, err = nv.CMOS.ReadEntry(le)
+		if err != nil {
+			return
+		}
+		changed, err = after.CMOS.ReadEntry(le)
+		if err != nil {
+			return
+		}
+		if !bytes.Equal(old, changed) {
+			reboot = true
+		}
+	}
+	return
+}
@@ -0,0 +1,165 @@
+// Copyright © 2019 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+package nvram
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"time"
+)
+
+// maxBootRecords is the number of boots kept in a boot health file.
+const maxBootRecords = 100
+
+// BootRecord is the outcome of one boot as left by the firmware in the
+// fallback boot entries. Attempts is the reboot_counter, the number of
+// normal image boots tried since the last successful one, and Fallback is
+// set if the fallback image booted.
+type BootRecord struct {
+	Time     time.Time `json:"time"`
+	Attempts uint64    `json:"attempts"`
+	Fallback bool      `json:"fallback,omitempty"`
+}
+
+// BootHealth holds the boot records persisted by WithBootHealth, oldest
+// first.
+type BootHealth struct {
+	Boots []BootRecord `json:"boots"`
+}
+
+// ReadBootHealth reads a boot health file. A missing file holds no boots.
+func ReadBootHealth(path string) (h BootHealth, err error) {
+	b, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return h, nil
+	}
+	if err != nil {
+		return
+	}
+	err = json.Unmarshal(b, &h)
+	return
+}
+
+// Fallbacks returns the number of recorded boots of the fallback image.
+func (h BootHealth) Fallbacks() (n int) {
+	for _, r := range h.Boots {
+		if r.Fallback {
+			n++
+		}
+	}
+	return
+}
+
+// WithBootHealth records the outcome of the current boot when the NVRAM is
+// opened, for services opening it once at start. The boot_option and
+// reboot_counter entries, and last_boot if the layout has it, are read, the
+// outcome is appended to the boot health file path and reboot_counter is
+// cleared so the firmware knows the boot succeeded. onFallback, if not nil,
+// is called when the firmware had fallen back to the fallback image. Going
+// back to the normal image by setting boot_option is left to the caller.
+func WithBootHealth(path string, onFallback func(BootRecord)) Option {
+	return func(nv *NVRAM) {
+		nv.bootHealth = &bootHealth{path: path, onFallback: onFallback}
+	}
+}
+
+type bootHealth struct {
+	path       string
+	onFallback func(BootRecord)
+	boot       BootRecord
+}
+
+// BootAttempts returns the number of normal image boots the firmware tried
+// for the current boot, as recorded by WithBootHealth. One is a clean boot.
+func (nv *NVRAM) BootAttempts() uint64 {
+	if nv.bootHealth == nil {
+		return 0
+	}
+	return nv.bootHealth.boot.Attempts
+}
+
+// BootRecord returns the outcome of the current boot recorded by
+// WithBootHealth.
+func (nv *NVRAM) BootRecord() (r BootRecord, ok bool) {
+	if nv.bootHealth == nil || nv.bootHealth.boot.Time.IsZero() {
+		return
+	}
+	return nv.bootHealth.boot, true
+}
+
+// recordBootHealth reads the outcome of the current boot, saves it and
+// clears the reboot counter.
+func (nv *NVRAM) recordBootHealth() (err error) {
+	b := nv.bootHealth
+	if b == nil {
+		return
+	}
+
+	// Read the fallback boot entries.
+	option, ok := nv.FindCMOSEntry("boot_option")
+	counter, cok := nv.FindCMOSEntry("reboot_counter")
+	if !ok || !cok {
+		return fmt.Errorf("nvram: Boot health requires the boot_option and reboot_counter entries.")
+	}
+	r := BootRecord{Time: time.Now()}
+	r.Attempts, err = nv.readRawValue(counter)
+	if err != nil {
+		return
+	}
+
+	// The firmware stores the image it booted in last_boot. Without it
+	// a cleared boot_option shows the fallback image booted.
+	booted := option
+	if last, ok := nv.FindCMOSEntry("last_boot"); ok {
+		booted = last
+	}
+	normal, err := nv.readRawValue(booted)
+	if err != nil {
+		return
+	}
+	r.Fallback = normal&1 == 0
+
+	// Append the boot to the boot health file.
+	h, err := ReadBootHealth(b.path)
+	if err != nil {
+		return
+	}
+	h.Boots = append(h.Boots, r)
+	if len(h.Boots) > maxBootRecords {
+		h.Boots = h.Boots[len(h.Boots)-maxBootRecords:]
+	}
+	data, err := json.Marshal(h)
+	if err != nil {
+		return
+	}
+	tmp := b.path + ".tmp"
+	err = ioutil.WriteFile(tmp, data, 0644)
+	if err != nil {
+		return
+	}
+	err = os.Rename(tmp, b.path)
+	if err != nil {
+		return
+	}
+	b.boot = r
+
+	// Tell the firmware the boot succeeded.
+	if r.Attempts != 0 {
+		err = nv.writeRawParameter(counter.name, uint64(0))
+		if err != nil {
+			return fmt.Errorf("nvram: Clearing %s failed: %w", counter.name, err)
+		}
+	}
+
+	if r.Fallback {
+		nv.logf("nvram: Firmware booted the fallback image, reboot_counter was %d.", r.Attempts)
+		if b.onFallback != nil {
+			b.onFallback(r)
+		}
+	}
+	return
+}
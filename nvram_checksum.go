@@ -0,0 +1,47 @@
+// Copyright © 2019 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+package nvram
+
+import (
+	"fmt"
+	"github.com/platinasystems/nvram/debug"
+)
+
+// WithNoChecksumUpdate stops Close from writing a new checksum after
+// parameters were modified. This leaves a bad checksum in CMOS, e.g. to test
+// the firmware's recovery from corrupt CMOS settings.
+func WithNoChecksumUpdate() Option {
+	return func(nv *NVRAM) {
+		nv.noChecksumUpdate = true
+	}
+}
+
+// InvalidateChecksum writes a checksum that does not match the checksum
+// area. Close does not fix the checksum unless parameters are written
+// afterwards.
+func (nv *NVRAM) InvalidateChecksum() (err error) {
+	if nv.CMOS.checksum.kind == ChecksumNone {
+		return fmt.Errorf("CMOS layout has no checksum.")
+	}
+
+	sum, err := nv.CMOS.ComputeChecksum()
+	if err != nil {
+		return
+	}
+
+	nv.beginWrites()
+	defer nv.endWrites(&err)
+	err = nv.checkWriteGate()
+	if err != nil {
+		return
+	}
+
+	debug.Trace(debug.LevelMSG1, "NVRAM invalidating checksum %02X.\n", sum)
+	err = nv.CMOS.WriteChecksum(^sum)
+	if err == nil {
+		nv.modified = false
+	}
+	return
+}
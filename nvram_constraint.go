@@ -0,0 +1,56 @@
+// Copyright © 2019 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+package nvram
+
+func (nv *NVRAM) checkConstraint(c *CMOSConstraint, name string, value interface{}) (err error) {
+	// Use the proposed value for the parameter being written and the
+	// current CMOS value for the other parameter.
+	read := func(n string) (interface{}, error) {
+		if n == name {
+			return value, nil
+		}
+		return nv.readRawParameter(n)
+	}
+
+	v, err := read(c.ifName)
+	if err != nil {
+		return
+	}
+	if !constraintValueMatches(v, c.ifValue) {
+		return
+	}
+
+	v, err = read(c.thenName)
+	if err != nil {
+		return
+	}
+	if !constraintValueMatches(v, c.thenValue) {
+		err = &ConstraintError{Constraint: *c, Value: v}
+	}
+	return
+}
+
+func (nv *NVRAM) checkConstraints(name string, value interface{}) (err error) {
+	// Check all constraints that involve the parameter being written.
+	for _, c := range nv.GetCMOSConstraints() {
+		if !c.involves(name) {
+			continue
+		}
+		err = nv.checkConstraint(c, name, value)
+		if err != nil {
+			return
+		}
+	}
+	return
+}
+
+func (nv *NVRAM) constraintViolations() (errs []error) {
+	for _, c := range nv.GetCMOSConstraints() {
+		if err := nv.checkConstraint(c, "", nil); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return
+}
@@ -0,0 +1,65 @@
+// Copyright © 2019 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+package nvram
+
+import (
+	"fmt"
+)
+
+// IncrementParameter adds delta to a hex CMOS parameter and returns the new
+// value. The parameter is treated as an unsigned counter as wide as its CMOS
+// field or subfield. If the result does not fit the field it either wraps
+// around modulo the field width or saturates at the field's maximum value.
+// The counter is the stored value, any transformer of the parameter is
+// bypassed.
+func (nv *NVRAM) IncrementParameter(name string, delta uint64, wrap bool) (value uint64, err error) {
+	name = nv.resolveName(name)
+
+	// Find counter width from the entry or subfield.
+	var length uint
+	if s, ok := nv.FindCMOSSubfield(name); ok {
+		length = s.length
+	} else {
+		var e *CMOSEntry
+		e, err = nv.findParameterEntry(name)
+		if err != nil {
+			return
+		}
+
+		// Counters are only supported for hex parameters.
+		if e.config != CMOSEntryHex {
+			err = fmt.Errorf("CMOS parameter %s is not a hex parameter.", name)
+			return
+		}
+		length = e.length
+	}
+
+	// Read current counter value.
+	v, err := nv.readRawParameter(name)
+	if err != nil {
+		return
+	}
+	current := v.(uint64)
+
+	// Find largest value the field can hold.
+	max := ^uint64(0)
+	if length < 64 {
+		max = (uint64(1) << length) - 1
+	}
+
+	// Check for overflow of the field.
+	if delta > max-current {
+		if wrap {
+			value = (current + delta) & max
+		} else {
+			value = max
+		}
+	} else {
+		value = current + delta
+	}
+
+	err = nv.writeRawParameter(name, value)
+	return
+}
@@ -0,0 +1,139 @@
+// Copyright © 2019 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+package nvram
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// DefaultConfigFile is the configuration file read by OpenDefault.
+const DefaultConfigFile = "/etc/nvram.conf"
+
+// Backends selected by the backend configuration key or NVRAM_BACKEND.
+const (
+	BackendHardware = "hardware"
+	BackendHelper   = "helper"
+	BackendFile     = "file"
+)
+
+// defaultConfig is the configuration of OpenDefault.
+type defaultConfig struct {
+	layout       string
+	cmosFile     string
+	backend      string
+	helperSocket string
+}
+
+// readDefaultConfig reads a configuration file of "key = value" lines.
+// A missing file is an empty configuration.
+func readDefaultConfig(filename string) (c defaultConfig, err error) {
+	f, err := os.Open(filename)
+	if os.IsNotExist(err) {
+		return c, nil
+	}
+	if err != nil {
+		return
+	}
+	defer f.Close()
+
+	settings, err := ReadSettings(f)
+	if err != nil {
+		return c, fmt.Errorf("nvram: %s: %v", filename, err)
+	}
+	for _, s := range settings {
+		switch s.Name {
+		case "layout":
+			c.layout = s.Value
+		case "cmos_file":
+			c.cmosFile = s.Value
+		case "backend":
+			c.backend = s.Value
+		case "helper_socket":
+			c.helperSocket = s.Value
+		default:
+			return c, fmt.Errorf("nvram: %s: Unknown key %s.", filename, s.Name)
+		}
+	}
+	return
+}
+
+// OpenDefault opens an NVRAM configured by the environment and the
+// configuration file, so tools built on the package agree on which layout
+// and CMOS to use.
+//
+// The configuration file is DefaultConfigFile, or the file named by
+// NVRAM_CONFIG, and holds "key = value" lines with the keys layout,
+// cmos_file, backend and helper_socket. The environment variables
+// NVRAM_LAYOUT, NVRAM_CMOS_FILE and NVRAM_BACKEND override the keys of the
+// same name. A layout of several comma separated files is merged as with
+// WithLayouts, no layout uses the coreboot table. The backend is
+// BackendHardware, BackendHelper, which uses helper_socket or
+// DefaultHelperSocket, or BackendFile, which uses cmos_file. Giving a CMOS
+// file selects BackendFile.
+//
+// opts are applied after the configuration and may override it.
+func OpenDefault(opts ...Option) (nv *NVRAM, err error) {
+	filename := DefaultConfigFile
+	if s := os.Getenv("NVRAM_CONFIG"); s != "" {
+		filename = s
+	}
+	c, err := readDefaultConfig(filename)
+	if err != nil {
+		return
+	}
+	if s := os.Getenv("NVRAM_LAYOUT"); s != "" {
+		c.layout = s
+	}
+	if s := os.Getenv("NVRAM_CMOS_FILE"); s != "" {
+		c.cmosFile = s
+	}
+	if s := os.Getenv("NVRAM_BACKEND"); s != "" {
+		c.backend = s
+	}
+
+	// Select the CMOS backend.
+	var config []Option
+	switch c.backend {
+	case "":
+		if c.cmosFile != "" {
+			c.backend = BackendFile
+		}
+	case BackendHardware:
+		c.cmosFile = ""
+	case BackendHelper:
+		c.cmosFile = ""
+		if c.helperSocket == "" {
+			c.helperSocket = DefaultHelperSocket
+		}
+		config = append(config, WithHelperSocket(c.helperSocket))
+	case BackendFile:
+		if c.cmosFile == "" {
+			return nil, fmt.Errorf("nvram: Backend %s requires a CMOS file.", c.backend)
+		}
+	default:
+		return nil, fmt.Errorf("nvram: Unknown backend %s.", c.backend)
+	}
+
+	// Several layouts are merged.
+	var args []string
+	if layouts := strings.Split(c.layout, ","); len(layouts) > 1 {
+		config = append(config, WithLayouts(layouts[0], layouts[1:]...))
+		args = append(args, "")
+	} else {
+		args = append(args, c.layout)
+	}
+	if c.cmosFile != "" {
+		args = append(args, c.cmosFile)
+	}
+
+	nv = NewNVRAM(append(config, opts...)...)
+	err = nv.Open(args...)
+	if err != nil {
+		return nil, err
+	}
+	return
+}
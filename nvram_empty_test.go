@@ -0,0 +1,162 @@
+// Copyright © 2019 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+package nvram
+
+import (
+	"bytes"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+)
+
+const emptyTestLayout = `entries
+
+enumerations
+
+checksums
+`
+
+// writeEmptyTest writes a layout without entries and a CMOS file with a
+// byte pattern and returns their paths.
+func writeEmptyTest(t *testing.T) (dir, layout, cmos string, image []byte) {
+	dir = t.TempDir()
+	layout = filepath.Join(dir, "cmos.layout")
+	cmos = filepath.Join(dir, "cmos.bin")
+	image = make([]byte, cmosSize)
+	for i := range image {
+		image[i] = byte(i)
+	}
+	if err := ioutil.WriteFile(layout, []byte(emptyTestLayout), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(cmos, image, 0644); err != nil {
+		t.Fatal(err)
+	}
+	return
+}
+
+func TestOpenEmptyLayout(t *testing.T) {
+	dir, layout, cmos, _ := writeEmptyTest(t)
+
+	nv := NewNVRAM(WithLockDir(dir))
+	if err := nv.Open(layout, cmos); err != ErrEmptyLayout {
+		t.Fatalf("Open returned %v, want %v", err, ErrEmptyLayout)
+	}
+
+	// The failed Open released the backend.
+	nv = NewNVRAM(WithLockDir(dir))
+	if err := nv.Open(layout, cmos); err != ErrEmptyLayout {
+		t.Fatalf("second Open returned %v, want %v", err, ErrEmptyLayout)
+	}
+}
+
+func TestOpenEmptyLayoutAllowed(t *testing.T) {
+	dir, layout, cmos, image := writeEmptyTest(t)
+
+	nv := NewNVRAM(WithLockDir(dir), WithEmptyLayout())
+	if err := nv.Open(layout, cmos); err != nil {
+		t.Fatal(err)
+	}
+	if !nv.Layout.IsEmpty() {
+		t.Error("layout is not empty")
+	}
+	if names := nv.ParameterNames(); len(names) != 0 {
+		t.Errorf("ParameterNames returned %v", names)
+	}
+	if _, err := nv.ReadCMOSParameter("any"); err == nil {
+		t.Error("reading a parameter of an empty layout did not fail")
+	}
+	if err := nv.ValidateChecksum(); err != nil {
+		t.Error(err)
+	}
+
+	// Raw access still works, and Close writes no checksum.
+	v, err := nv.ReadBits(400, 8)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v[0] != image[50] {
+		t.Errorf("ReadBits returned %02X, want %02X", v[0], image[50])
+	}
+	if err = nv.WriteBits(400, 8, []byte{0xEE}); err != nil {
+		t.Fatal(err)
+	}
+	if err = nv.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := ioutil.ReadFile(cmos)
+	if err != nil {
+		t.Fatal(err)
+	}
+	image[50] = 0xEE
+	if !bytes.Equal(b, image) {
+		t.Errorf("CMOS file changed beyond the written byte")
+	}
+}
+
+func newEmptyTestEntry(t *testing.T, bit, length uint, name string) *CMOSEntry {
+	e, err := NewCMOSEntry(bit, length, CMOSEntryHex, 0, name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return e
+}
+
+func entryNames(l *Layout) (names []string) {
+	for _, e := range l.GetCMOSEntriesList() {
+		names = append(names, e.Name())
+	}
+	return
+}
+
+func TestAddCMOSEntryEmptyLayout(t *testing.T) {
+	l := NewLayout()
+	if !l.IsEmpty() {
+		t.Fatal("new layout is not empty")
+	}
+
+	// The first entry goes to position 0 of the empty list.
+	if err := l.AddCMOSEntry(newEmptyTestEntry(t, 400, 8, "b")); err != nil {
+		t.Fatal(err)
+	}
+	if l.IsEmpty() {
+		t.Error("layout is empty after adding an entry")
+	}
+
+	// Entries before and after the first one are sorted by bit.
+	if err := l.AddCMOSEntry(newEmptyTestEntry(t, 384, 8, "a")); err != nil {
+		t.Fatal(err)
+	}
+	if err := l.AddCMOSEntry(newEmptyTestEntry(t, 408, 8, "c")); err != nil {
+		t.Fatal(err)
+	}
+	if err := l.AddCMOSEntry(newEmptyTestEntry(t, 404, 4, "overlap")); err == nil {
+		t.Error("adding an overlapping entry did not fail")
+	}
+
+	names := entryNames(l)
+	if len(names) != 3 || names[0] != "a" || names[1] != "b" || names[2] != "c" {
+		t.Errorf("entries are %v, want [a b c]", names)
+	}
+}
+
+func TestAddCMOSEntryDuplicateName(t *testing.T) {
+	l := NewLayout()
+	if err := l.AddCMOSEntry(newEmptyTestEntry(t, 384, 8, "a")); err != nil {
+		t.Fatal(err)
+	}
+	if err := l.AddCMOSEntry(newEmptyTestEntry(t, 400, 8, "a")); err == nil {
+		t.Error("adding an entry with a duplicate name did not fail")
+	}
+
+	list := l.GetCMOSEntriesList()
+	if len(list) != 1 || list[0].Bit() != 384 {
+		t.Errorf("entries are %v after the duplicate, want only a at 384", list)
+	}
+	if e, ok := l.FindCMOSEntry("a"); !ok || e.Bit() != 384 {
+		t.Errorf("FindCMOSEntry(a) returned %v, %v", e, ok)
+	}
+}
@@ -0,0 +1,103 @@
+// Copyright © 2019 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+package nvram
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// ExportOptions select what ExportParameters writes besides the values.
+type ExportOptions struct {
+	// Descriptions writes each parameter's description as a comment
+	// line before the value.
+	Descriptions bool
+	// Locale writes translated enum display texts as a trailing comment.
+	// Values are always written with the canonical enum text.
+	Locale string
+	// Group limits the export to the parameters of one group.
+	Group string
+	// Drift limits the export to the parameters differing from their
+	// default, as found by DriftFromDefaults, and writes the default as
+	// a trailing comment.
+	Drift bool
+}
+
+func formatParameterValue(value interface{}) string {
+	switch v := value.(type) {
+	case uint64:
+		return fmt.Sprintf("0x%X", v)
+	case string:
+		return v
+	}
+	return fmt.Sprint(value)
+}
+
+// ExportParameters writes all parameters in the nvramtool settings format,
+// one "name = value" line per parameter. Reserved entries listed
+// WithReservedEntries are written as comments, so the export can still be
+// applied.
+func (nv *NVRAM) ExportParameters(w io.Writer, opts ExportOptions) (err error) {
+	params, err := nv.ReadAllParameters()
+	if err != nil {
+		return
+	}
+
+	var defaults map[string]interface{}
+	if opts.Drift {
+		var drift []ParamDiff
+		drift, err = nv.DriftFromDefaults()
+		if err != nil {
+			return
+		}
+		defaults = make(map[string]interface{})
+		for _, d := range drift {
+			defaults[d.Name] = d.Before
+		}
+	}
+
+	bw := bufio.NewWriter(w)
+	for _, p := range params {
+		if opts.Group != "" && nv.parameterGroup(p.Name) != opts.Group {
+			continue
+		}
+		def, changed := defaults[p.Name]
+		if opts.Drift && !changed {
+			continue
+		}
+		value := formatParameterValue(p.Value)
+		e, isEntry := nv.FindCMOSEntry(p.Name)
+		if isEntry {
+			value = e.formatValue(p.Value)
+		}
+		value = quoteSettingValue(value)
+
+		if opts.Descriptions && isEntry {
+			comment := e.description
+			if e.meta.unit != "" {
+				comment = strings.TrimSpace(comment + " (" + e.meta.unit + ")")
+			}
+			if comment != "" {
+				fmt.Fprintf(bw, "# %s\n", comment)
+			}
+		}
+		if opts.Locale != "" {
+			if text, ok := nv.enumDisplayText(opts.Locale, p.Name, p.Value); ok {
+				value += " # " + text
+			}
+		}
+		if changed {
+			value += " # default " + e.formatValue(def)
+		}
+		if isEntry && e.config == CMOSEntryReserved {
+			fmt.Fprintf(bw, "# %s = %s (reserved)\n", p.Name, value)
+			continue
+		}
+		fmt.Fprintf(bw, "%s = %s\n", p.Name, value)
+	}
+	return bw.Flush()
+}
@@ -0,0 +1,168 @@
+// Copyright © 2019 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+package nvram
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"sync"
+	"time"
+)
+
+// maxFailureRuns is the number of runs kept in a failure statistics file.
+const maxFailureRuns = 100
+
+// FailureRun counts the hard failures seen while an NVRAM was open.
+type FailureRun struct {
+	Start            time.Time `json:"start"`
+	ChecksumFailures int       `json:"checksum_failures,omitempty"`
+	Corruptions      int       `json:"corruptions,omitempty"`
+	WriteErrors      int       `json:"write_errors,omitempty"`
+	BatteryFailed    bool      `json:"battery_failed,omitempty"`
+}
+
+func (r FailureRun) failures() int {
+	n := r.ChecksumFailures + r.Corruptions + r.WriteErrors
+	if r.BatteryFailed {
+		n++
+	}
+	return n
+}
+
+// FailureStats are hard failure counters persisted across runs by
+// WithFailureStats. Totals count all runs, Runs holds the most recent ones,
+// oldest first.
+type FailureStats struct {
+	ChecksumFailures uint64       `json:"checksum_failures"`
+	Corruptions      uint64       `json:"corruptions"`
+	WriteErrors      uint64       `json:"write_errors"`
+	BatteryFailures  uint64       `json:"battery_failures"`
+	Runs             []FailureRun `json:"runs"`
+}
+
+// ReadFailureStats reads a failure statistics file. A missing file holds
+// no failures.
+func ReadFailureStats(path string) (s FailureStats, err error) {
+	b, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return s, nil
+	}
+	if err != nil {
+		return
+	}
+	err = json.Unmarshal(b, &s)
+	return
+}
+
+// Trend returns the average number of failures per run in the last n runs
+// and in the n runs before them. A recent average above the previous one
+// shows a failing CMOS, such as a dying battery, getting worse.
+func (s FailureStats) Trend(n int) (recent, previous float64) {
+	average := func(runs []FailureRun) float64 {
+		if len(runs) == 0 {
+			return 0
+		}
+		total := 0
+		for _, r := range runs {
+			total += r.failures()
+		}
+		return float64(total) / float64(len(runs))
+	}
+
+	end := len(s.Runs)
+	start := end - n
+	if start < 0 {
+		start = 0
+	}
+	prev := start - n
+	if prev < 0 {
+		prev = 0
+	}
+	return average(s.Runs[start:end]), average(s.Runs[prev:start])
+}
+
+// WithFailureStats counts checksum failures, corruption events, write
+// errors and battery failures in the statistics file path. The file is
+// updated on Close.
+func WithFailureStats(path string) Option {
+	return func(nv *NVRAM) {
+		nv.failures = &failureStats{path: path}
+	}
+}
+
+type failureStats struct {
+	path  string
+	mu    sync.Mutex
+	stats FailureStats
+	run   FailureRun
+}
+
+// FailureStats returns the failure statistics including the current run.
+func (nv *NVRAM) FailureStats() (s FailureStats) {
+	f := nv.failures
+	if f == nil {
+		return
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	s = f.stats
+	s.Runs = append(append([]FailureRun(nil), f.stats.Runs...), f.run)
+	return
+}
+
+// startFailureStats loads the statistics file and starts a new run.
+func (nv *NVRAM) startFailureStats() (err error) {
+	f := nv.failures
+	if f == nil {
+		return
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.stats, err = ReadFailureStats(f.path)
+	f.run = FailureRun{Start: time.Now()}
+	return
+}
+
+// countFailure adds a failure to the current run.
+func (nv *NVRAM) countFailure(count func(s *FailureStats, r *FailureRun)) {
+	f := nv.failures
+	if f == nil {
+		return
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	count(&f.stats, &f.run)
+}
+
+// saveFailureStats appends the current run to the statistics file.
+func (nv *NVRAM) saveFailureStats() (err error) {
+	f := nv.failures
+	if f == nil || f.run.Start.IsZero() {
+		return
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.stats.Runs = append(f.stats.Runs, f.run)
+	if len(f.stats.Runs) > maxFailureRuns {
+		f.stats.Runs = f.stats.Runs[len(f.stats.Runs)-maxFailureRuns:]
+	}
+	f.run = FailureRun{}
+
+	b, err := json.Marshal(f.stats)
+	if err != nil {
+		return
+	}
+	tmp := f.path + ".tmp"
+	err = ioutil.WriteFile(tmp, b, 0644)
+	if err != nil {
+		return
+	}
+	return os.Rename(tmp, f.path)
+}
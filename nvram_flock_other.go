@@ -0,0 +1,18 @@
+// Copyright © 2019 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+//go:build !linux && !darwin && !freebsd && !netbsd && !openbsd && !dragonfly
+// +build !linux,!darwin,!freebsd,!netbsd,!openbsd,!dragonfly
+
+package nvram
+
+import (
+	"os"
+)
+
+// flockFile does not lock on this system, backends are only held against
+// other NVRAM handles of this process.
+func flockFile(f *os.File) (held bool, err error) {
+	return
+}
@@ -0,0 +1,47 @@
+// Copyright © 2019 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+package nvram
+
+import (
+	"fmt"
+)
+
+// WriteGatedError is returned for a write blocked by the write gate. It
+// matches ErrWriteGated with errors.Is and unwraps to the gate's error.
+type WriteGatedError struct {
+	Err error
+}
+
+func (e *WriteGatedError) Error() string {
+	return fmt.Sprintf("nvram: CMOS writes blocked: %v", e.Err)
+}
+
+func (e *WriteGatedError) Unwrap() error {
+	return e.Err
+}
+
+func (e *WriteGatedError) Is(target error) bool {
+	return target == ErrWriteGated
+}
+
+// SetWriteGate sets a function called before every CMOS write. If it
+// returns an error the write fails with a *WriteGatedError, e.g. to block
+// CMOS changes while a firmware update is flashed. A nil gate allows all
+// writes. SetWriteGate must not be called concurrently with writes.
+func (nv *NVRAM) SetWriteGate(gate func() error) {
+	nv.writeGate = gate
+}
+
+// checkWriteGate checks the write gate and calls the pre-write hooks
+// before a write.
+func (nv *NVRAM) checkWriteGate() error {
+	if nv.writeGate == nil {
+		return nv.preWrite()
+	}
+	if err := nv.writeGate(); err != nil {
+		return &WriteGatedError{Err: err}
+	}
+	return nv.preWrite()
+}
@@ -0,0 +1,109 @@
+// Copyright © 2019 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+package nvram
+
+import (
+	"time"
+)
+
+type checksumGuard struct {
+	interval time.Duration
+	repair   bool
+	stop     chan struct{}
+	done     chan struct{}
+}
+
+// WithChecksumGuard starts a background goroutine while the NVRAM is open
+// that checks the checksummed CMOS area and stored checksum every interval.
+// Any byte changed other than through this NVRAM is reported as an
+// EventCorruption to the event handler. If repair is set the changed bytes
+// are restored and an EventRepaired is reported.
+func WithChecksumGuard(interval time.Duration, repair bool) Option {
+	return func(nv *NVRAM) {
+		nv.guard.interval = interval
+		nv.guard.repair = repair
+	}
+}
+
+func (nv *NVRAM) startChecksumGuard() (err error) {
+	if nv.guard.interval <= 0 {
+		return
+	}
+
+	// Take a copy of the current CMOS to check against
+	err = nv.CMOS.startShadow()
+	if err != nil {
+		return
+	}
+
+	nv.guard.stop = make(chan struct{})
+	nv.guard.done = make(chan struct{})
+	go nv.runChecksumGuard(nv.guard.stop, nv.guard.done)
+	return
+}
+
+func (nv *NVRAM) stopChecksumGuard() {
+	if nv.guard.stop == nil {
+		return
+	}
+	close(nv.guard.stop)
+	<-nv.guard.done
+	nv.guard.stop = nil
+	nv.guard.done = nil
+	nv.CMOS.stopShadow()
+}
+
+func (nv *NVRAM) runChecksumGuard(stop, done chan struct{}) {
+	defer close(done)
+
+	ticker := time.NewTicker(nv.guard.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			nv.checkChecksumGuard()
+		}
+	}
+}
+
+func (nv *NVRAM) checkChecksumGuard() {
+	ranges, err := nv.CMOS.compareShadow()
+	if err != nil {
+		nv.emit(Event{Kind: EventGuardError, Err: err})
+		return
+	}
+	if len(ranges) == 0 {
+		return
+	}
+
+	ev := Event{Kind: EventCorruption, Ranges: ranges, Suspects: nv.suspects(ranges)}
+	ev.Computed, _ = nv.CMOS.ComputeChecksum()
+	ev.Stored, _ = nv.CMOS.ReadChecksum()
+	nv.emit(ev)
+
+	if !nv.guard.repair {
+		return
+	}
+
+	// Restore changed bytes from the shadow copy as a write operation, so
+	// the write gate and hooks apply.
+	nv.beginWrites()
+	err = nv.checkWriteGate()
+	if err == nil {
+		err = nv.CMOS.restoreShadow(ranges)
+	}
+	nv.endWrites(&err)
+	if err != nil {
+		nv.emit(Event{Kind: EventGuardError, Ranges: ranges, Err: err})
+		return
+	}
+	ev = Event{Kind: EventRepaired, Ranges: ranges, Suspects: nv.suspects(ranges)}
+	ev.Computed, _ = nv.CMOS.ComputeChecksum()
+	ev.Stored, _ = nv.CMOS.ReadChecksum()
+	nv.emit(ev)
+}
@@ -0,0 +1,119 @@
+// Copyright © 2019 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+package nvram
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// WriteHook is called before or after a burst of CMOS writes.
+type WriteHook func(ctx context.Context) error
+
+// writeHooks holds the hooks and the state of the current write burst. The
+// state is locked as the checksum guard repairs CMOS from its goroutine.
+type writeHooks struct {
+	pre, post []WriteHook
+	mu        sync.Mutex
+	depth     int
+	ctx       context.Context
+	started   bool
+	preErr    error
+}
+
+// RegisterPreWriteHook adds a hook called before the first CMOS write of
+// every write operation, such as WriteCMOSParameter, WriteCMOSParameters,
+// a restore or the checksum update of Close, e.g. to pause other software
+// using the CMOS ports. Operations that write nothing do not call it. Hooks
+// are called in registration order. If one fails the operation fails with
+// its error without writing. Hooks must not be registered concurrently
+// with writes.
+func (nv *NVRAM) RegisterPreWriteHook(hook WriteHook) {
+	nv.hooks.pre = append(nv.hooks.pre, hook)
+}
+
+// RegisterPostWriteHook adds a hook called after the writes of every
+// operation that started the pre-write hooks, also when a write or a
+// pre-write hook failed, so paused software is always resumed. Hooks are
+// called in reverse registration order and an error is returned by the
+// operation if it succeeded otherwise.
+func (nv *NVRAM) RegisterPostWriteHook(hook WriteHook) {
+	nv.hooks.post = append(nv.hooks.post, hook)
+}
+
+// WriteBurst runs f as one write operation, so the write hooks are called
+// once around all writes of f instead of around each of them, and receive
+// ctx.
+func (nv *NVRAM) WriteBurst(ctx context.Context, f func() error) (err error) {
+	h := &nv.hooks
+	h.mu.Lock()
+	if h.depth == 0 {
+		h.ctx = ctx
+	}
+	h.depth++
+	h.mu.Unlock()
+	defer nv.endWrites(&err)
+	return f()
+}
+
+// beginWrites starts a write operation, which endWrites must end.
+// Operations may nest, only the outermost one calls the hooks.
+func (nv *NVRAM) beginWrites() {
+	nv.hooks.mu.Lock()
+	nv.hooks.depth++
+	nv.hooks.mu.Unlock()
+}
+
+// endWrites ends a write operation and calls the post-write hooks once the
+// outermost one ends.
+func (nv *NVRAM) endWrites(err *error) {
+	h := &nv.hooks
+	h.mu.Lock()
+	h.depth--
+	if h.depth > 0 {
+		h.mu.Unlock()
+		return
+	}
+	ctx := h.writeContext()
+	started := h.started
+	h.started, h.preErr, h.ctx = false, nil, nil
+	h.mu.Unlock()
+	if !started {
+		return
+	}
+	for i := len(h.post) - 1; i >= 0; i-- {
+		if herr := h.post[i](ctx); herr != nil && *err == nil {
+			*err = fmt.Errorf("nvram: Post-write hook failed: %w", herr)
+		}
+	}
+}
+
+// preWrite calls the pre-write hooks before the first write of an
+// operation.
+func (nv *NVRAM) preWrite() error {
+	h := &nv.hooks
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.started || len(h.pre)+len(h.post) == 0 {
+		return h.preErr
+	}
+	h.started = true
+	ctx := h.writeContext()
+	for _, hook := range h.pre {
+		if herr := hook(ctx); herr != nil {
+			h.preErr = fmt.Errorf("nvram: Pre-write hook failed: %w", herr)
+			break
+		}
+	}
+	return h.preErr
+}
+
+func (h *writeHooks) writeContext() context.Context {
+	if h.ctx == nil {
+		return context.Background()
+	}
+	return h.ctx
+}
@@ -0,0 +1,109 @@
+// Copyright © 2019 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+package nvram
+
+import (
+	"fmt"
+)
+
+// ParameterInfo describes a parameter for user interfaces.
+type ParameterInfo struct {
+	Name        string
+	Config      CMOSEntryConfig
+	Bit         uint
+	Length      uint
+	Description string
+	// Unit, Base, Min and Max are display hints for hex parameters.
+	Unit string
+	Base int
+	Min  uint64
+	Max  uint64
+	// Values holds the allowed texts of an enum parameter.
+	Values []string
+	// Parent is the hex parameter holding a subfield.
+	Parent string
+	// Deprecated is set for parameters marked deprecated in the layout.
+	Deprecated bool
+	// Aliases are old names that refer to this parameter.
+	Aliases []string
+	// Virtual is set for parameters registered with
+	// RegisterVirtualParameter.
+	Virtual bool
+}
+
+// ParameterInfo returns the description of a named parameter.
+func (nv *NVRAM) ParameterInfo(name string) (info ParameterInfo, err error) {
+	name = nv.resolveName(name)
+
+	info.Name = name
+
+	if _, ok := nv.virtuals[name]; ok {
+		info.Virtual = true
+		return
+	}
+
+	if s, ok := nv.FindCMOSSubfield(name); ok {
+		info.Config = CMOSEntryHex
+		info.Bit = s.parent.bit + s.bit
+		info.Length = s.length
+		info.Parent = s.parent.name
+		info.Base = 16
+		info.Max = subfieldMask(s)
+		return
+	}
+
+	e, err := nv.findParameterEntry(name)
+	if err != nil {
+		return
+	}
+
+	info.Config = e.config
+	info.Bit = e.bit
+	info.Length = e.length
+	info.Description = e.description
+	info.Deprecated = e.meta.deprecated
+	info.Aliases = nv.GetCMOSAliases(name)
+	if e.config == CMOSEntryHex {
+		info.Unit = e.Unit()
+		info.Base = e.DisplayBase()
+		info.Min, info.Max = e.Range()
+	}
+
+	if e.config == CMOSEntryEnum {
+		items, _ := nv.GetCMOSEnumItemsById(e.config_id)
+		for _, item := range items {
+			info.Values = append(info.Values, item.text)
+		}
+	}
+	return
+}
+
+// FormatParameter formats a parameter value for display using the layout's
+// display base and unit.
+func (nv *NVRAM) FormatParameter(name string, value interface{}) string {
+	e, ok := nv.FindCMOSEntry(name)
+	if !ok {
+		return formatParameterValue(value)
+	}
+	s := e.formatValue(value)
+	if e.meta.unit != "" {
+		s += " " + e.meta.unit
+	}
+	return s
+}
+
+// MaxLength returns the maximum number of characters of a string parameter.
+func (nv *NVRAM) MaxLength(name string) (max int, err error) {
+	name = nv.resolveName(name)
+	e, err := nv.findParameterEntry(name)
+	if err != nil {
+		return
+	}
+	if e.config != CMOSEntryString {
+		err = fmt.Errorf("CMOS parameter %s is not a string parameter.", name)
+		return
+	}
+	return int(e.length / 8), nil
+}
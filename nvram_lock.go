@@ -0,0 +1,196 @@
+// Copyright © 2019 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+package nvram
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"github.com/platinasystems/nvram/debug"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultLockDir is the directory of the lock files holding CMOS backends
+// against other processes.
+const DefaultLockDir = "/run/lock"
+
+// WithLockDir makes Open create the lock files holding the CMOS backend
+// against other processes in dir instead of DefaultLockDir.
+func WithLockDir(dir string) Option {
+	return func(nv *NVRAM) {
+		nv.lockDir = dir
+	}
+}
+
+// backendLocks holds the CMOS backends currently opened by an NVRAM handle.
+var backendLocks = struct {
+	sync.Mutex
+	held map[string]bool
+}{held: make(map[string]bool)}
+
+// backendLockKey returns the lock key of a CMOS backend. Memory files are
+// locked by absolute path, the hardware and the privileged helper share one
+// key as both access the same CMOS.
+func backendLockKey(cmosMemFileName string) string {
+	if cmosMemFileName == "" {
+		return "hardware"
+	}
+	if abs, err := filepath.Abs(cmosMemFileName); err == nil {
+		cmosMemFileName = abs
+	}
+	return "file:" + cmosMemFileName
+}
+
+// lockBackend reports false if the backend is already held.
+func lockBackend(key string) bool {
+	backendLocks.Lock()
+	defer backendLocks.Unlock()
+
+	if backendLocks.held[key] {
+		return false
+	}
+	backendLocks.held[key] = true
+	return true
+}
+
+func unlockBackend(key string) {
+	backendLocks.Lock()
+	defer backendLocks.Unlock()
+
+	delete(backendLocks.held, key)
+}
+
+// lockFilePath returns the lock file of a backend key, or "" for backends
+// that only exist in this process.
+func (nv *NVRAM) lockFilePath(key string) string {
+	dir := nv.lockDir
+	if dir == "" {
+		dir = DefaultLockDir
+	}
+	switch {
+	case key == "hardware":
+		return filepath.Join(dir, "nvram-hardware.lock")
+	case strings.HasPrefix(key, "file:"):
+		sum := sha256.Sum256([]byte(key))
+		return filepath.Join(dir, "nvram-file-"+hex.EncodeToString(sum[:8])+".lock")
+	}
+	return ""
+}
+
+// lockBackendFile holds the backend against other processes with a lock
+// on its lock file and returns ErrNVRAMAccessInUse if another process
+// holds it. The lock is skipped if the lock file can not be created, e.g.
+// when the lock directory is not writable for an unprivileged user.
+func (nv *NVRAM) lockBackendFile(key string) (err error) {
+	path := nv.lockFilePath(key)
+	if path == "" {
+		return
+	}
+	f, err := os.OpenFile(path, os.O_RDONLY|os.O_CREATE, 0644)
+	if err != nil {
+		debug.Trace(debug.LevelMSG1, "Not locking CMOS backend against other processes: %v\n", err)
+		return nil
+	}
+
+	held, err := flockFile(f)
+	if err != nil || held {
+		f.Close()
+		if held {
+			return ErrNVRAMAccessInUse
+		}
+		debug.Trace(debug.LevelMSG1, "Not locking CMOS backend against other processes: %v\n", err)
+		return nil
+	}
+	nv.lockFile = f
+	return
+}
+
+// unlockBackendFile releases the lock of lockBackendFile.
+func (nv *NVRAM) unlockBackendFile() {
+	if nv.lockFile != nil {
+		nv.lockFile.Close()
+		nv.lockFile = nil
+	}
+}
+
+// Backoff controls waiting for a busy CMOS backend in OpenWithRetry.
+type Backoff struct {
+	// Delay is the wait before the second attempt. It doubles for every
+	// further attempt up to MaxDelay if that is set.
+	Delay    time.Duration
+	MaxDelay time.Duration
+}
+
+// OpenWithRetry opens the NVRAM like Open, but while the CMOS backend is
+// held by another NVRAM handle, in this or another process, it waits with
+// exponential backoff and tries again, until ctx is done. Other errors are
+// returned at once. A zero Delay waits 10ms.
+func (nv *NVRAM) OpenWithRetry(ctx context.Context, b Backoff, args ...string) (err error) {
+	delay := b.Delay
+	if delay <= 0 {
+		delay = 10 * time.Millisecond
+	}
+	for {
+		err = nv.Open(args...)
+		if err != ErrNVRAMAccessInUse {
+			return
+		}
+
+		// Back off before the next attempt
+		t := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return fmt.Errorf("nvram: Gave up waiting for busy NVRAM (%v): %w", ctx.Err(), err)
+		case <-t.C:
+		}
+		delay *= 2
+		if b.MaxDelay > 0 && delay > b.MaxDelay {
+			delay = b.MaxDelay
+		}
+	}
+}
+
+// CopyParameters copies the named parameters from src to dst as one
+// transaction. Without names every parameter of src that dst also defines
+// is copied. Values are copied in the form they are presented in, so the
+// transformers of src and dst apply.
+func CopyParameters(dst, src *NVRAM, names []string) (err error) {
+	if len(names) == 0 {
+		for _, name := range src.ParameterNames() {
+			if dst.hasParameter(name) {
+				names = append(names, name)
+			}
+		}
+	}
+
+	params := make([]Parameter, len(names))
+	for i, name := range names {
+		params[i].Name = name
+		params[i].Value, err = src.ReadCMOSParameter(name)
+		if err != nil {
+			return
+		}
+	}
+	return dst.WriteCMOSParameters(params)
+}
+
+// hasParameter reports if a parameter of this name can be written.
+func (nv *NVRAM) hasParameter(name string) bool {
+	name = nv.resolveName(name)
+	if _, ok := nv.virtuals[name]; ok {
+		return true
+	}
+	if _, ok := nv.FindCMOSSubfield(name); ok {
+		return true
+	}
+	e, err := nv.findParameterEntry(name)
+	return err == nil && e.config != CMOSEntryReserved
+}
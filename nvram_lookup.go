@@ -0,0 +1,66 @@
+// Copyright © 2019 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+package nvram
+
+import (
+	"fmt"
+)
+
+// ParameterError is returned for a parameter that can not be accessed. Err
+// is ErrParameterNotFound or ErrChecksumParameter.
+type ParameterError struct {
+	Name string
+	Err  error
+}
+
+func (e *ParameterError) Error() string {
+	switch e.Err {
+	case ErrParameterNotFound:
+		return fmt.Sprintf("CMOS parameter %s not found.", e.Name)
+	case ErrChecksumParameter:
+		return fmt.Sprintf("CMOS parameter %s holds the checksum, use ReadStoredChecksumParameter or WriteStoredChecksumParameter.", e.Name)
+	}
+	return fmt.Sprintf("CMOS parameter %s: %v", e.Name, e.Err)
+}
+
+func (e *ParameterError) Unwrap() error {
+	return e.Err
+}
+
+// findParameterEntry returns the CMOS entry of a parameter. The check_sum
+// entry is only accessible through the stored checksum methods.
+func (nv *NVRAM) findParameterEntry(name string) (e *CMOSEntry, err error) {
+	if name == "check_sum" {
+		err = &ParameterError{Name: name, Err: ErrChecksumParameter}
+		return
+	}
+	e, ok := nv.FindCMOSEntry(name)
+	if !ok {
+		err = &ParameterError{Name: name, Err: ErrParameterNotFound}
+	}
+	return
+}
+
+// ReadStoredChecksumParameter returns the checksum stored in CMOS.
+func (nv *NVRAM) ReadStoredChecksumParameter() (sum uint16, err error) {
+	return nv.CMOS.ReadChecksum()
+}
+
+// WriteStoredChecksumParameter writes the checksum stored in CMOS. Close does
+// not replace it with a computed checksum unless parameters are written
+// afterwards.
+func (nv *NVRAM) WriteStoredChecksumParameter(sum uint16) (err error) {
+	nv.beginWrites()
+	defer nv.endWrites(&err)
+	err = nv.checkWriteGate()
+	if err != nil {
+		return
+	}
+	err = nv.CMOS.WriteChecksum(sum)
+	if err == nil {
+		nv.modified = false
+	}
+	return
+}
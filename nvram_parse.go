@@ -0,0 +1,54 @@
+// Copyright © 2019 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+package nvram
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// ParseParameterValue converts the text form of a parameter value, as
+// written by ExportParameters, to the value type of the named parameter.
+// Hex parameters accept decimal or 0x prefixed hex numbers. Enum and string
+// parameters, virtual parameters and parameters with a write transformer
+// take the text as is.
+func (nv *NVRAM) ParseParameterValue(name string, s string) (value interface{}, err error) {
+	name = nv.resolveName(name)
+
+	if _, ok := nv.virtuals[name]; ok {
+		value = s
+		return
+	}
+	if t, ok := nv.transformers[name]; ok && t.write != nil {
+		value = s
+		return
+	}
+
+	config := CMOSEntryHex
+	if _, ok := nv.FindCMOSSubfield(name); !ok {
+		var e *CMOSEntry
+		e, err = nv.findParameterEntry(name)
+		if err != nil {
+			return
+		}
+		config = e.config
+	}
+
+	switch config {
+	case CMOSEntryHex:
+		var n uint64
+		n, err = strconv.ParseUint(s, 0, 64)
+		if err != nil {
+			err = fmt.Errorf("Bad value %s for parameter %s", s, name)
+			return
+		}
+		value = n
+	case CMOSEntryString, CMOSEntryEnum:
+		value = s
+	default:
+		err = fmt.Errorf("Parameter %s is reserved.", name)
+	}
+	return
+}
@@ -0,0 +1,126 @@
+// Copyright © 2019 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+package nvram
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+// DefaultMaxRebootCount is coreboot's default CONFIG_MAX_REBOOT_CNT, the
+// number of normal boot attempts before it boots the fallback image.
+const DefaultMaxRebootCount = 3
+
+// FirmwarePrediction describes what coreboot will do with the current CMOS
+// state at the next boot.
+type FirmwarePrediction struct {
+	// ChecksumValid and BatteryGood are the CMOS state checked by
+	// coreboot. BatteryKnown is false if the backend has no RTC.
+	ChecksumValid bool
+	BatteryGood   bool
+	BatteryKnown  bool
+	// UseDefaults is set when coreboot ignores the stored options, and
+	// WritesDefaultImage when it writes the layout's default image
+	// (cmos.default) over CMOS instead of using built in defaults.
+	UseDefaults        bool
+	WritesDefaultImage bool
+	// BootKnown is set if the layout has the boot_option and
+	// reboot_counter entries used for fallback boot.
+	BootKnown bool
+	// FallbackBoot is set if the fallback image will boot, and
+	// RebootCounter holds the counter coreboot will store.
+	FallbackBoot  bool
+	RebootCounter uint64
+	// Notes explain the prediction.
+	Notes []string
+}
+
+// WithMaxRebootCount sets the CONFIG_MAX_REBOOT_CNT of the firmware for
+// PredictFirmwareBehavior. The default is DefaultMaxRebootCount.
+func WithMaxRebootCount(n uint64) Option {
+	return func(nv *NVRAM) {
+		nv.maxRebootCount = n
+	}
+}
+
+// PredictFirmwareBehavior reports what coreboot will do at the next boot
+// given the current CMOS state, following its handling of a bad checksum
+// or lost CMOS and of the boot_option and reboot_counter fallback boot
+// entries. Nothing is written.
+func (nv *NVRAM) PredictFirmwareBehavior() (p FirmwarePrediction, err error) {
+	p.ChecksumValid = nv.CMOS.checksum.kind == ChecksumNone || nv.ValidateChecksum() == nil
+	if ok, berr := nv.BatteryGood(); berr == nil {
+		p.BatteryKnown = true
+		p.BatteryGood = ok
+	}
+	bad := !p.ChecksumValid || (p.BatteryKnown && !p.BatteryGood)
+
+	if bad {
+		p.UseDefaults = true
+		_, p.WritesDefaultImage = nv.DefaultImage()
+		if !p.ChecksumValid {
+			p.Notes = append(p.Notes, "CMOS checksum is bad.")
+		} else {
+			p.Notes = append(p.Notes, "CMOS battery failed.")
+		}
+		if p.WritesDefaultImage {
+			p.Notes = append(p.Notes, "Firmware writes the default CMOS image, all settings are replaced.")
+		} else {
+			p.Notes = append(p.Notes, "Firmware ignores the stored settings and uses built in defaults.")
+		}
+	}
+
+	// Read the fallback boot entries.
+	option, ok := nv.FindCMOSEntry("boot_option")
+	counter, cok := nv.FindCMOSEntry("reboot_counter")
+	if !ok || !cok {
+		p.Notes = append(p.Notes, "Layout has no boot_option and reboot_counter, boot image unknown.")
+		return
+	}
+	p.BootKnown = true
+	normal, err := nv.readRawValue(option)
+	if err != nil {
+		return
+	}
+	p.RebootCounter, err = nv.readRawValue(counter)
+	if err != nil {
+		return
+	}
+	max := nv.maxRebootCount
+	if max == 0 {
+		max = DefaultMaxRebootCount
+	}
+
+	switch {
+	case bad:
+		// A bad CMOS forces fallback boot with a full counter.
+		p.FallbackBoot = true
+		p.RebootCounter = 1<<counter.length - 1
+		p.Notes = append(p.Notes, "Fallback image boots, the bad CMOS forces it.")
+	case normal&1 == 0:
+		p.FallbackBoot = true
+		p.Notes = append(p.Notes, "Fallback image boots, boot_option selects it.")
+	case p.RebootCounter < max:
+		p.RebootCounter++
+		p.Notes = append(p.Notes, fmt.Sprintf("Normal image boots, attempt %d of %d.",
+			p.RebootCounter, max))
+	default:
+		p.FallbackBoot = true
+		p.Notes = append(p.Notes, fmt.Sprintf("Fallback image boots, reboot_counter reached %d.", max))
+	}
+	return
+}
+
+// readRawValue reads the numeric value of an enum or hex entry.
+func (nv *NVRAM) readRawValue(e *CMOSEntry) (value uint64, err error) {
+	if e.config != CMOSEntryEnum && e.config != CMOSEntryHex {
+		return 0, fmt.Errorf("CMOS entry %s is not an enum or hex entry.", e.name)
+	}
+	v, err := nv.CMOS.ReadEntry(e)
+	if err != nil {
+		return
+	}
+	return binary.LittleEndian.Uint64(v), nil
+}
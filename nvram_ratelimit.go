@@ -0,0 +1,94 @@
+// Copyright © 2019 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+package nvram
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// RateLimit allows Count writes of a parameter in any period of length Per.
+// Writes beyond the limit fail with a RateLimitError, or if Wait is set
+// are delayed until the limit allows them.
+type RateLimit struct {
+	Count int
+	Per   time.Duration
+	Wait  bool
+}
+
+// RateLimitError is returned for a write rejected by a rate limit.
+type RateLimitError struct {
+	Name       string
+	Limit      RateLimit
+	RetryAfter time.Duration
+}
+
+func (e *RateLimitError) Error() string {
+	return fmt.Sprintf("CMOS parameter %s written more than %d times in %v, retry after %v.",
+		e.Name, e.Limit.Count, e.Limit.Per, e.RetryAfter)
+}
+
+type rateLimiter struct {
+	limit RateLimit
+	mu    sync.Mutex
+	times []time.Time
+}
+
+// WithRateLimit limits writes to the named CMOS entries, e.g. for vendor
+// areas backed by flash emulated CMOS that wear with every write. Writes of
+// subfields count against their parent entry. Writes are counted per NVRAM,
+// while it is in use.
+func WithRateLimit(limit RateLimit, names ...string) Option {
+	return func(nv *NVRAM) {
+		if nv.rateLimits == nil {
+			nv.rateLimits = make(map[string]*rateLimiter)
+		}
+		for _, name := range names {
+			nv.rateLimits[name] = &rateLimiter{limit: limit}
+		}
+	}
+}
+
+// allow records a write at now, or returns how long to wait before the
+// write is allowed.
+func (r *rateLimiter) allow(now time.Time) (wait time.Duration) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	// Forget writes older than the period
+	i := 0
+	for i < len(r.times) && now.Sub(r.times[i]) >= r.limit.Per {
+		i++
+	}
+	r.times = r.times[i:]
+
+	if len(r.times) >= r.limit.Count {
+		if len(r.times) == 0 {
+			return r.limit.Per
+		}
+		return r.times[0].Add(r.limit.Per).Sub(now)
+	}
+	r.times = append(r.times, now)
+	return 0
+}
+
+// checkRateLimit counts a write of name against its rate limit.
+func (nv *NVRAM) checkRateLimit(name string) (err error) {
+	r, ok := nv.rateLimits[name]
+	if !ok {
+		return
+	}
+	for {
+		wait := r.allow(time.Now())
+		if wait <= 0 {
+			return
+		}
+		if !r.limit.Wait {
+			return &RateLimitError{Name: name, Limit: r.limit, RetryAfter: wait}
+		}
+		time.Sleep(wait)
+	}
+}
@@ -0,0 +1,98 @@
+// Copyright © 2019 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+package nvram
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+// WithRebootFlag names a CMOS parameter that is set to 1 when a write
+// changes an option that requires a reboot, so the need for a reboot
+// survives until it is cleared. Without a flag parameter the need for a
+// reboot is only remembered by this NVRAM.
+func WithRebootFlag(name string) Option {
+	return func(nv *NVRAM) {
+		nv.rebootFlag = name
+	}
+}
+
+// documentsReboot returns true if any entry of the layout is marked as
+// requiring a reboot.
+func (l *Layout) documentsReboot() bool {
+	for _, e := range l.entryList() {
+		if e.meta.reboot {
+			return true
+		}
+	}
+	return false
+}
+
+func (nv *NVRAM) rebootFlagEntry() (e *CMOSEntry, err error) {
+	e, ok := nv.FindCMOSEntry(nv.rebootFlag)
+	if !ok || e.config == CMOSEntryString || e.config == CMOSEntryReserved {
+		err = fmt.Errorf("CMOS reboot flag parameter %s not found.", nv.rebootFlag)
+	}
+	return
+}
+
+func (nv *NVRAM) writeRebootFlag(n uint64) (err error) {
+	e, err := nv.rebootFlagEntry()
+	if err != nil {
+		return
+	}
+	v := make([]byte, 8)
+	binary.LittleEndian.PutUint64(v, n)
+	nv.beginWrites()
+	defer nv.endWrites(&err)
+	err = nv.checkWriteGate()
+	if err != nil {
+		return
+	}
+	err = nv.CMOS.WriteEntry(e, v)
+	if err == nil {
+		nv.modified = true
+	}
+	return
+}
+
+// SetRebootRequired notes that a reboot is required, setting the reboot flag
+// parameter if one is configured.
+func (nv *NVRAM) SetRebootRequired() (err error) {
+	nv.rebootRequired = true
+	if nv.rebootFlag == "" {
+		return
+	}
+	return nv.writeRebootFlag(1)
+}
+
+// ClearRebootRequired clears the reboot flag, e.g. once the reboot has been
+// scheduled.
+func (nv *NVRAM) ClearRebootRequired() (err error) {
+	nv.rebootRequired = false
+	if nv.rebootFlag == "" {
+		return
+	}
+	return nv.writeRebootFlag(0)
+}
+
+// RebootRequired returns true if options were changed that take effect
+// after a reboot. With a reboot flag parameter the flag is read from CMOS,
+// so changes made before a warm reboot or kexec that skipped the firmware
+// are still reported.
+func (nv *NVRAM) RebootRequired() bool {
+	if nv.rebootFlag == "" {
+		return nv.rebootRequired
+	}
+	e, err := nv.rebootFlagEntry()
+	if err != nil {
+		return nv.rebootRequired
+	}
+	v, err := nv.CMOS.ReadEntry(e)
+	if err != nil {
+		return nv.rebootRequired
+	}
+	return binary.LittleEndian.Uint64(v) != 0
+}
@@ -0,0 +1,51 @@
+// Copyright © 2019 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+package nvram
+
+import (
+	"fmt"
+	"strings"
+)
+
+// WithReservedEntries lists reserved entries with the other parameters, so
+// areas the layout marks reserved can be observed when chasing corruption.
+// ReadCMOSParameter returns their raw contents as a hex string of the
+// entry's bits, most significant first. Reserved entries stay read only.
+func WithReservedEntries() Option {
+	return func(nv *NVRAM) {
+		nv.showReserved = true
+	}
+}
+
+// readRawEntry reads the bits of an entry of any config or width as a hex
+// string. Bits in the RTC area read as zero, as with ReadAllMemory.
+func (c *CMOS) readRawEntry(e *CMOSEntry) (value string, err error) {
+	// Collect the entry bits least significant byte first.
+	v := make([]byte, (e.length+7)/8)
+	var n byte
+	for i := uint(0); i < e.length; i++ {
+		bit := e.bit + i
+		if !verifyCMOSByteIndex(bit / 8) {
+			continue
+		}
+		if i == 0 || bit%8 == 0 || bit/8 == cmosRTCAreaSize {
+			n, err = c.ReadByte(bit / 8)
+			if err != nil {
+				return
+			}
+		}
+		if n&(1<<(bit%8)) != 0 {
+			v[i/8] |= 1 << (i % 8)
+		}
+	}
+
+	var b strings.Builder
+	b.WriteString("0x")
+	digits := int(e.length+3) / 4
+	for i := digits - 1; i >= 0; i-- {
+		fmt.Fprintf(&b, "%X", v[i/2]>>(uint(i%2)*4)&0xF)
+	}
+	return b.String(), nil
+}
@@ -0,0 +1,269 @@
+// Copyright © 2019 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+package nvram
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"io/ioutil"
+	"os"
+)
+
+// restoreJournalMagic starts a restore journal. The journal holds the CMOS
+// image before the restore, the image restored and a sha256 over both.
+var restoreJournalMagic = []byte("NVRAMJ1\n")
+
+// WithRestoreJournal makes RestoreImage write a journal file of the restore
+// before changing CMOS. Open finishes a restore found in the journal, so a
+// crash during a restore does not leave partly restored settings.
+func WithRestoreJournal(path string) Option {
+	return func(nv *NVRAM) {
+		nv.restoreJournal = path
+	}
+}
+
+// RestoreImage writes a full CMOS image, such as one read by ReadAllMemory,
+// with the checksum written last. The image is restored as is, Close does
+// not compute a new checksum for it. Parameters marked unique keep their
+// current value unless the NVRAM was configured WithUniqueRestore, the
+// checksum of the image is then updated to match.
+func (nv *NVRAM) RestoreImage(image []byte) (err error) {
+	if len(image) < int(cmosSize) {
+		return fmt.Errorf("nvram: CMOS image smaller than %d bytes.", cmosSize)
+	}
+	image = append([]byte(nil), image[:cmosSize]...)
+	span := nv.startSpan("nvram.RestoreImage", SpanAttribute{"nvram.bytes", len(image)})
+	defer nv.endSpan(span, &err)
+	nv.beginWrites()
+	defer nv.endWrites(&err)
+
+	err = nv.checkWriteGate()
+	if err != nil {
+		return
+	}
+
+	// Keep unique parameters and checksum the image again if any were.
+	kept, err := nv.keepUniqueBits(image, 0)
+	if err != nil {
+		return
+	}
+	if c := nv.CMOS.checksum; kept && c.kind != ChecksumNone {
+		var sum uint16
+		for _, b := range image[c.start : c.end+1] {
+			sum += uint16(b)
+		}
+		image[c.index] = byte(sum >> 8)
+		image[c.index+1] = byte(sum & 0xFF)
+	}
+
+	if nv.restoreJournal != "" {
+		var old []byte
+		old, err = nv.CMOS.ReadAllMemory()
+		if err != nil {
+			return
+		}
+		err = writeRestoreJournal(nv.restoreJournal, old, image)
+		if err != nil {
+			return
+		}
+	}
+
+	err = nv.CMOS.WriteAllMemory(image)
+	if err != nil {
+		return
+	}
+	nv.modified = false
+
+	if nv.restoreJournal != "" {
+		err = os.Remove(nv.restoreJournal)
+	}
+	return
+}
+
+// writeRestoreJournal writes and syncs the journal under a temporary name
+// and renames it into place, so a journal is either complete or missing.
+func writeRestoreJournal(path string, old, image []byte) (err error) {
+	var b bytes.Buffer
+	b.Write(restoreJournalMagic)
+	b.Write(old)
+	b.Write(image)
+	sum := sha256.Sum256(b.Bytes())
+	b.Write(sum[:])
+
+	tmp := path + ".tmp"
+	f, err := os.OpenFile(tmp, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return
+	}
+	_, err = f.Write(b.Bytes())
+	if err == nil {
+		err = f.Sync()
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return
+	}
+	return os.Rename(tmp, path)
+}
+
+func readRestoreJournal(path string) (old, image []byte, err error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return
+	}
+
+	n := len(restoreJournalMagic)
+	size := n + 2*int(cmosSize) + sha256.Size
+	if len(b) != size || !bytes.Equal(b[:n], restoreJournalMagic) {
+		err = fmt.Errorf("nvram: Bad restore journal %s.", path)
+		return
+	}
+	sum := sha256.Sum256(b[:size-sha256.Size])
+	if !bytes.Equal(sum[:], b[size-sha256.Size:]) {
+		err = fmt.Errorf("nvram: Restore journal %s checksum mismatch.", path)
+		return
+	}
+	old = b[n : n+int(cmosSize)]
+	image = b[n+int(cmosSize) : n+2*int(cmosSize)]
+	return
+}
+
+// recoverRestore finishes a restore left in the journal by an interrupted
+// RestoreImage, or rolls back to the previous image if that fails.
+func (nv *NVRAM) recoverRestore() (err error) {
+	if nv.restoreJournal == "" {
+		return
+	}
+	if _, err = os.Stat(nv.restoreJournal); os.IsNotExist(err) {
+		return nil
+	}
+
+	// CMOS is only written once the journal is complete, an unreadable
+	// journal means nothing was changed.
+	old, image, err := readRestoreJournal(nv.restoreJournal)
+	if err != nil {
+		nv.logf("%v Discarding it.", err)
+		return os.Remove(nv.restoreJournal)
+	}
+
+	nv.logf("nvram: Finishing interrupted CMOS restore.")
+	err = nv.CMOS.WriteAllMemory(image)
+	if err != nil {
+		nv.logf("nvram: Finishing CMOS restore failed: %v. Rolling back.", err)
+		err = nv.CMOS.WriteAllMemory(old)
+		if err != nil {
+			return fmt.Errorf("nvram: Rolling back CMOS restore failed: %v", err)
+		}
+	}
+	return os.Remove(nv.restoreJournal)
+}
+
+// DumpChecksummedRegion returns the CMOS bytes covered by the layout's
+// checksum, from the first to the last byte of the checksum range.
+func (nv *NVRAM) DumpChecksummedRegion() (region []byte, err error) {
+	c := nv.CMOS.checksum
+	if c.kind == ChecksumNone {
+		return nil, fmt.Errorf("nvram: Layout has no checksum range.")
+	}
+	region = make([]byte, c.end-c.start+1)
+	for i := range region {
+		region[i], err = nv.CMOS.ReadByte(c.start + uint(i))
+		if err != nil {
+			return nil, err
+		}
+	}
+	return
+}
+
+// RestoreChecksummedRegion writes a region returned by DumpChecksummedRegion
+// and stores its checksum. Bytes outside the checksum range, such as vendor
+// scratch areas, are not written. The restore is not journaled. Parameters
+// marked unique keep their current value unless the NVRAM was configured
+// WithUniqueRestore.
+func (nv *NVRAM) RestoreChecksummedRegion(region []byte) (err error) {
+	c := nv.CMOS.checksum
+	if c.kind == ChecksumNone {
+		return fmt.Errorf("nvram: Layout has no checksum range.")
+	}
+	if uint(len(region)) != c.end-c.start+1 {
+		return fmt.Errorf("nvram: Checksummed region is %d bytes, not %d.",
+			len(region), c.end-c.start+1)
+	}
+	span := nv.startSpan("nvram.RestoreChecksummedRegion",
+		SpanAttribute{"nvram.bytes", len(region)})
+	defer nv.endSpan(span, &err)
+	nv.beginWrites()
+	defer nv.endWrites(&err)
+
+	err = nv.checkWriteGate()
+	if err != nil {
+		return
+	}
+
+	region = append([]byte(nil), region...)
+	_, err = nv.keepUniqueBits(region, c.start)
+	if err != nil {
+		return
+	}
+	for i, b := range region {
+		err = nv.CMOS.WriteByte(c.start+uint(i), b)
+		if err != nil {
+			return
+		}
+	}
+
+	// Store the checksum of the restored region.
+	sum, err := nv.CMOS.ComputeChecksum()
+	if err != nil {
+		return
+	}
+	err = nv.CMOS.WriteChecksum(sum)
+	if err != nil {
+		return
+	}
+	nv.modified = false
+	return
+}
+
+// RestoreParameters decodes the named parameters from a CMOS image, such as
+// a dump or a snapshot image, and writes only those as one transaction.
+// The stored values are written, bypassing any transformer. Parameters
+// marked unique are skipped unless the NVRAM was configured
+// WithUniqueRestore.
+func (nv *NVRAM) RestoreParameters(dump []byte, names []string) (err error) {
+	if len(names) == 0 {
+		return fmt.Errorf("nvram: No parameters to restore.")
+	}
+	src, err := openImage(nv.Layout, dump)
+	if err != nil {
+		return
+	}
+	defer src.CMOS.Close()
+
+	var restore []string
+	for _, name := range names {
+		if nv.restorable(name) {
+			restore = append(restore, name)
+		}
+	}
+	if len(restore) == 0 {
+		return
+	}
+
+	// The image has no transformers, so copy stored values.
+	params := make([]Parameter, len(restore))
+	for i, name := range restore {
+		params[i].Name = name
+		params[i].Value, err = src.readRawParameter(name)
+		if err != nil {
+			return
+		}
+	}
+	return nv.writeRawParameters(params)
+}
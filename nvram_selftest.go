@@ -0,0 +1,153 @@
+// Copyright © 2019 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+package nvram
+
+import (
+	"bytes"
+	"fmt"
+)
+
+// selfTestPatterns are written to the scratch area by SelfTest.
+var selfTestPatterns = []byte{0x55, 0xAA}
+
+// WithScratchParameter names the parameter SelfTest may overwrite. It must
+// be a hex or string parameter whose value does not matter while the test
+// runs.
+func WithScratchParameter(name string) Option {
+	return func(nv *NVRAM) {
+		nv.scratch = name
+	}
+}
+
+// SelfTest checks reading, writing and checksumming through the open
+// backend. It writes test patterns to the parameter configured
+// WithScratchParameter, or else to a byte of the checksummed area no entry
+// uses, reads them back and stores and validates their checksum. The
+// scratch value and the stored checksum are restored afterwards, also when
+// the test fails.
+func (nv *NVRAM) SelfTest() (err error) {
+	span := nv.startSpan("nvram.SelfTest")
+	defer nv.endSpan(span, &err)
+	nv.beginWrites()
+	defer nv.endWrites(&err)
+
+	e, err := nv.selfTestEntry()
+	if err != nil {
+		return
+	}
+	err = nv.checkWriteGate()
+	if err != nil {
+		return
+	}
+
+	// Save the scratch value and the stored checksum.
+	old, err := nv.CMOS.ReadEntry(e)
+	if err != nil {
+		return fmt.Errorf("nvram: Self test reading %s failed: %w", e.name, err)
+	}
+	hasSum := nv.CMOS.checksum.kind != ChecksumNone
+	var oldSum uint16
+	if hasSum {
+		oldSum, err = nv.CMOS.ReadChecksum()
+		if err != nil {
+			return fmt.Errorf("nvram: Self test reading checksum failed: %w", err)
+		}
+	}
+
+	// Restore both whatever happens.
+	defer func() {
+		rerr := nv.CMOS.WriteEntry(e, old)
+		if rerr == nil && hasSum {
+			rerr = nv.CMOS.WriteChecksum(oldSum)
+		}
+		if rerr != nil && err == nil {
+			err = fmt.Errorf("nvram: Self test restoring %s failed: %w", e.name, rerr)
+		}
+	}()
+
+	for _, p := range selfTestPatterns {
+		want := make([]byte, len(old))
+		for i := range want {
+			want[i] = p
+		}
+		maskEntryBits(want, e.length)
+
+		err = nv.CMOS.WriteEntry(e, want)
+		if err != nil {
+			return fmt.Errorf("nvram: Self test writing %s failed: %w", e.name, err)
+		}
+		var got []byte
+		got, err = nv.CMOS.ReadEntry(e)
+		if err != nil {
+			return fmt.Errorf("nvram: Self test reading %s failed: %w", e.name, err)
+		}
+		if !bytes.Equal(got, want) {
+			return fmt.Errorf("nvram: Self test read % X from %s after writing % X.",
+				got, e.name, want)
+		}
+
+		if !hasSum {
+			continue
+		}
+		var sum uint16
+		sum, err = nv.CMOS.ComputeChecksum()
+		if err == nil {
+			err = nv.CMOS.WriteChecksum(sum)
+		}
+		if err == nil {
+			err = nv.ValidateChecksum()
+		}
+		if err != nil {
+			return fmt.Errorf("nvram: Self test checksum failed: %w", err)
+		}
+	}
+	return
+}
+
+// selfTestEntry returns the configured scratch entry, or an entry for the
+// first byte of the checksummed area not used by the layout.
+func (nv *NVRAM) selfTestEntry() (e *CMOSEntry, err error) {
+	if nv.scratch != "" {
+		e, err = nv.findParameterEntry(nv.resolveName(nv.scratch))
+		if err != nil {
+			return
+		}
+		if e.config != CMOSEntryHex && e.config != CMOSEntryString {
+			err = fmt.Errorf("nvram: Scratch parameter %s is not a hex or string parameter.",
+				e.name)
+		}
+		return
+	}
+
+	c := nv.CMOS.checksum
+	entries := nv.entryList()
+	for off := c.start; off <= c.end; off++ {
+		used := false
+		for _, u := range entries {
+			if checkAreaOverLap(off*8, 8, u.bit, u.length) {
+				used = true
+				break
+			}
+		}
+		if !used {
+			return &CMOSEntry{bit: off * 8, length: 8, config: CMOSEntryHex,
+				name: fmt.Sprintf("byte 0x%02X", off)}, nil
+		}
+	}
+	return nil, fmt.Errorf("nvram: No scratch parameter configured and no unused CMOS byte.")
+}
+
+// maskEntryBits clears the bits of v beyond an entry length.
+func maskEntryBits(v []byte, length uint) {
+	for i := range v {
+		bit := uint(i) * 8
+		switch {
+		case bit >= length:
+			v[i] = 0
+		case bit+8 > length:
+			v[i] &= byte(1<<(length-bit)) - 1
+		}
+	}
+}
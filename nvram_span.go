@@ -0,0 +1,65 @@
+// Copyright © 2019 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+package nvram
+
+// SpanAttribute is a key value attribute of a tracing span.
+type SpanAttribute struct {
+	Key   string
+	Value interface{}
+}
+
+// Span is a tracing span started by a Tracer.
+type Span interface {
+	SetAttributes(attrs ...SpanAttribute)
+	RecordError(err error)
+	End()
+}
+
+// Tracer starts tracing spans. It is the subset of an OpenTelemetry tracer
+// the package uses, so a TracerProvider's tracer is plugged in with an
+// adapter starting a trace.Span and converting attributes to
+// attribute.KeyValue.
+type Tracer interface {
+	Start(name string, attrs ...SpanAttribute) Span
+}
+
+// WithTracer wraps Open, Close, parameter reads and writes, checksum
+// operations and image restores in spans of t. Spans carry the backend, the
+// parameter name and the number of bytes accessed.
+func WithTracer(t Tracer) Option {
+	return func(nv *NVRAM) {
+		nv.tracer = t
+	}
+}
+
+type noSpan struct{}
+
+func (noSpan) SetAttributes(attrs ...SpanAttribute) {}
+func (noSpan) RecordError(err error)                {}
+func (noSpan) End()                                 {}
+
+func (nv *NVRAM) startSpan(name string, attrs ...SpanAttribute) Span {
+	if nv.tracer == nil {
+		return noSpan{}
+	}
+	return nv.tracer.Start(name, attrs...)
+}
+
+// endSpan records the backend and any error of an operation and ends its
+// span. It is deferred with a pointer to the named error result.
+func (nv *NVRAM) endSpan(span Span, err *error) {
+	if _, ok := span.(noSpan); ok {
+		return
+	}
+	span.SetAttributes(SpanAttribute{"nvram.backend", nv.CMOS.backend})
+	if *err != nil {
+		span.RecordError(*err)
+	}
+	span.End()
+}
+
+func entryBytes(e *CMOSEntry) SpanAttribute {
+	return SpanAttribute{"nvram.bytes", int((e.length + 7) / 8)}
+}
@@ -0,0 +1,186 @@
+// Copyright © 2019 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+package nvram
+
+import (
+	"bytes"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// stringTestLayout has string entries of every tested width, with hex
+// entries in the spare bits after the 63 and 65 bit strings to catch
+// writes past the end of a string.
+const stringTestLayout = `entries
+
+#start-bit length  config config-ID    name
+0          384     r      0        reserved_memory
+384        8       s      0        s8
+392        63      s      0        s63
+455        1       h      0        after_s63
+456        64      s      0        s64
+520        65      s      0        s65
+585        7       h      0        after_s65
+592        120     s      0        s120
+712        512     s      0        s512
+1232       16      h      0        check_sum
+
+enumerations
+
+checksums
+
+checksum 384 1223 1232
+`
+
+var stringTestWidths = []struct {
+	name  string
+	width uint
+}{
+	{"s8", 8},
+	{"s63", 63},
+	{"s64", 64},
+	{"s65", 65},
+	{"s120", 120},
+	{"s512", 512},
+}
+
+// openStringTest opens an NVRAM on a zeroed cmos.bin with stringTestLayout.
+func openStringTest(t *testing.T) (nv *NVRAM, layout, cmos string) {
+	dir := t.TempDir()
+	layout = filepath.Join(dir, "cmos.layout")
+	cmos = filepath.Join(dir, "cmos.bin")
+	if err := ioutil.WriteFile(layout, []byte(stringTestLayout), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(cmos, make([]byte, cmosSize), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	nv = NewNVRAM(WithLockDir(dir))
+	if err := nv.Open(layout, cmos); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { nv.Close() })
+	return
+}
+
+// stringTestValue returns a value of n characters.
+func stringTestValue(n uint) string {
+	return strings.Repeat("abcdefghijklmnopqrstuvwxyz", int(n)/26+1)[:n]
+}
+
+func setStringTestGuards(t *testing.T, nv *NVRAM) {
+	if err := nv.WriteCMOSParameter("after_s63", uint64(1)); err != nil {
+		t.Fatal(err)
+	}
+	if err := nv.WriteCMOSParameter("after_s65", uint64(0x7F)); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func checkStringTestGuards(t *testing.T, nv *NVRAM) {
+	t.Helper()
+	for name, want := range map[string]uint64{"after_s63": 1, "after_s65": 0x7F} {
+		v, err := nv.ReadCMOSParameter(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if v != want {
+			t.Errorf("%s is %v, want %v", name, v, want)
+		}
+	}
+}
+
+func TestStringEntryReadWrite(t *testing.T) {
+	nv, _, _ := openStringTest(t)
+	setStringTestGuards(t, nv)
+
+	for _, tc := range stringTestWidths {
+		e, err := nv.findParameterEntry(tc.name)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// Write every bit of the entry.
+		n := (tc.width + 7) / 8
+		v := bytes.Repeat([]byte{0xA5}, int(n))
+		if r := tc.width % 8; r != 0 {
+			v[n-1] &= 1<<r - 1
+		}
+		if err = nv.CMOS.WriteEntry(e, v); err != nil {
+			t.Fatalf("%s: WriteEntry: %v", tc.name, err)
+		}
+		got, err := nv.CMOS.ReadEntry(e)
+		if err != nil {
+			t.Fatalf("%s: ReadEntry: %v", tc.name, err)
+		}
+		if !bytes.Equal(got, v) {
+			t.Errorf("%s: ReadEntry returned % X, want % X", tc.name, got, v)
+		}
+	}
+	checkStringTestGuards(t, nv)
+}
+
+func TestStringParameterWidths(t *testing.T) {
+	nv, layout, cmos := openStringTest(t)
+	setStringTestGuards(t, nv)
+
+	for _, tc := range stringTestWidths {
+		max := tc.width / 8
+		if n, err := nv.MaxLength(tc.name); err != nil || uint(n) != max {
+			t.Errorf("%s: MaxLength is %d, %v, want %d", tc.name, n, err, max)
+		}
+
+		// The longest value fits, a longer one is refused.
+		s := stringTestValue(max)
+		if err := nv.WriteCMOSParameter(tc.name, s); err != nil {
+			t.Fatalf("%s: writing %d characters: %v", tc.name, max, err)
+		}
+		if err := nv.WriteCMOSParameter(tc.name, stringTestValue(max+1)); err == nil {
+			t.Errorf("%s: writing %d characters did not fail", tc.name, max+1)
+		}
+		v, err := nv.ReadCMOSParameter(tc.name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if v != s {
+			t.Errorf("%s: read %q, want %q", tc.name, v, s)
+		}
+
+		// Shorter values are NUL padded.
+		if err = nv.WriteCMOSParameter(tc.name, "x"); err != nil {
+			t.Fatal(err)
+		}
+		if v, _ = nv.ReadCMOSParameter(tc.name); v != "x" {
+			t.Errorf("%s: read %q, want %q", tc.name, v, "x")
+		}
+		if err = nv.WriteCMOSParameter(tc.name, s); err != nil {
+			t.Fatal(err)
+		}
+	}
+	checkStringTestGuards(t, nv)
+
+	// Values and checksum survive reopening the file.
+	if err := nv.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := nv.Open(layout, cmos); err != nil {
+		t.Fatal(err)
+	}
+	if err := nv.ValidateChecksum(); err != nil {
+		t.Error(err)
+	}
+	for _, tc := range stringTestWidths {
+		v, err := nv.ReadCMOSParameter(tc.name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if s := stringTestValue(tc.width / 8); v != s {
+			t.Errorf("%s: read %q after reopening, want %q", tc.name, v, s)
+		}
+	}
+}
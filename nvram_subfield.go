@@ -0,0 +1,66 @@
+// Copyright © 2019 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+package nvram
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+func subfieldMask(s *CMOSSubfield) uint64 {
+	if s.length >= 64 {
+		return ^uint64(0)
+	}
+	return (uint64(1) << s.length) - 1
+}
+
+func (nv *NVRAM) readSubfield(s *CMOSSubfield) (value interface{}, err error) {
+	// Read the whole parent entry
+	v, err := nv.CMOS.ReadEntry(s.parent)
+	if err != nil {
+		return
+	}
+
+	// Extract subfield bits from parent value
+	n := binary.LittleEndian.Uint64(v)
+	value = (n >> s.bit) & subfieldMask(s)
+	return
+}
+
+// subfieldValue validates value for subfield s.
+func subfieldValue(s *CMOSSubfield, value interface{}) (n uint64, err error) {
+	n, ok := value.(uint64)
+	if !ok {
+		err = fmt.Errorf("A uint64 value is required for CMOS subfield %s, not %T.", s.name, value)
+		return
+	}
+
+	// Check length
+	if n > subfieldMask(s) {
+		err = fmt.Errorf("Can not write value 0x%X to CMOS subfield %s that is only %d-bits wide.", n, s.name, s.length)
+	}
+	return
+}
+
+func (nv *NVRAM) writeSubfield(s *CMOSSubfield, value interface{}) (err error) {
+	n, err := subfieldValue(s, value)
+	if err != nil {
+		return
+	}
+	mask := subfieldMask(s)
+
+	// Read the whole parent entry
+	v, err := nv.CMOS.ReadEntry(s.parent)
+	if err != nil {
+		return
+	}
+
+	// Replace subfield bits in parent value and write it back.
+	p := binary.LittleEndian.Uint64(v)
+	p = (p & ^(mask << s.bit)) | (n << s.bit)
+	binary.LittleEndian.PutUint64(v, p)
+
+	return nv.writeEntry(s.parent, v)
+}
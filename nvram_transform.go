@@ -0,0 +1,76 @@
+// Copyright © 2019 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+package nvram
+
+import (
+	"fmt"
+)
+
+// TransformFunc converts a parameter value between the form stored in CMOS
+// and the form presented to callers.
+type TransformFunc func(nv *NVRAM, value interface{}) (interface{}, error)
+
+type valueTransformer struct {
+	read  TransformFunc
+	write TransformFunc
+}
+
+// RegisterTransformer adds hooks converting the values of a CMOS parameter
+// or subfield, for example scaling a raw multiplier to MHz or mapping legacy
+// magic numbers to strings. read converts values read by ReadCMOSParameter,
+// and so the values of exports and snapshots, and write converts values
+// passed to WriteCMOSParameter back to the stored form before they are
+// validated against the layout. write is required, so values read can
+// always be written back, while read may be nil. Defaults and restored
+// values are in the stored form and bypass write. ParseParameterValue
+// takes the text of a parameter with a transformer as is, so write must
+// also accept the text form.
+func (nv *NVRAM) RegisterTransformer(name string, read, write TransformFunc) (err error) {
+	name = nv.resolveName(name)
+	if read == nil && write == nil {
+		err = fmt.Errorf("Transformer for parameter %s has no functions.", name)
+		return
+	}
+	if write == nil {
+		err = fmt.Errorf("Transformer for parameter %s has a read function but no write function.", name)
+		return
+	}
+
+	// Virtual parameters convert their values themselves.
+	if _, ok := nv.virtuals[name]; ok {
+		err = fmt.Errorf("Parameter %s is a virtual parameter.", name)
+		return
+	}
+
+	if nv.transformers == nil {
+		nv.transformers = make(map[string]*valueTransformer)
+	}
+	if _, ok := nv.transformers[name]; ok {
+		err = fmt.Errorf("Transformer for parameter %s already exists.", name)
+		return
+	}
+
+	nv.transformers[name] = &valueTransformer{read: read, write: write}
+	return
+}
+
+// UnregisterTransformer removes the transformer of a parameter.
+func (nv *NVRAM) UnregisterTransformer(name string) {
+	delete(nv.transformers, nv.resolveName(name))
+}
+
+func (t *valueTransformer) readValue(nv *NVRAM, value interface{}) (interface{}, error) {
+	if t.read == nil {
+		return value, nil
+	}
+	return t.read(nv, value)
+}
+
+func (t *valueTransformer) writeValue(nv *NVRAM, value interface{}) (interface{}, error) {
+	if t.write == nil {
+		return value, nil
+	}
+	return t.write(nv, value)
+}
@@ -0,0 +1,115 @@
+// Copyright © 2019 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+package nvram
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+)
+
+// transformTestLayout has a multiplier entry with a default, presented in
+// MHz by a scaling transformer.
+const transformTestLayout = `entries
+
+#start-bit length  config config-ID    name
+0          384     r      0        reserved_memory
+384        8       h      0        cpu_mult
+392        8       h      0        other
+1008       16      h      0        check_sum
+
+enumerations
+
+checksums
+
+checksum 384 1007 1008
+
+metadata
+
+cpu_mult default=0x10
+`
+
+// openTransformTest opens an NVRAM on a zeroed cmos.bin with
+// transformTestLayout and a transformer presenting cpu_mult times 100.
+func openTransformTest(t *testing.T) (nv *NVRAM) {
+	dir := t.TempDir()
+	layout := filepath.Join(dir, "cmos.layout")
+	cmos := filepath.Join(dir, "cmos.bin")
+	if err := ioutil.WriteFile(layout, []byte(transformTestLayout), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(cmos, make([]byte, cmosSize), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	nv = NewNVRAM(WithLockDir(dir))
+	if err := nv.Open(layout, cmos); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { nv.Close() })
+
+	err := nv.RegisterTransformer("cpu_mult",
+		func(nv *NVRAM, value interface{}) (interface{}, error) {
+			return value.(uint64) * 100, nil
+		},
+		func(nv *NVRAM, value interface{}) (interface{}, error) {
+			return value.(uint64) / 100, nil
+		})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return
+}
+
+func checkTransformTestValue(t *testing.T, nv *NVRAM, raw uint64) {
+	t.Helper()
+	v, err := nv.readRawParameter("cpu_mult")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v != raw {
+		t.Errorf("stored value is %v, want %v", v, raw)
+	}
+	if v, err = nv.ReadCMOSParameter("cpu_mult"); err != nil || v != raw*100 {
+		t.Errorf("ReadCMOSParameter returned %v, %v, want %v", v, err, raw*100)
+	}
+}
+
+func TestTransformerResetToDefaults(t *testing.T) {
+	nv := openTransformTest(t)
+	if err := nv.WriteCMOSParameter("cpu_mult", uint64(2400)); err != nil {
+		t.Fatal(err)
+	}
+	checkTransformTestValue(t, nv, 24)
+
+	// The default is a stored value and is not scaled down again.
+	if err := nv.ResetToDefaults(); err != nil {
+		t.Fatal(err)
+	}
+	checkTransformTestValue(t, nv, 0x10)
+	drift, err := nv.DriftFromDefaults()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(drift) != 0 {
+		t.Errorf("DriftFromDefaults returned %v after the reset", drift)
+	}
+}
+
+func TestTransformerRestoreParameters(t *testing.T) {
+	nv := openTransformTest(t)
+	dump := make([]byte, cmosSize)
+	dump[48] = 0x20
+	dump[49] = 0x33
+
+	// The dump holds stored values and is not scaled down again.
+	if err := nv.RestoreParameters(dump, []string{"cpu_mult", "other"}); err != nil {
+		t.Fatal(err)
+	}
+	checkTransformTestValue(t, nv, 0x20)
+	if v, err := nv.ReadCMOSParameter("other"); err != nil || v != uint64(0x33) {
+		t.Errorf("other is %v, %v, want %v", v, err, 0x33)
+	}
+}
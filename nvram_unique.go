@@ -0,0 +1,61 @@
+// Copyright © 2019 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+package nvram
+
+// WithUniqueRestore lets snapshot checkout, CSV import, ResetToDefaults and
+// the image and parameter restores write parameters marked unique in the
+// layout. Without it they keep their current value, e.g. when restoring a
+// backup taken on another machine.
+func WithUniqueRestore() Option {
+	return func(nv *NVRAM) {
+		nv.uniqueRestore = true
+	}
+}
+
+// isUnique returns true if the named parameter, or the parent of a
+// subfield, is marked unique.
+func (nv *NVRAM) isUnique(name string) bool {
+	name = nv.resolveName(name)
+	if s, ok := nv.FindCMOSSubfield(name); ok {
+		name = s.parent.name
+	}
+	e, ok := nv.entry(name)
+	return ok && e.meta.unique
+}
+
+// restorable returns true if a restore may write the named parameter.
+func (nv *NVRAM) restorable(name string) bool {
+	if nv.uniqueRestore || !nv.isUnique(name) {
+		return true
+	}
+	nv.logf("nvram: Keeping unique CMOS parameter %s.", name)
+	return false
+}
+
+// keepUniqueBits copies the current CMOS bits of unique parameters into
+// image, which holds the CMOS bytes from base on, so restoring the image
+// keeps them. It returns true if any unique parameter was kept.
+func (nv *NVRAM) keepUniqueBits(image []byte, base uint) (kept bool, err error) {
+	for _, e := range nv.Layout.entryList() {
+		if !e.meta.unique || nv.restorable(e.name) {
+			continue
+		}
+		for bit := e.bit; bit < e.bit+e.length; bit++ {
+			i := bit>>3 - base
+			if bit>>3 < base || i >= uint(len(image)) {
+				continue
+			}
+			var b byte
+			b, err = nv.CMOS.ReadByte(bit >> 3)
+			if err != nil {
+				return
+			}
+			mask := byte(1) << (bit & 7)
+			image[i] = image[i]&^mask | b&mask
+		}
+		kept = true
+	}
+	return
+}
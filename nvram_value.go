@@ -0,0 +1,120 @@
+// Copyright © 2019 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+package nvram
+
+import (
+	"encoding/binary"
+	"fmt"
+	"strings"
+)
+
+// ValidateValue checks that value can be written to entry without writing
+// it. Strings must fit the entry, enum texts must be items of the entry's
+// enum that fit its width and hex values must fit its width and range.
+func (l *Layout) ValidateValue(e *CMOSEntry, value interface{}) (err error) {
+	_, err = l.encodeValue(e, value)
+	return
+}
+
+// ValidateParameter checks that value can be written to the named parameter
+// without writing it, including subfield widths and layout constraints.
+// Values of virtual parameters are only checked when written, values of
+// parameters with a transformer are converted to the stored form first.
+func (nv *NVRAM) ValidateParameter(name string, value interface{}) (err error) {
+	name = nv.resolveName(name)
+
+	if _, ok := nv.virtuals[name]; ok {
+		return
+	}
+	if t, ok := nv.transformers[name]; ok {
+		value, err = t.writeValue(nv, value)
+		if err != nil {
+			return
+		}
+	}
+
+	if s, ok := nv.FindCMOSSubfield(name); ok {
+		_, err = subfieldValue(s, value)
+	} else {
+		var e *CMOSEntry
+		e, err = nv.findParameterEntry(name)
+		if err == nil {
+			err = nv.ValidateValue(e, value)
+		}
+	}
+	if err != nil {
+		return
+	}
+	return nv.checkConstraints(name, value)
+}
+
+// encodeValue validates value for entry and returns the bytes to write.
+func (l *Layout) encodeValue(e *CMOSEntry, value interface{}) (v []byte, err error) {
+	switch e.config {
+	case CMOSEntryString:
+		s, ok := value.(string)
+		if !ok {
+			err = fmt.Errorf("A string value is required for CMOS parameter %s, not %T.", e.name, value)
+			return
+		}
+		if max := e.length / 8; uint(len(s)) > max {
+			err = fmt.Errorf("Can not write %d character value %q to CMOS parameter %s that holds at most %d characters.", len(s), s, e.name, max)
+			return
+		}
+		if strings.IndexByte(s, 0) >= 0 {
+			err = fmt.Errorf("Can not write value %q with a NUL character to CMOS parameter %s.", s, e.name)
+			return
+		}
+		// Copy string to byte array
+		v = make([]byte, (e.length+7)/8)
+		copy(v[:], []byte(s))
+
+	case CMOSEntryEnum:
+		s, ok := value.(string)
+		if !ok {
+			err = fmt.Errorf("A string value is required for CMOS parameter %s, not %T.", e.name, value)
+			return
+		}
+		n, ok := l.FindCMOSEnumValue(e.config_id, s)
+		if !ok {
+			err = fmt.Errorf("Bad value %s for parameter %s", s, e.name)
+			return
+		}
+		// Check length
+		if e.length < 64 && (uint64(n) >= (uint64(1) << e.length)) {
+			err = fmt.Errorf("Enum %s value %d is too wide for %d-bit parameter %s", s, n, e.length, e.name)
+			return
+		}
+		// Copy uint64 to byte array
+		v = make([]byte, 8)
+		binary.LittleEndian.PutUint64(v, uint64(n))
+
+	case CMOSEntryHex:
+		n, ok := value.(uint64)
+		if !ok {
+			err = fmt.Errorf("A uint64 value is required for CMOS parameter %s, not %T.", e.name, value)
+			return
+		}
+		// Check length
+		if e.length < 64 && (n >= (uint64(1) << e.length)) {
+			err = fmt.Errorf("Can not write value 0x%X to CMOS parameter %s that is only %d-bits wide.", n, e.name, e.length)
+			return
+		}
+		// Check range from layout metadata
+		err = e.verifyRange(n)
+		if err != nil {
+			return
+		}
+
+		// Copy uint64 to byte array
+		v = make([]byte, 8)
+		binary.LittleEndian.PutUint64(v, n)
+
+	default:
+		err = fmt.Errorf("CMOS entry %s is reserved.", e.name)
+	}
+
+	return
+}
@@ -0,0 +1,79 @@
+// Copyright © 2019 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+package nvram
+
+import (
+	"fmt"
+	"sort"
+)
+
+// VirtualReadFunc returns the current value of a virtual parameter.
+type VirtualReadFunc func(nv *NVRAM) (value interface{}, err error)
+
+// VirtualWriteFunc stores a new value of a virtual parameter.
+type VirtualWriteFunc func(nv *NVRAM, value interface{}) error
+
+type virtualParameter struct {
+	name  string
+	read  VirtualReadFunc
+	write VirtualWriteFunc
+}
+
+// RegisterVirtualParameter adds a parameter computed by Go callbacks instead
+// of being stored in a single CMOS entry. Virtual parameters are read,
+// written and listed like CMOS parameters. The callbacks usually translate
+// to and from one or more real CMOS parameters, for example presenting a
+// boot order kept in three enum entries as one comma separated list.
+// A nil write function makes the parameter read-only.
+func (nv *NVRAM) RegisterVirtualParameter(name string, read VirtualReadFunc, write VirtualWriteFunc) (err error) {
+	if read == nil {
+		err = fmt.Errorf("Virtual parameter %s has no read function.", name)
+		return
+	}
+
+	// Virtual parameters must not hide CMOS parameters in the layout.
+	if nv.Layout != nil {
+		if _, ok := nv.FindCMOSEntry(name); ok {
+			err = fmt.Errorf("CMOS parameter %s already exists.", name)
+			return
+		}
+		if _, ok := nv.FindCMOSSubfield(name); ok {
+			err = fmt.Errorf("CMOS subfield %s already exists.", name)
+			return
+		}
+	}
+
+	if nv.virtuals == nil {
+		nv.virtuals = make(map[string]*virtualParameter)
+	}
+	if _, ok := nv.virtuals[name]; ok {
+		err = fmt.Errorf("Virtual parameter %s already exists.", name)
+		return
+	}
+
+	nv.virtuals[name] = &virtualParameter{name: name, read: read, write: write}
+	return
+}
+
+// UnregisterVirtualParameter removes a virtual parameter.
+func (nv *NVRAM) UnregisterVirtualParameter(name string) {
+	delete(nv.virtuals, name)
+}
+
+func (nv *NVRAM) virtualParameterNames() (names []string) {
+	for name := range nv.virtuals {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return
+}
+
+func (p *virtualParameter) writeValue(nv *NVRAM, value interface{}) (err error) {
+	if p.write == nil {
+		err = fmt.Errorf("Virtual parameter %s is read-only.", p.name)
+		return
+	}
+	return p.write(nv, value)
+}
@@ -0,0 +1,88 @@
+// Copyright © 2019 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+package nvram
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strconv"
+	"strings"
+)
+
+// ReadNvramtoolDump reads a binary CMOS dump as written by "nvramtool -b"
+// and returns a full size CMOS image. Dumps of only the lower 128 byte bank
+// are zero extended.
+func ReadNvramtoolDump(r io.Reader) (image []byte, err error) {
+	d, err := ioutil.ReadAll(io.LimitReader(r, int64(cmosSize)+1))
+	if err != nil {
+		return
+	}
+
+	if len(d) > int(cmosSize) {
+		err = fmt.Errorf("nvram: CMOS dump larger than %d bytes.", cmosSize)
+		return
+	}
+
+	image = make([]byte, cmosSize)
+	copy(image, d)
+	return
+}
+
+func isHexByte(s string) bool {
+	if len(s) != 2 {
+		return false
+	}
+	_, err := strconv.ParseUint(s, 16, 8)
+	return err == nil
+}
+
+// ReadNvramtoolHexdump parses a hex dump as shown by "nvramtool -x" or
+// "nvramtool -X" and returns a full size CMOS image. Each line starts with
+// the hex offset of its first byte followed by the data bytes as two digit
+// hex values. Group separators and the trailing ASCII column are ignored.
+func ReadNvramtoolHexdump(r io.Reader) (image []byte, err error) {
+	image = make([]byte, cmosSize)
+
+	var linenum uint = 0
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		linenum++
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+
+		// First field is the offset of the line
+		var off uint64
+		off, err = strconv.ParseUint(strings.TrimSuffix(fields[0], ":"), 16, 32)
+		if err != nil {
+			err = fmt.Errorf("nvram: Bad offset in hex dump on line %d", linenum)
+			return
+		}
+
+		// Copy bytes until the ASCII column
+		for _, field := range fields[1:] {
+			if field == "|" || field == ":" {
+				continue
+			}
+			if !isHexByte(field) {
+				break
+			}
+			if off >= uint64(cmosSize) {
+				err = fmt.Errorf("nvram: Hex dump offset out of range on line %d", linenum)
+				return
+			}
+			b, _ := strconv.ParseUint(field, 16, 8)
+			image[off] = byte(b)
+			off++
+		}
+	}
+
+	err = scanner.Err()
+	return
+}
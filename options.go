@@ -0,0 +1,73 @@
+// Copyright © 2019 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+package nvram
+
+import (
+	"fmt"
+	"github.com/platinasystems/nvram/debug"
+	"log"
+)
+
+// Option configures optional NVRAM behavior.
+type Option func(nv *NVRAM)
+
+// NewNVRAM returns an NVRAM configured with the given options. A zero
+// NVRAM is ready to use with default behavior.
+func NewNVRAM(opts ...Option) *NVRAM {
+	nv := new(NVRAM)
+	nv.SetOptions(opts...)
+	return nv
+}
+
+// SetOptions applies options to the NVRAM. Options should be set before
+// calling Open.
+func (nv *NVRAM) SetOptions(opts ...Option) {
+	for _, opt := range opts {
+		opt(nv)
+	}
+}
+
+// WithLogger sends notices, such as use of deprecated parameters, to l.
+func WithLogger(l *log.Logger) Option {
+	return func(nv *NVRAM) {
+		nv.logger = l
+	}
+}
+
+// WithLayouts makes Open read a base layout and merge overlay layouts on
+// top of it in order, as with ReadLayoutFromFiles, when it is not given a
+// layout file.
+func WithLayouts(base string, overlays ...string) Option {
+	return func(nv *NVRAM) {
+		nv.layoutFiles = append([]string{base}, overlays...)
+	}
+}
+
+// WithAlphabeticalOrder lists parameters sorted by name instead of by bit
+// offset. ReadAllParameters and all exports and reports follow this order,
+// so exported documents stay comparable across layout changes that move
+// parameters.
+func WithAlphabeticalOrder() Option {
+	return func(nv *NVRAM) {
+		nv.alphabetical = true
+	}
+}
+
+// WithEmptyLayout makes Open accept a layout without entries instead of
+// failing with ErrEmptyLayout. The NVRAM is then only usable in raw mode:
+// there are no parameters, but the CMOS bytes can be read and written with
+// ReadByte and WriteByte. The checksum is neither checked nor updated.
+func WithEmptyLayout() Option {
+	return func(nv *NVRAM) {
+		nv.allowEmpty = true
+	}
+}
+
+func (nv *NVRAM) logf(format string, a ...interface{}) {
+	debug.Trace(debug.LevelMSG1, format+"\n", a...)
+	if nv.logger != nil {
+		nv.logger.Output(2, fmt.Sprintf(format, a...))
+	}
+}
@@ -0,0 +1,188 @@
+// Copyright © 2019 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+package nvram
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// PresetExt is the file name extension of preset files.
+const PresetExt = ".preset"
+
+// Preset is a named set of settings, such as "performance" or "low-power",
+// applied together with ApplyPreset.
+type Preset struct {
+	Name        string
+	Description string
+	Settings    []Setting
+}
+
+// WithPresetDir makes ListPresets and ApplyPreset also use the preset files
+// in dir. A preset file is named after its preset with PresetExt appended
+// and holds settings in the format read by ReadSettings. Its first comment
+// line is the description of the preset.
+func WithPresetDir(dir string) Option {
+	return func(nv *NVRAM) {
+		nv.presetDir = dir
+	}
+}
+
+// ReadPresetFile reads a preset file.
+func ReadPresetFile(filename string) (p Preset, err error) {
+	b, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return
+	}
+	p.Name = strings.TrimSuffix(filepath.Base(filename), PresetExt)
+	p.Settings, err = ReadSettings(bytes.NewReader(b))
+	if err != nil {
+		return p, fmt.Errorf("nvram: %s: %v", filename, err)
+	}
+
+	// Take the description from the first comment.
+	scanner := bufio.NewScanner(bytes.NewReader(b))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if strings.HasPrefix(line, "#") {
+			p.Description = strings.TrimSpace(line[1:])
+			break
+		}
+	}
+	return
+}
+
+// RegisterPreset adds a preset defined in code. It hides a preset file of
+// the same name.
+func (nv *NVRAM) RegisterPreset(p Preset) (err error) {
+	if p.Name == "" {
+		return fmt.Errorf("nvram: Preset has no name.")
+	}
+	if nv.presets == nil {
+		nv.presets = make(map[string]Preset)
+	}
+	if _, ok := nv.presets[p.Name]; ok {
+		return fmt.Errorf("nvram: Preset %s already exists.", p.Name)
+	}
+	nv.presets[p.Name] = p
+	return
+}
+
+// ListPresets returns the registered presets and those of the preset
+// directory sorted by name.
+func (nv *NVRAM) ListPresets() (presets []Preset, err error) {
+	byName := make(map[string]Preset)
+	if nv.presetDir != "" {
+		var files []string
+		files, err = filepath.Glob(filepath.Join(nv.presetDir, "*"+PresetExt))
+		if err != nil {
+			return
+		}
+		for _, file := range files {
+			var p Preset
+			p, err = ReadPresetFile(file)
+			if err != nil {
+				return
+			}
+			byName[p.Name] = p
+		}
+	}
+	for name, p := range nv.presets {
+		byName[name] = p
+	}
+
+	for _, p := range byName {
+		presets = append(presets, p)
+	}
+	sort.Slice(presets, func(i, j int) bool {
+		return presets[i].Name < presets[j].Name
+	})
+	return
+}
+
+// Preset returns the named preset.
+func (nv *NVRAM) Preset(name string) (p Preset, err error) {
+	if p, ok := nv.presets[name]; ok {
+		return p, nil
+	}
+	if nv.presetDir != "" {
+		p, err = ReadPresetFile(filepath.Join(nv.presetDir, name+PresetExt))
+		if !os.IsNotExist(err) {
+			return
+		}
+	}
+	return p, fmt.Errorf("nvram: Preset %s not found.", name)
+}
+
+// ValidatePreset checks every setting of the named preset against the open
+// layout, as ApplyPreset would, and returns every problem found.
+func (nv *NVRAM) ValidatePreset(name string) (errs []error) {
+	p, err := nv.Preset(name)
+	if err != nil {
+		return []error{err}
+	}
+	_, errs = nv.presetParameters(p)
+	return
+}
+
+// presetParameters parses and validates the settings of a preset.
+func (nv *NVRAM) presetParameters(p Preset) (params []Parameter, errs []error) {
+	for _, s := range p.Settings {
+		value, err := nv.ParseParameterValue(s.Name, s.Value)
+		if err == nil {
+			err = nv.ValidateParameter(s.Name, value)
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("nvram: Preset %s: %v", p.Name, err))
+			continue
+		}
+		params = append(params, Parameter{Name: s.Name, Value: value})
+	}
+	return
+}
+
+// ApplyPreset validates the named preset and writes the settings that
+// differ from the current values in one transaction, so either all or none
+// are changed. It returns the names of the parameters changed.
+func (nv *NVRAM) ApplyPreset(name string) (changed []string, err error) {
+	span := nv.startSpan("nvram.ApplyPreset", SpanAttribute{"nvram.preset", name})
+	defer nv.endSpan(span, &err)
+
+	p, err := nv.Preset(name)
+	if err != nil {
+		return
+	}
+	params, errs := nv.presetParameters(p)
+	if len(errs) > 0 {
+		return nil, errs[0]
+	}
+
+	// Only write what the preset changes.
+	var writes []Parameter
+	for _, param := range params {
+		var current interface{}
+		current, err = nv.ReadCMOSParameter(param.Name)
+		if err != nil {
+			return
+		}
+		if !reflect.DeepEqual(current, param.Value) {
+			writes = append(writes, param)
+			changed = append(changed, param.Name)
+		}
+	}
+	err = nv.WriteCMOSParameters(writes)
+	if err != nil {
+		return nil, err
+	}
+	nv.logf("nvram: Applied preset %s, changed %d parameters.", name, len(changed))
+	return
+}
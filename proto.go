@@ -0,0 +1,344 @@
+// Copyright © 2019 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+package nvram
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// SettingsSnapshotFormat is the format version written by
+// MarshalSettingsSnapshot.
+const SettingsSnapshotFormat = 1
+
+var errProtoTruncated = errors.New("nvram: Truncated protobuf message.")
+
+// SettingsSnapshot is the decoded form of the SettingsSnapshot message of
+// proto/nvram.proto.
+type SettingsSnapshot struct {
+	Time       time.Time
+	Layout     *Layout
+	Settings   []Parameter
+	Provenance Provenance
+}
+
+// Protobuf wire types
+const (
+	protoVarint  = 0
+	protoFixed64 = 1
+	protoBytes   = 2
+	protoFixed32 = 5
+)
+
+func protoAppendUvarint(b []byte, v uint64) []byte {
+	var buf [binary.MaxVarintLen64]byte
+	n := binary.PutUvarint(buf[:], v)
+	return append(b, buf[:n]...)
+}
+
+func protoAppendVarint(b []byte, field int, v uint64) []byte {
+	if v == 0 {
+		return b
+	}
+	b = protoAppendUvarint(b, uint64(field<<3|protoVarint))
+	return protoAppendUvarint(b, v)
+}
+
+func protoAppendBytes(b []byte, field int, data []byte) []byte {
+	b = protoAppendUvarint(b, uint64(field<<3|protoBytes))
+	b = protoAppendUvarint(b, uint64(len(data)))
+	return append(b, data...)
+}
+
+func protoAppendString(b []byte, field int, s string) []byte {
+	if s == "" {
+		return b
+	}
+	return protoAppendBytes(b, field, []byte(s))
+}
+
+// protoFields calls f for each field of a message. Varint fields pass their
+// value in v, length delimited fields their data. Fixed size fields are
+// skipped, so unknown fields of newer formats are ignored.
+func protoFields(b []byte, f func(field int, v uint64, data []byte) error) (err error) {
+	for len(b) > 0 {
+		tag, n := binary.Uvarint(b)
+		if n <= 0 {
+			return errProtoTruncated
+		}
+		b = b[n:]
+		field := int(tag >> 3)
+
+		var v uint64
+		var data []byte
+		switch tag & 7 {
+		case protoVarint:
+			v, n = binary.Uvarint(b)
+			if n <= 0 {
+				return errProtoTruncated
+			}
+			b = b[n:]
+		case protoBytes:
+			v, n = binary.Uvarint(b)
+			if n <= 0 || uint64(len(b)-n) < v {
+				return errProtoTruncated
+			}
+			data = b[n : n+int(v)]
+			b = b[n+int(v):]
+			v = 0
+		case protoFixed64:
+			if len(b) < 8 {
+				return errProtoTruncated
+			}
+			b = b[8:]
+			continue
+		case protoFixed32:
+			if len(b) < 4 {
+				return errProtoTruncated
+			}
+			b = b[4:]
+			continue
+		default:
+			return fmt.Errorf("nvram: Unsupported protobuf wire type %d.", tag&7)
+		}
+
+		err = f(field, v, data)
+		if err != nil {
+			return
+		}
+	}
+	return
+}
+
+func marshalEntryProto(e *CMOSEntry) (b []byte) {
+	b = protoAppendVarint(b, 1, uint64(e.bit))
+	b = protoAppendVarint(b, 2, uint64(e.length))
+	b = protoAppendString(b, 3, string(e.config))
+	b = protoAppendVarint(b, 4, uint64(e.config_id))
+	b = protoAppendString(b, 5, e.name)
+	b = protoAppendString(b, 6, e.description)
+	return
+}
+
+func unmarshalEntryProto(b []byte) (e *CMOSEntry, err error) {
+	e = new(CMOSEntry)
+	err = protoFields(b, func(field int, v uint64, data []byte) error {
+		switch field {
+		case 1:
+			e.bit = uint(v)
+		case 2:
+			e.length = uint(v)
+		case 3:
+			if len(data) != 1 {
+				return fmt.Errorf("Bad entry config %q", data)
+			}
+			e.config = CMOSEntryConfig(data[0])
+		case 4:
+			e.config_id = uint(v)
+		case 5:
+			e.name = string(data)
+		case 6:
+			e.description = string(data)
+		}
+		return nil
+	})
+	return
+}
+
+func marshalEnumItemProto(item CMOSEnumItem) (b []byte) {
+	b = protoAppendVarint(b, 1, uint64(item.id))
+	b = protoAppendVarint(b, 2, uint64(item.value))
+	b = protoAppendString(b, 3, item.text)
+	return
+}
+
+func unmarshalEnumItemProto(b []byte) (item CMOSEnumItem, err error) {
+	err = protoFields(b, func(field int, v uint64, data []byte) error {
+		switch field {
+		case 1:
+			item.id = uint(v)
+		case 2:
+			item.value = uint(v)
+		case 3:
+			item.text = string(data)
+		}
+		return nil
+	})
+	return
+}
+
+// MarshalLayoutProto encodes a layout as a Layout message.
+func MarshalLayoutProto(l *Layout) (b []byte) {
+	b = protoAppendVarint(b, 1, uint64(l.Version()))
+	for _, e := range l.GetCMOSEntriesList() {
+		b = protoAppendBytes(b, 2, marshalEntryProto(e))
+	}
+	for _, item := range l.GetCMOSEnumItems() {
+		b = protoAppendBytes(b, 3, marshalEnumItemProto(item))
+	}
+
+	c := l.GetCheckChecksum()
+	var sum []byte
+	sum = protoAppendVarint(sum, 1, uint64(c.start*8))
+	sum = protoAppendVarint(sum, 2, uint64(c.end*8+7))
+	sum = protoAppendVarint(sum, 3, uint64(c.index*8))
+
+	// Type is always written as none is zero.
+	sum = protoAppendUvarint(sum, 4<<3|protoVarint)
+	sum = protoAppendUvarint(sum, uint64(c.kind))
+	return protoAppendBytes(b, 4, sum)
+}
+
+// UnmarshalLayoutProto decodes a Layout message.
+func UnmarshalLayoutProto(b []byte) (l *Layout, err error) {
+	l = NewLayout()
+	err = protoFields(b, func(field int, v uint64, data []byte) (err error) {
+		switch field {
+		case 1:
+			l.SetVersion(uint(v))
+		case 2:
+			var e *CMOSEntry
+			e, err = unmarshalEntryProto(data)
+			if err == nil {
+				err = l.AddCMOSEntry(e)
+			}
+		case 3:
+			var item CMOSEnumItem
+			item, err = unmarshalEnumItemProto(data)
+			if err == nil {
+				l.AddCMOSEnum(&item)
+			}
+		case 4:
+			var start, end, index uint
+			kind := ChecksumPCBIOS
+			err = protoFields(data, func(field int, v uint64, data []byte) error {
+				switch field {
+				case 1:
+					start = uint(v)
+				case 2:
+					end = uint(v)
+				case 3:
+					index = uint(v)
+				case 4:
+					kind = ChecksumType(v)
+				}
+				return nil
+			})
+			if err == nil {
+				l.cmosChecksum, err = NewCMOSChecksum(start, end, index)
+			}
+			if err == nil {
+				err = l.cmosChecksum.setType(kind)
+			}
+		}
+		return
+	})
+	if err != nil {
+		l = nil
+	}
+	return
+}
+
+// MarshalSettingsSnapshot encodes a snapshot as a SettingsSnapshot message.
+func MarshalSettingsSnapshot(s *SettingsSnapshot) (b []byte, err error) {
+	b = protoAppendVarint(b, 1, SettingsSnapshotFormat)
+	if !s.Time.IsZero() {
+		b = protoAppendVarint(b, 2, uint64(s.Time.UnixNano()))
+	}
+	if s.Layout != nil {
+		b = protoAppendBytes(b, 3, MarshalLayoutProto(s.Layout))
+	}
+	for _, p := range s.Settings {
+		var m []byte
+		m = protoAppendString(m, 1, p.Name)
+		switch v := p.Value.(type) {
+		case string:
+			m = protoAppendBytes(m, 2, []byte(v))
+		case uint64:
+			m = protoAppendUvarint(m, 3<<3|protoVarint)
+			m = protoAppendUvarint(m, v)
+		default:
+			err = fmt.Errorf("Setting %s has unsupported type %T", p.Name, p.Value)
+			return
+		}
+		b = protoAppendBytes(b, 4, m)
+	}
+	if !s.Provenance.IsZero() {
+		var m []byte
+		m = protoAppendString(m, 1, s.Provenance.Operator)
+		m = protoAppendString(m, 2, s.Provenance.Hostname)
+		m = protoAppendString(m, 3, s.Provenance.Reason)
+		m = protoAppendString(m, 4, s.Provenance.ToolVersion)
+		b = protoAppendBytes(b, 5, m)
+	}
+	return
+}
+
+// UnmarshalSettingsSnapshot decodes a SettingsSnapshot message.
+func UnmarshalSettingsSnapshot(b []byte) (s *SettingsSnapshot, err error) {
+	s = new(SettingsSnapshot)
+	var format uint64
+	err = protoFields(b, func(field int, v uint64, data []byte) (err error) {
+		switch field {
+		case 1:
+			format = v
+		case 2:
+			s.Time = time.Unix(0, int64(v))
+		case 3:
+			s.Layout, err = UnmarshalLayoutProto(data)
+		case 4:
+			var p Parameter
+			err = protoFields(data, func(field int, v uint64, data []byte) error {
+				switch field {
+				case 1:
+					p.Name = string(data)
+				case 2:
+					p.Value = string(data)
+				case 3:
+					p.Value = v
+				}
+				return nil
+			})
+			s.Settings = append(s.Settings, p)
+		case 5:
+			p := &s.Provenance
+			err = protoFields(data, func(field int, v uint64, data []byte) error {
+				switch field {
+				case 1:
+					p.Operator = string(data)
+				case 2:
+					p.Hostname = string(data)
+				case 3:
+					p.Reason = string(data)
+				case 4:
+					p.ToolVersion = string(data)
+				}
+				return nil
+			})
+		}
+		return
+	})
+	if err == nil && format > SettingsSnapshotFormat {
+		err = fmt.Errorf("Settings snapshot format %d is newer than %d", format, SettingsSnapshotFormat)
+	}
+	if err != nil {
+		s = nil
+	}
+	return
+}
+
+// SettingsSnapshot returns a snapshot of the layout and all parameter
+// values.
+func (nv *NVRAM) SettingsSnapshot() (s *SettingsSnapshot, err error) {
+	params, err := nv.ReadAllParameters()
+	if err != nil {
+		return
+	}
+	s = &SettingsSnapshot{Time: time.Now(), Layout: nv.Layout, Settings: params,
+		Provenance: nv.currentProvenance()}
+	return
+}
@@ -0,0 +1,57 @@
+// Copyright © 2019 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+package nvram
+
+import (
+	"os"
+	"os/user"
+	"runtime/debug"
+)
+
+// Provenance records who took a snapshot, where, why and with which tool,
+// so restores and rollbacks can be audited.
+type Provenance struct {
+	Operator    string `json:"operator,omitempty"`
+	Hostname    string `json:"hostname,omitempty"`
+	Reason      string `json:"reason,omitempty"`
+	ToolVersion string `json:"tool_version,omitempty"`
+}
+
+// IsZero returns true if no provenance is recorded.
+func (p Provenance) IsZero() bool {
+	return p == Provenance{}
+}
+
+// WithProvenance sets the provenance recorded in snapshots and snapshot
+// history. An empty Operator defaults to the user running the process, or
+// the user that ran sudo, an empty Hostname to the host name and an empty
+// ToolVersion to the module version of the running program.
+func WithProvenance(p Provenance) Option {
+	return func(nv *NVRAM) {
+		nv.provenance = p
+	}
+}
+
+// currentProvenance returns the configured provenance with defaults filled
+// in.
+func (nv *NVRAM) currentProvenance() (p Provenance) {
+	p = nv.provenance
+	if p.Operator == "" {
+		if s := os.Getenv("SUDO_USER"); s != "" {
+			p.Operator = s
+		} else if u, err := user.Current(); err == nil {
+			p.Operator = u.Username
+		}
+	}
+	if p.Hostname == "" {
+		p.Hostname, _ = os.Hostname()
+	}
+	if p.ToolVersion == "" {
+		if bi, ok := debug.ReadBuildInfo(); ok {
+			p.ToolVersion = bi.Main.Path + " " + bi.Main.Version
+		}
+	}
+	return
+}
@@ -0,0 +1,99 @@
+// Copyright © 2019 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+package nvram
+
+import (
+	"bufio"
+	"fmt"
+	"html/template"
+	"io"
+	"strings"
+)
+
+// reportRow is one parameter of a report.
+type reportRow struct {
+	Name        string
+	Value       string
+	Allowed     string
+	Description string
+}
+
+var reportHTMLTemplate = template.Must(template.New("report").Parse(`<!DOCTYPE html>
+<html>
+<head><meta charset="utf-8"><title>CMOS settings</title></head>
+<body>
+<table>
+<tr><th>Name</th><th>Value</th><th>Allowed values</th><th>Description</th></tr>
+{{range .}}<tr><td>{{.Name}}</td><td>{{.Value}}</td><td>{{.Allowed}}</td><td>{{.Description}}</td></tr>
+{{end}}</table>
+</body>
+</html>
+`))
+
+func (nv *NVRAM) allowedValues(info ParameterInfo) string {
+	switch {
+	case info.Virtual:
+		return ""
+	case info.Config == CMOSEntryEnum:
+		return strings.Join(info.Values, ", ")
+	case info.Config == CMOSEntryString:
+		return fmt.Sprintf("up to %d characters", info.Length/8)
+	case info.Config == CMOSEntryHex:
+		return nv.FormatParameter(info.Name, info.Min) + " .. " + nv.FormatParameter(info.Name, info.Max)
+	}
+	return ""
+}
+
+// reportRows reads all parameters with their allowed values and
+// descriptions.
+func (nv *NVRAM) reportRows() (rows []reportRow, err error) {
+	params, err := nv.ReadAllParameters()
+	if err != nil {
+		return
+	}
+	for _, p := range params {
+		var info ParameterInfo
+		info, err = nv.ParameterInfo(p.Name)
+		if err != nil {
+			return
+		}
+		rows = append(rows, reportRow{
+			Name:        p.Name,
+			Value:       nv.FormatParameter(p.Name, p.Value),
+			Allowed:     nv.allowedValues(info),
+			Description: info.Description,
+		})
+	}
+	return
+}
+
+// ReportMarkdown writes a Markdown table of all parameters with their
+// current values, allowed values and descriptions.
+func (nv *NVRAM) ReportMarkdown(w io.Writer) (err error) {
+	rows, err := nv.reportRows()
+	if err != nil {
+		return
+	}
+
+	cell := strings.NewReplacer("|", `\|`, "\n", " ")
+	bw := bufio.NewWriter(w)
+	fmt.Fprintln(bw, "| Name | Value | Allowed values | Description |")
+	fmt.Fprintln(bw, "| --- | --- | --- | --- |")
+	for _, r := range rows {
+		fmt.Fprintf(bw, "| %s | %s | %s | %s |\n", cell.Replace(r.Name),
+			cell.Replace(r.Value), cell.Replace(r.Allowed), cell.Replace(r.Description))
+	}
+	return bw.Flush()
+}
+
+// ReportHTML writes an HTML page with a table of all parameters with their
+// current values, allowed values and descriptions.
+func (nv *NVRAM) ReportHTML(w io.Writer) (err error) {
+	rows, err := nv.reportRows()
+	if err != nil {
+		return
+	}
+	return reportHTMLTemplate.Execute(w, rows)
+}
@@ -0,0 +1,190 @@
+// Copyright © 2019 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+package nvram
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// SerialConsoleProfile names the parameters of the serial console options.
+// Enable and Port are optional. Enable is an enum with EnableOn and
+// EnableOff texts or a hex parameter holding 1 or 0.
+type SerialConsoleProfile struct {
+	Enable    string
+	EnableOn  string
+	EnableOff string
+	Baud      string
+	Port      string
+}
+
+// SerialConsole holds the serial console settings. Port is the text of an
+// enum port parameter or the number of a hex one.
+type SerialConsole struct {
+	Enabled bool
+	Baud    uint
+	Port    string
+}
+
+// serialConsoleNames are the parameter names DetectSerialConsoleProfile
+// looks for, in order of preference.
+var serialConsoleNames = struct {
+	enable, baud, port []string
+}{
+	enable: []string{"serial_console", "console_enable", "serial_enable"},
+	baud:   []string{"baud_rate", "serial_baud_rate", "console_baud_rate"},
+	port:   []string{"serial_port", "uart_port", "console_port", "uart_index"},
+}
+
+// DetectSerialConsoleProfile finds the serial console parameters of a
+// layout by their common names, such as baud_rate, serial_console and
+// uart_port.
+func DetectSerialConsoleProfile(l *Layout) (p SerialConsoleProfile, ok bool) {
+	find := func(names []string) string {
+		for _, name := range names {
+			if _, ok := l.entry(name); ok {
+				return name
+			}
+		}
+		return ""
+	}
+	p.Baud = find(serialConsoleNames.baud)
+	if p.Baud == "" {
+		return p, false
+	}
+	p.Port = find(serialConsoleNames.port)
+	p.Enable = find(serialConsoleNames.enable)
+	if e, ok := l.entry(p.Enable); ok && e.config == CMOSEntryEnum {
+		for _, on := range []string{"Enable", "Enabled", "On"} {
+			if _, ok := l.FindCMOSEnumValue(e.config_id, on); ok {
+				p.EnableOn = on
+				break
+			}
+		}
+		for _, off := range []string{"Disable", "Disabled", "Off"} {
+			if _, ok := l.FindCMOSEnumValue(e.config_id, off); ok {
+				p.EnableOff = off
+				break
+			}
+		}
+	}
+	return p, true
+}
+
+func (nv *NVRAM) serialConsoleProfile(p SerialConsoleProfile) (SerialConsoleProfile, error) {
+	if p.Baud != "" {
+		return p, nil
+	}
+	p, ok := DetectSerialConsoleProfile(nv.Layout)
+	if !ok {
+		return p, fmt.Errorf("nvram: Layout has no serial console parameters.")
+	}
+	return p, nil
+}
+
+// ReadSerialConsole reads the serial console settings of profile, or of
+// the profile found by DetectSerialConsoleProfile if profile has no Baud.
+// Without an Enable parameter the console is always enabled.
+func (nv *NVRAM) ReadSerialConsole(profile SerialConsoleProfile) (c SerialConsole, err error) {
+	p, err := nv.serialConsoleProfile(profile)
+	if err != nil {
+		return
+	}
+
+	c.Enabled = true
+	if p.Enable != "" {
+		var v interface{}
+		v, err = nv.ReadCMOSParameter(p.Enable)
+		if err != nil {
+			return
+		}
+		c.Enabled = v == p.EnableOn || v == uint64(1)
+	}
+
+	v, err := nv.ReadCMOSParameter(p.Baud)
+	if err != nil {
+		return
+	}
+	switch v := v.(type) {
+	case uint64:
+		c.Baud = uint(v)
+	case string:
+		var n uint64
+		n, err = strconv.ParseUint(v, 10, 32)
+		if err != nil {
+			return c, fmt.Errorf("nvram: Bad baud rate %s in %s.", v, p.Baud)
+		}
+		c.Baud = uint(n)
+	}
+
+	if p.Port != "" {
+		v, err = nv.ReadCMOSParameter(p.Port)
+		if err != nil {
+			return
+		}
+		c.Port = formatParameterValue(v)
+	}
+	return
+}
+
+// WriteSerialConsole writes all serial console settings in one
+// transaction after checking them together: the baud rate and port must
+// be values the layout allows, and a console can only be disabled if the
+// layout has an Enable parameter. The baud rate and port of a disabled
+// console are written too, so enabling it later gives the expected
+// settings. A zero Baud or empty Port keeps the current value.
+func (nv *NVRAM) WriteSerialConsole(profile SerialConsoleProfile, c SerialConsole) (err error) {
+	p, err := nv.serialConsoleProfile(profile)
+	if err != nil {
+		return
+	}
+
+	var params []Parameter
+	add := func(name, s string) error {
+		value, err := nv.ParseParameterValue(name, s)
+		if err == nil {
+			err = nv.ValidateParameter(name, value)
+		}
+		if err != nil {
+			return fmt.Errorf("nvram: Bad serial console setting: %v", err)
+		}
+		params = append(params, Parameter{Name: name, Value: value})
+		return nil
+	}
+
+	switch {
+	case p.Enable == "" && !c.Enabled:
+		return fmt.Errorf("nvram: Serial console can not be disabled, the layout has no enable parameter.")
+	case p.Enable == "":
+	case p.EnableOn != "" && c.Enabled:
+		err = add(p.Enable, p.EnableOn)
+	case p.EnableOff != "" && !c.Enabled:
+		err = add(p.Enable, p.EnableOff)
+	case c.Enabled:
+		err = add(p.Enable, "1")
+	default:
+		err = add(p.Enable, "0")
+	}
+	if err != nil {
+		return
+	}
+
+	if c.Baud != 0 {
+		err = add(p.Baud, strconv.FormatUint(uint64(c.Baud), 10))
+		if err != nil {
+			return
+		}
+	}
+	if c.Port != "" {
+		if p.Port == "" {
+			return fmt.Errorf("nvram: Layout has no serial port parameter.")
+		}
+		err = add(p.Port, c.Port)
+		if err != nil {
+			return
+		}
+	}
+	return nv.WriteCMOSParameters(params)
+}
@@ -0,0 +1,208 @@
+// Copyright © 2019 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+package nvram
+
+import (
+	"sync"
+)
+
+// Names used to export a Service on the D-Bus system bus.
+const (
+	DBusServiceName = "org.platinasystems.nvram"
+	DBusObjectPath  = "/org/platinasystems/nvram"
+	DBusInterface   = "org.platinasystems.nvram.Settings"
+
+	// polkit actions checked by the Authorizer of a Service before the
+	// calls of a Caller.
+	PolkitActionRead  = "org.platinasystems.nvram.read"
+	PolkitActionWrite = "org.platinasystems.nvram.write"
+)
+
+// DBusIntrospection describes the D-Bus interface implemented by Service.
+const DBusIntrospection = `<node>
+  <interface name="org.platinasystems.nvram.Settings">
+    <method name="Get">
+      <arg name="name" type="s" direction="in"/>
+      <arg name="value" type="s" direction="out"/>
+    </method>
+    <method name="Set">
+      <arg name="name" type="s" direction="in"/>
+      <arg name="value" type="s" direction="in"/>
+    </method>
+    <method name="List">
+      <arg name="names" type="as" direction="out"/>
+    </method>
+    <signal name="Changed">
+      <arg name="name" type="s"/>
+      <arg name="value" type="s"/>
+    </signal>
+  </interface>
+</node>`
+
+// Change is sent to subscribers of a Service when a parameter is set.
+type Change struct {
+	Name  string
+	Value string
+}
+
+// Authorizer checks that caller, such as the unique bus name of the sender
+// of a D-Bus call, is allowed a polkit action such as PolkitActionWrite. A
+// bus binding implements it with the polkit CheckAuthorization method.
+type Authorizer func(caller, action string) error
+
+// Service provides string based Get, Set and List operations on an open
+// NVRAM with change notifications, in the shape of the D-Bus interface
+// described by DBusIntrospection. Service does not connect to a bus, this
+// module has no D-Bus dependency. A bus binding exports the methods of the
+// Caller of each sender, which are authorized by the Authorizer, and emits
+// the Changed signal for every Change received from Subscribe. Service
+// methods are safe for concurrent use.
+type Service struct {
+	nv          *NVRAM
+	mu          sync.Mutex
+	subscribers map[chan Change]struct{}
+	authorize   Authorizer
+}
+
+func NewService(nv *NVRAM) *Service {
+	return &Service{nv: nv, subscribers: make(map[chan Change]struct{})}
+}
+
+// Get returns the formatted value of a parameter. The value is formatted
+// without its unit, which Types returns, so it can be given back to Set.
+func (s *Service) Get(name string) (value string, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	v, err := s.nv.ReadCMOSParameter(name)
+	if err != nil {
+		return
+	}
+	value = s.formatValue(name, v)
+	return
+}
+
+// formatValue formats a value as ExportParameters does, in the display
+// base of the entry and without its unit.
+func (s *Service) formatValue(name string, v interface{}) string {
+	if e, ok := s.nv.FindCMOSEntry(s.nv.resolveName(name)); ok {
+		return e.formatValue(v)
+	}
+	return formatParameterValue(v)
+}
+
+// Set parses and writes a parameter value and notifies subscribers.
+func (s *Service) Set(name, value string) (err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	v, err := s.nv.ParseParameterValue(name, value)
+	if err != nil {
+		return
+	}
+	err = s.nv.WriteCMOSParameter(name, v)
+	if err != nil {
+		return
+	}
+
+	// Notify subscribers without blocking on slow readers
+	c := Change{Name: name, Value: s.formatValue(name, v)}
+	for ch := range s.subscribers {
+		select {
+		case ch <- c:
+		default:
+		}
+	}
+	return
+}
+
+// List returns the names of all parameters.
+func (s *Service) List() (names []string, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	names = s.nv.ParameterNames()
+	return
+}
+
+// Subscribe returns a channel receiving a Change for every Set, and a
+// function ending the subscription. Changes are dropped if the channel
+// is not read.
+func (s *Service) Subscribe() (changes <-chan Change, cancel func()) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	ch := make(chan Change, 16)
+	s.subscribers[ch] = struct{}{}
+	cancel = func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if _, ok := s.subscribers[ch]; ok {
+			delete(s.subscribers, ch)
+			close(ch)
+		}
+	}
+	return ch, cancel
+}
+
+// SetAuthorizer sets the Authorizer checking the calls of a Caller.
+func (s *Service) SetAuthorizer(authorize Authorizer) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.authorize = authorize
+}
+
+// Caller returns the Store of a caller, which checks PolkitActionRead for
+// Get, List and Types and PolkitActionWrite for Set with the Authorizer
+// before calling the Service. Without an Authorizer all calls fail with
+// ErrNotAuthorized.
+func (s *Service) Caller(caller string) Store {
+	return &serviceCaller{s: s, caller: caller}
+}
+
+type serviceCaller struct {
+	s      *Service
+	caller string
+}
+
+func (c *serviceCaller) check(action string) error {
+	c.s.mu.Lock()
+	authorize := c.s.authorize
+	c.s.mu.Unlock()
+
+	if authorize == nil {
+		return ErrNotAuthorized
+	}
+	return authorize(c.caller, action)
+}
+
+func (c *serviceCaller) Get(name string) (value string, err error) {
+	if err = c.check(PolkitActionRead); err != nil {
+		return
+	}
+	return c.s.Get(name)
+}
+
+func (c *serviceCaller) Set(name, value string) (err error) {
+	if err = c.check(PolkitActionWrite); err != nil {
+		return
+	}
+	return c.s.Set(name, value)
+}
+
+func (c *serviceCaller) List() (names []string, err error) {
+	if err = c.check(PolkitActionRead); err != nil {
+		return
+	}
+	return c.s.List()
+}
+
+func (c *serviceCaller) Types() (types map[string]SettingType, err error) {
+	if err = c.check(PolkitActionRead); err != nil {
+		return
+	}
+	return c.s.Types()
+}
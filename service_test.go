@@ -0,0 +1,84 @@
+// Copyright © 2019 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+package nvram
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+)
+
+// serviceTestLayout has a decimal entry with a unit.
+const serviceTestLayout = `entries
+
+#start-bit length  config config-ID    name
+0          384     r      0        reserved_memory
+384        16      h      0        cpu_freq
+1008       16      h      0        check_sum
+
+enumerations
+
+checksums
+
+checksum 384 1007 1008
+
+metadata
+
+cpu_freq unit=MHz base=10
+`
+
+// openServiceTest opens an NVRAM on a zeroed cmos.bin with
+// serviceTestLayout.
+func openServiceTest(t *testing.T) (nv *NVRAM) {
+	dir := t.TempDir()
+	layout := filepath.Join(dir, "cmos.layout")
+	cmos := filepath.Join(dir, "cmos.bin")
+	if err := ioutil.WriteFile(layout, []byte(serviceTestLayout), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(cmos, make([]byte, cmosSize), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	nv = NewNVRAM(WithLockDir(dir))
+	if err := nv.Open(layout, cmos); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { nv.Close() })
+	return
+}
+
+func TestServiceUnitRoundTrip(t *testing.T) {
+	s := NewService(openServiceTest(t))
+	changes, cancel := s.Subscribe()
+	defer cancel()
+
+	if err := s.Set("cpu_freq", "1600"); err != nil {
+		t.Fatal(err)
+	}
+	if c := <-changes; c.Value != "1600" {
+		t.Errorf("Change value is %q, want %q", c.Value, "1600")
+	}
+
+	// The value read can be written back.
+	v, err := s.Get("cpu_freq")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v != "1600" {
+		t.Errorf("Get returned %q, want %q", v, "1600")
+	}
+	if err = s.Set("cpu_freq", v); err != nil {
+		t.Error(err)
+	}
+
+	types, err := s.Types()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if typ := types["cpu_freq"]; typ.Kind != SettingNumber || typ.Unit != "MHz" {
+		t.Errorf("cpu_freq type is %+v, want a number in MHz", typ)
+	}
+}
@@ -0,0 +1,112 @@
+// Copyright © 2019 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+package nvram
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// Setting is one "name = value" line of a settings file.
+type Setting struct {
+	Name  string
+	Value string
+}
+
+// ReadSettings parses a settings file in the format written by
+// ExportParameters. Blank lines, comment lines and trailing comments are
+// ignored. A # only starts a comment at the start of a line or after white
+// space, outside double quotes. Values in double quotes are unquoted like
+// Go strings, so they may contain " #" or leading and trailing spaces.
+func ReadSettings(r io.Reader) (settings []Setting, err error) {
+	var linenum uint = 0
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		// Get line and ignore blanks and comments
+		line := strings.TrimSpace(stripSettingComment(scanner.Text()))
+		linenum++
+		if len(line) == 0 {
+			continue
+		}
+
+		// Split name and value
+		kv := strings.SplitN(line, "=", 2)
+		if len(kv) != 2 || strings.TrimSpace(kv[0]) == "" {
+			err = fmt.Errorf("Expected name = value on line %d", linenum)
+			return
+		}
+		value := strings.TrimSpace(kv[1])
+		if len(value) >= 2 && value[0] == '"' && value[len(value)-1] == '"' {
+			value, err = strconv.Unquote(value)
+			if err != nil {
+				err = fmt.Errorf("Bad quoted value on line %d: %v", linenum, err)
+				return
+			}
+		}
+		settings = append(settings, Setting{Name: strings.TrimSpace(kv[0]), Value: value})
+	}
+
+	err = scanner.Err()
+	return
+}
+
+// stripSettingComment removes a comment from a settings line.
+func stripSettingComment(line string) string {
+	quoted := false
+	for i := 0; i < len(line); i++ {
+		switch c := line[i]; {
+		case c == '\\' && quoted:
+			i++
+		case c == '"':
+			quoted = !quoted
+		case c == '#' && !quoted && (i == 0 || line[i-1] == ' ' || line[i-1] == '\t'):
+			return line[:i]
+		}
+	}
+	return line
+}
+
+// quoteSettingValue quotes a value that ReadSettings would not read back
+// as is.
+func quoteSettingValue(value string) string {
+	if value != strings.TrimSpace(value) || strings.HasPrefix(value, "#") ||
+		strings.Contains(value, "\"") || strings.Contains(value, " #") ||
+		strings.Contains(value, "\t#") {
+		return strconv.Quote(value)
+	}
+	return value
+}
+
+// ApplySettings writes every setting whose value differs from the current
+// parameter value, in order, and returns the names of the parameters
+// changed. It stops at the first error.
+func (nv *NVRAM) ApplySettings(settings []Setting) (changed []string, err error) {
+	for _, s := range settings {
+		var value, current interface{}
+		value, err = nv.ParseParameterValue(s.Name, s.Value)
+		if err != nil {
+			return
+		}
+		current, err = nv.ReadCMOSParameter(s.Name)
+		if err != nil {
+			return
+		}
+		if reflect.DeepEqual(current, value) {
+			continue
+		}
+
+		err = nv.WriteCMOSParameter(s.Name, value)
+		if err != nil {
+			return
+		}
+		changed = append(changed, s.Name)
+	}
+	return
+}
@@ -0,0 +1,216 @@
+// Copyright © 2019 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+package nvram
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// SnapshotStore keeps the history of an NVRAM's settings in a directory.
+// Snapshots are stored by the hash of their content in the objects
+// directory, HEAD holds the id of the current snapshot and history holds
+// one JSON SnapshotInfo line per commit or checkout.
+type SnapshotStore struct {
+	dir string
+	nv  *NVRAM
+}
+
+// SnapshotInfo describes an entry of the snapshot history. The provenance
+// is that of the NVRAM making the commit or checkout.
+type SnapshotInfo struct {
+	ID      string    `json:"id"`
+	Parent  string    `json:"parent,omitempty"`
+	Time    time.Time `json:"time"`
+	Message string    `json:"message"`
+	Provenance
+}
+
+// OpenSnapshotStore opens the snapshot store in dir for the open NVRAM,
+// creating it if needed.
+func (nv *NVRAM) OpenSnapshotStore(dir string) (s *SnapshotStore, err error) {
+	err = os.MkdirAll(filepath.Join(dir, "objects"), 0755)
+	if err != nil {
+		return
+	}
+	s = &SnapshotStore{dir: dir, nv: nv}
+	return
+}
+
+// Head returns the id of the current snapshot, or "" for an empty store.
+func (s *SnapshotStore) Head() (id string, err error) {
+	b, err := ioutil.ReadFile(filepath.Join(s.dir, "HEAD"))
+	if os.IsNotExist(err) {
+		return "", nil
+	}
+	id = strings.TrimSpace(string(b))
+	return
+}
+
+func (s *SnapshotStore) objectFile(id string) string {
+	return filepath.Join(s.dir, "objects", id)
+}
+
+// record moves HEAD to id and appends it to the history.
+func (s *SnapshotStore) record(id, message string) (info SnapshotInfo, err error) {
+	info = SnapshotInfo{ID: id, Time: time.Now(), Message: message,
+		Provenance: s.nv.currentProvenance()}
+	info.Parent, err = s.Head()
+	if err != nil {
+		return
+	}
+
+	b, err := json.Marshal(&info)
+	if err != nil {
+		return
+	}
+	f, err := os.OpenFile(filepath.Join(s.dir, "history"), os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		return
+	}
+	_, err = f.Write(append(b, '\n'))
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return
+	}
+
+	// Replace HEAD atomically
+	tmp := filepath.Join(s.dir, "HEAD.tmp")
+	err = ioutil.WriteFile(tmp, []byte(id+"\n"), 0644)
+	if err != nil {
+		return
+	}
+	err = os.Rename(tmp, filepath.Join(s.dir, "HEAD"))
+	return
+}
+
+// Commit stores a snapshot of the current layout parameter values and makes
+// it HEAD. Identical settings share one snapshot id.
+func (s *SnapshotStore) Commit(message string) (info SnapshotInfo, err error) {
+	snap := &SettingsSnapshot{Layout: s.nv.Layout}
+	for _, name := range s.nv.parameterNames() {
+		var value interface{}
+		value, err = s.nv.ReadCMOSParameter(name)
+		if err != nil {
+			return
+		}
+		snap.Settings = append(snap.Settings, Parameter{Name: name, Value: value})
+	}
+
+	// Address snapshot by content, leaving out the time and provenance
+	// kept in the history.
+	b, err := MarshalSettingsSnapshot(snap)
+	if err != nil {
+		return
+	}
+	sum := sha256.Sum256(b)
+	id := hex.EncodeToString(sum[:])
+
+	if _, err = os.Stat(s.objectFile(id)); os.IsNotExist(err) {
+		err = ioutil.WriteFile(s.objectFile(id), b, 0644)
+	}
+	if err != nil {
+		return
+	}
+	return s.record(id, message)
+}
+
+// Snapshot returns a stored snapshot. An id prefix that matches one
+// snapshot is accepted.
+func (s *SnapshotStore) Snapshot(id string) (snap *SettingsSnapshot, err error) {
+	id, err = s.resolve(id)
+	if err != nil {
+		return
+	}
+	b, err := ioutil.ReadFile(s.objectFile(id))
+	if err != nil {
+		return
+	}
+	return UnmarshalSettingsSnapshot(b)
+}
+
+func (s *SnapshotStore) resolve(prefix string) (id string, err error) {
+	files, err := ioutil.ReadDir(filepath.Join(s.dir, "objects"))
+	if err != nil {
+		return
+	}
+	for _, f := range files {
+		if !strings.HasPrefix(f.Name(), prefix) {
+			continue
+		}
+		if id != "" {
+			return "", fmt.Errorf("Snapshot id %s is ambiguous", prefix)
+		}
+		id = f.Name()
+	}
+	if id == "" || prefix == "" {
+		err = fmt.Errorf("Snapshot %s not found", prefix)
+	}
+	return
+}
+
+// History returns the commits and checkouts of the store, newest first.
+func (s *SnapshotStore) History() (history []SnapshotInfo, err error) {
+	f, err := os.Open(filepath.Join(s.dir, "history"))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var info SnapshotInfo
+		err = json.Unmarshal(scanner.Bytes(), &info)
+		if err != nil {
+			return
+		}
+		history = append([]SnapshotInfo{info}, history...)
+	}
+	err = scanner.Err()
+	return
+}
+
+// Checkout writes the parameter values of a snapshot that differ from the
+// current values in one transaction and makes the snapshot HEAD.
+// Parameters no longer in the layout are ignored, unique parameters are
+// kept unless the NVRAM was configured WithUniqueRestore.
+func (s *SnapshotStore) Checkout(id string) (info SnapshotInfo, err error) {
+	id, err = s.resolve(id)
+	if err != nil {
+		return
+	}
+	snap, err := s.Snapshot(id)
+	if err != nil {
+		return
+	}
+
+	var params []Parameter
+	for _, p := range snap.Settings {
+		current, rerr := s.nv.ReadCMOSParameter(p.Name)
+		if rerr != nil || reflect.DeepEqual(current, p.Value) || !s.nv.restorable(p.Name) {
+			continue
+		}
+		params = append(params, p)
+	}
+	err = s.nv.WriteCMOSParameters(params)
+	if err != nil {
+		return
+	}
+	return s.record(id, "checkout "+id)
+}
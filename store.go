@@ -0,0 +1,76 @@
+// Copyright © 2019 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+package nvram
+
+// SettingKind is the kind of value of a Store setting.
+type SettingKind int
+
+const (
+	SettingString SettingKind = iota
+	SettingNumber
+	SettingEnum
+)
+
+func (k SettingKind) String() string {
+	switch k {
+	case SettingString:
+		return "string"
+	case SettingNumber:
+		return "number"
+	case SettingEnum:
+		return "enum"
+	}
+	return "unknown"
+}
+
+// SettingType describes the values a Store setting accepts.
+type SettingType struct {
+	Kind SettingKind
+	// Values are the allowed values of an enum setting.
+	Values []string
+	// Unit is the unit of a number setting, which values do not include.
+	Unit string
+}
+
+// Store is a firmware settings store with string values, independent of
+// how the firmware stores them. Applications written against Store run on
+// any firmware settings backend. Service implements Store for an NVRAM.
+type Store interface {
+	Get(name string) (value string, err error)
+	Set(name, value string) error
+	List() (names []string, err error)
+	Types() (types map[string]SettingType, err error)
+}
+
+var _ Store = (*Service)(nil)
+
+// Types returns the type of every parameter.
+func (s *Service) Types() (types map[string]SettingType, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	types = make(map[string]SettingType)
+	for _, name := range s.nv.ParameterNames() {
+		var info ParameterInfo
+		info, err = s.nv.ParameterInfo(name)
+		if err != nil {
+			return nil, err
+		}
+		switch {
+		case info.Config == CMOSEntryEnum:
+			types[name] = SettingType{Kind: SettingEnum, Values: info.Values}
+		case info.Config == CMOSEntryHex && !info.Virtual:
+			types[name] = SettingType{Kind: SettingNumber, Unit: info.Unit}
+		default:
+			types[name] = SettingType{Kind: SettingString}
+		}
+	}
+	return
+}
+
+// Store returns the NVRAM as a Store.
+func (nv *NVRAM) Store() Store {
+	return NewService(nv)
+}
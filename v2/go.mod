@@ -0,0 +1,5 @@
+module github.com/platinasystems/nvram/v2
+
+go 1.12
+
+require github.com/platinasystems/nvram v0.0.0-20261014130058-5649b1d8fc41
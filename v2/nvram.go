@@ -0,0 +1,208 @@
+// Copyright © 2019 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+// Package nvram is the second version of the CMOS parameter API.
+//
+// The API takes a context on every call that may touch the CMOS, reports
+// all failures through *Error and is configured only through options.
+// It is the module github.com/platinasystems/nvram/v2 and is implemented
+// on top of the version 1 module, which remains supported and keeps the
+// implementation, so programs can move one call site at a time. V1
+// returns the underlying version 1 NVRAM for calls not yet covered here.
+//
+// The module requires a published version of the version 1 module. To
+// build against the version 1 module of a checkout instead, use a go.work
+// file listing both, as created by "go work init . ./v2" at the top of the
+// repository.
+package nvram
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	v1 "github.com/platinasystems/nvram"
+)
+
+// Errors shared with the version 1 package, so errors.Is works the same
+// for both.
+var (
+	ErrBusy           = v1.ErrNVRAMAccessInUse
+	ErrNotOpen        = v1.ErrCMOSNotOpen
+	ErrReadOnly       = v1.ErrCMOSReadOnly
+	ErrNotFound       = v1.ErrParameterNotFound
+	ErrBatteryFailed  = v1.ErrCMOSBatteryFailed
+	ErrGroupReadOnly  = v1.ErrGroupReadOnly
+	ErrWriteGated     = v1.ErrWriteGated
+	ErrChecksumFailed = errors.New("nvram: CMOS checksum is invalid.")
+)
+
+// Error is returned by every NVRAM method. Op names the method, Name the
+// parameter if the operation was on one.
+type Error struct {
+	Op   string
+	Name string
+	Err  error
+}
+
+func (e *Error) Error() string {
+	if e.Name == "" {
+		return fmt.Sprintf("%s: %v", e.Op, e.Err)
+	}
+	return fmt.Sprintf("%s %s: %v", e.Op, e.Name, e.Err)
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+func wrap(op, name string, err error) error {
+	if err == nil {
+		return nil
+	}
+	var pe *v1.ParameterError
+	if errors.As(err, &pe) && pe.Name == name {
+		err = pe.Err
+	}
+	return &Error{Op: op, Name: name, Err: err}
+}
+
+// Parameter is a named parameter value. Values are uint64 for hex and
+// reserved parameters and string for enum and string parameters.
+type Parameter = v1.Parameter
+
+// ParameterInfo describes a parameter for user interfaces.
+type ParameterInfo = v1.ParameterInfo
+
+// NVRAM is an open CMOS. Methods are safe for use by one goroutine at a
+// time, like the version 1 NVRAM.
+type NVRAM struct {
+	nv *v1.NVRAM
+}
+
+// Open opens the CMOS described by the options. Without WithLayoutFile the
+// layout is read from the coreboot table, without WithCMOSFile the CMOS
+// hardware is used.
+func Open(ctx context.Context, opts ...Option) (n *NVRAM, err error) {
+	var c config
+	for _, opt := range opts {
+		opt(&c)
+	}
+
+	if err = ctx.Err(); err != nil {
+		return nil, wrap("open", "", err)
+	}
+
+	nv := v1.NewNVRAM(c.options...)
+	args := []string{c.layoutFile}
+	if c.cmosFile != "" {
+		args = append(args, c.cmosFile)
+	}
+	if c.lockWait != nil {
+		err = nv.OpenWithRetry(ctx, *c.lockWait, args...)
+	} else {
+		err = nv.Open(args...)
+	}
+	if err != nil {
+		return nil, wrap("open", "", err)
+	}
+	return &NVRAM{nv: nv}, nil
+}
+
+// V1 returns the version 1 NVRAM backing n. It stays valid until Close.
+func (n *NVRAM) V1() *v1.NVRAM {
+	return n.nv
+}
+
+// Close updates the checksum if parameters were written and releases the
+// CMOS.
+func (n *NVRAM) Close() (err error) {
+	return wrap("close", "", n.nv.Close())
+}
+
+// Names returns the names of all parameters, including virtual ones.
+func (n *NVRAM) Names() []string {
+	return n.nv.ParameterNames()
+}
+
+// Info describes the named parameter.
+func (n *NVRAM) Info(name string) (info ParameterInfo, err error) {
+	info, err = n.nv.ParameterInfo(name)
+	err = wrap("info", name, err)
+	return
+}
+
+// Get reads the named parameter.
+func (n *NVRAM) Get(ctx context.Context, name string) (value interface{}, err error) {
+	if err = ctx.Err(); err != nil {
+		return nil, wrap("get", name, err)
+	}
+	value, err = n.nv.ReadCMOSParameter(name)
+	if err != nil {
+		return nil, wrap("get", name, err)
+	}
+	return
+}
+
+// GetAll reads every parameter returned by Names. It stops with the
+// context's error if ctx is done before all parameters are read.
+func (n *NVRAM) GetAll(ctx context.Context) (params []Parameter, err error) {
+	for _, name := range n.Names() {
+		var value interface{}
+		value, err = n.Get(ctx, name)
+		if err != nil {
+			return nil, err
+		}
+		params = append(params, Parameter{Name: name, Value: value})
+	}
+	return
+}
+
+// Set writes the named parameter. String values are parsed as the
+// parameter's type, so command line input can be passed unchanged.
+func (n *NVRAM) Set(ctx context.Context, name string, value interface{}) (err error) {
+	if err = ctx.Err(); err != nil {
+		return wrap("set", name, err)
+	}
+	if s, ok := value.(string); ok {
+		value, err = n.nv.ParseParameterValue(name, s)
+		if err != nil {
+			return wrap("set", name, err)
+		}
+	}
+	return wrap("set", name, n.nv.WriteBurst(ctx, func() error {
+		return n.nv.WriteCMOSParameter(name, value)
+	}))
+}
+
+// SetAll writes several parameters as one update, checking constraints
+// against the combined result. Nothing is written if ctx is done before
+// the update starts.
+func (n *NVRAM) SetAll(ctx context.Context, params []Parameter) (err error) {
+	if err = ctx.Err(); err != nil {
+		return wrap("set", "", err)
+	}
+	return wrap("set", "", n.nv.WriteBurst(ctx, func() error {
+		return n.nv.WriteCMOSParameters(params)
+	}))
+}
+
+// ValidateChecksum returns an *Error wrapping ErrChecksumFailed if the
+// stored checksum does not match the CMOS contents.
+func (n *NVRAM) ValidateChecksum(ctx context.Context) (err error) {
+	if err = ctx.Err(); err != nil {
+		return wrap("validate checksum", "", err)
+	}
+	if err = n.nv.ValidateChecksum(); err != nil {
+		return wrap("validate checksum", "", fmt.Errorf("%w (%v)", ErrChecksumFailed, err))
+	}
+	return
+}
+
+// RepairChecksum recomputes and stores the checksum.
+func (n *NVRAM) RepairChecksum(ctx context.Context) (err error) {
+	if err = ctx.Err(); err != nil {
+		return wrap("repair checksum", "", err)
+	}
+	return wrap("repair checksum", "", n.nv.RepairChecksum())
+}
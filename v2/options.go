@@ -0,0 +1,53 @@
+// Copyright © 2019 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+package nvram
+
+import (
+	v1 "github.com/platinasystems/nvram"
+)
+
+type config struct {
+	layoutFile string
+	cmosFile   string
+	options    []v1.Option
+	lockWait   *v1.Backoff
+}
+
+// Option configures Open.
+type Option func(c *config)
+
+// WithLayoutFile reads the layout from a text layout file or, for names
+// ending in .bin, a binary CMOS option table. It replaces the first
+// argument of the version 1 Open.
+func WithLayoutFile(path string) Option {
+	return func(c *config) {
+		c.layoutFile = path
+	}
+}
+
+// WithCMOSFile accesses a CMOS image file instead of the hardware. It
+// replaces the second argument of the version 1 Open.
+func WithCMOSFile(path string) Option {
+	return func(c *config) {
+		c.cmosFile = path
+	}
+}
+
+// WithV1Options applies version 1 options, for behavior that has no
+// version 2 option yet.
+func WithV1Options(opts ...v1.Option) Option {
+	return func(c *config) {
+		c.options = append(c.options, opts...)
+	}
+}
+
+// WithLockWait makes Open wait with backoff b while the CMOS is held by
+// another NVRAM, until the context of Open is done, instead of failing with
+// ErrBusy.
+func WithLockWait(b v1.Backoff) Option {
+	return func(c *config) {
+		c.lockWait = &b
+	}
+}
@@ -0,0 +1,117 @@
+// Copyright © 2019 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+package nvram
+
+import (
+	"sync"
+)
+
+// WriteQueue applies parameter writes in a single worker goroutine, so
+// callers do not wait for slow CMOS access. Writes are applied in order. A
+// queued write that has not started is replaced by a later write of the
+// same parameter, which then takes the later position in the order. While
+// a queue is in use the NVRAM should only be written through the queue.
+type WriteQueue struct {
+	nv      *NVRAM
+	mu      sync.Mutex
+	cond    *sync.Cond
+	pending []Parameter
+	busy    bool
+	closed  bool
+	err     error
+	done    chan struct{}
+}
+
+// NewWriteQueue starts a write queue for the open NVRAM.
+func (nv *NVRAM) NewWriteQueue() *WriteQueue {
+	q := &WriteQueue{nv: nv, done: make(chan struct{})}
+	q.cond = sync.NewCond(&q.mu)
+	go q.run()
+	return q
+}
+
+// Write queues a parameter write.
+func (q *WriteQueue) Write(name string, value interface{}) (err error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.closed {
+		return ErrWriteQueueClosed
+	}
+
+	// Coalesce with a queued write of the same parameter.
+	for i, p := range q.pending {
+		if p.Name == name {
+			q.pending = append(q.pending[:i], q.pending[i+1:]...)
+			break
+		}
+	}
+	q.pending = append(q.pending, Parameter{Name: name, Value: value})
+	q.cond.Broadcast()
+	return
+}
+
+// Wait blocks until all queued writes are applied and returns the first
+// error of the writes applied since the last Wait.
+func (q *WriteQueue) Wait() (err error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	for len(q.pending) > 0 || q.busy {
+		q.cond.Wait()
+	}
+	err, q.err = q.err, nil
+	return
+}
+
+// Close applies the queued writes, stops the worker and returns the result
+// of Wait.
+func (q *WriteQueue) Close() (err error) {
+	// Refuse new writes first, so none is queued after the last error is
+	// taken.
+	q.mu.Lock()
+	q.closed = true
+	q.cond.Broadcast()
+	q.mu.Unlock()
+
+	// The worker applies the queued writes before it stops.
+	<-q.done
+
+	q.mu.Lock()
+	err, q.err = q.err, nil
+	q.mu.Unlock()
+	return
+}
+
+func (q *WriteQueue) run() {
+	defer close(q.done)
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	for {
+		for len(q.pending) == 0 && !q.closed {
+			q.cond.Wait()
+		}
+		if len(q.pending) == 0 {
+			return
+		}
+
+		// Write next parameter without holding the lock.
+		p := q.pending[0]
+		q.pending = q.pending[1:]
+		q.busy = true
+		q.mu.Unlock()
+
+		err := q.nv.WriteCMOSParameter(p.Name, p.Value)
+
+		q.mu.Lock()
+		q.busy = false
+		if err != nil && q.err == nil {
+			q.err = err
+		}
+		q.cond.Broadcast()
+	}
+}